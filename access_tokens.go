@@ -25,11 +25,15 @@ type AccessToken struct {
 
 	// Prefix is the first set of characters in the token. The prefix is used to identify the user
 	// and help a user keep track of their tokens. We use 'latin1_general_cs` collation to enforce
-	// case-senstive queries.
+	// case-sensitive queries in MySQL.
+	//
+	// The collation uses the legacy `sql` struct tag, which Gorm v2 ignores. It must be applied by
+	// migrations on MySQL, and it's not needed on PostgreSQL, which compares text case-sensitively.
 	Prefix string `sql:"type:VARCHAR(64) CHARACTER SET latin1 COLLATE latin1_general_cs" json:"prefix"`
 
 	// Key is the second set of characters in the token, following the Prefix. The key is used to
-	// authenticate the user. We use 'latin1_general_cs` collation to enforce case-senstive queries. The key is omitted from json to prevent it from being transmitted over the wire.
+	// authenticate the user. We use 'latin1_general_cs` collation to enforce case-sensitive queries
+	// in MySQL, see Prefix. The key is omitted from json to prevent it from being transmitted over the wire.
 	Key string `sql:"type:VARCHAR(512) CHARACTER SET latin1 COLLATE latin1_general_cs" json:"-"`
 
 	// Last used time.
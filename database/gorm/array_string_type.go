@@ -1,23 +1,59 @@
 package gorm
 
 import (
+	"context"
 	"database/sql/driver"
 	"errors"
+	"fmt"
 	"strings"
+
+	"github.com/gazebo-web/gz-go/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
-// ArrayString is a custom data type for SQL databases. It adds support for an array of strings to MySQL.
+// ArrayString is a custom data type for SQL databases. It adds support for an array of strings to MySQL, where it's
+// stored as a comma-separated list of elements. In PostgreSQL, it's stored using the native text[] type.
 type ArrayString []string
 
-// Value takes the current ArrayString and converts it to a valid SQL value.
+// Value takes the current ArrayString and converts it to a valid SQL value. Gorm operations use GormValue instead,
+// which takes the database into account.
 //
 // See driver.Valuer for more details.
 func (arr *ArrayString) Value() (driver.Value, error) {
 	return strings.Join(*arr, ","), nil
 }
 
+// GormValue converts the current ArrayString to a SQL value for the database used by db. PostgreSQL values are array
+// literals, such as {"a","b"}, and values for other databases are comma-separated lists of elements. Lists enclosed
+// in braces cannot be told apart from array literals when scanned, and they add an error to db.
+//
+// See gorm.Valuer for more details.
+func (arr ArrayString) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	if db.Dialector.Name() == gz.DriverPostgres {
+		return clause.Expr{SQL: "?", Vars: []interface{}{formatPostgresArray(arr)}}
+	}
+	value := strings.Join(arr, ",")
+	if isArrayLiteral(value) {
+		_ = db.AddError(fmt.Errorf("array string %q cannot be enclosed in braces", value))
+	}
+	return clause.Expr{SQL: "?", Vars: []interface{}{value}}
+}
+
+// GormDBDataType returns the column type used to store ArrayString values in the database used by db.
+// It returns an empty string to use the default column type for strings.
+//
+// See schema.GormDataTypeInterface for more details.
+func (arr ArrayString) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == gz.DriverPostgres {
+		return "text[]"
+	}
+	return ""
+}
+
 // Scan fills out the current ArrayString with the string provided as source. It returns an error if source if not a
-// string.
+// string. PostgreSQL array literals, such as {a,b}, are parsed as arrays, other values as comma-separated lists.
 //
 // See sql.Scanner for more details.
 func (arr *ArrayString) Scan(src any) error {
@@ -25,6 +61,87 @@ func (arr *ArrayString) Scan(src any) error {
 	if !ok {
 		return errors.New("invalid data type, must be a string")
 	}
+	if isArrayLiteral(v) {
+		values, err := parsePostgresArray(v)
+		if err != nil {
+			return err
+		}
+		*arr = values
+		return nil
+	}
 	*arr = strings.Split(v, ",")
 	return nil
 }
+
+// isArrayLiteral returns true if value is enclosed in braces, like PostgreSQL array literals.
+func isArrayLiteral(value string) bool {
+	return strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}")
+}
+
+// formatPostgresArray returns the PostgreSQL array literal of the given values. Every element is quoted.
+func formatPostgresArray(values []string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('"')
+		b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// parsePostgresArray parses a one-dimensional PostgreSQL array literal. NULL elements are returned as empty strings.
+func parsePostgresArray(literal string) ([]string, error) {
+	errInvalid := errors.New("invalid array literal")
+	body := literal[1 : len(literal)-1]
+	out := []string{}
+	if len(body) == 0 {
+		return out, nil
+	}
+
+	for i := 0; i <= len(body); {
+		var element strings.Builder
+		quoted := i < len(body) && body[i] == '"'
+		if quoted {
+			i++
+			for ; i < len(body) && body[i] != '"'; i++ {
+				if body[i] == '\\' {
+					i++
+					if i == len(body) {
+						return nil, errInvalid
+					}
+				}
+				element.WriteByte(body[i])
+			}
+			if i == len(body) {
+				return nil, errInvalid
+			}
+			// Skip the closing quote
+			i++
+		} else {
+			for ; i < len(body) && body[i] != ','; i++ {
+				element.WriteByte(body[i])
+			}
+		}
+
+		value := element.String()
+		if !quoted {
+			value = strings.TrimSpace(value)
+			if strings.EqualFold(value, "NULL") {
+				value = ""
+			}
+		}
+		out = append(out, value)
+
+		if i < len(body) && body[i] != ',' {
+			return nil, errInvalid
+		}
+		// Skip the separator
+		i++
+	}
+	return out, nil
+}
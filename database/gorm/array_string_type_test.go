@@ -3,8 +3,14 @@ package gorm
 import (
 	"database/sql"
 	"database/sql/driver"
-	"github.com/stretchr/testify/assert"
 	"testing"
+
+	"github.com/gazebo-web/gz-go/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 func TestArrayString_Scanner(t *testing.T) {
@@ -33,3 +39,99 @@ func TestArrayString_Valuer(t *testing.T) {
 	assert.Equal(t, "test,test", str)
 
 }
+
+// namedDialector is a MySQL dialector that reports a different database name. It's used to build queries and column
+// types for other databases without their drivers.
+type namedDialector struct {
+	gorm.Dialector
+	name string
+}
+
+func (d namedDialector) Name() string {
+	return d.name
+}
+
+// newDryRunDB returns a database that builds queries for the given database driver without running them.
+func newDryRunDB(t *testing.T, driver string) *gorm.DB {
+	db, err := gorm.Open(namedDialector{
+		Dialector: mysql.New(mysql.Config{SkipInitializeWithVersion: true}),
+		name:      driver,
+	}, &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Discard,
+	})
+	require.NoError(t, err)
+	return db
+}
+
+type arrayStringModel struct {
+	ID   uint
+	Tags ArrayString
+}
+
+func TestArrayString_Dialects(t *testing.T) {
+	cases := []struct {
+		driver   string
+		dataType string
+		value    interface{}
+	}{
+		{driver: gz.DriverMySQL, dataType: "longtext", value: `a,b "c"`},
+		{driver: gz.DriverPostgres, dataType: "text[]", value: `{"a","b \"c\""}`},
+		{driver: "sqlite", dataType: "longtext", value: `a,b "c"`},
+	}
+	for _, c := range cases {
+		t.Run(c.driver, func(t *testing.T) {
+			db := newDryRunDB(t, c.driver)
+
+			stmt := &gorm.Statement{DB: db}
+			require.NoError(t, stmt.Parse(&arrayStringModel{}))
+			dataType := db.Migrator().FullDataTypeOf(stmt.Schema.LookUpField("Tags"))
+			assert.Equal(t, c.dataType, dataType.SQL)
+
+			tx := db.Create(&arrayStringModel{Tags: ArrayString{"a", `b "c"`}})
+			assert.NoError(t, tx.Error)
+			assert.Contains(t, tx.Statement.Vars, c.value)
+
+			// Values are scanned back into the same elements
+			var scanned ArrayString
+			assert.NoError(t, scanned.Scan(c.value))
+			assert.Equal(t, ArrayString{"a", `b "c"`}, scanned)
+		})
+	}
+}
+
+func TestArrayString_Braces(t *testing.T) {
+	// Lists enclosed in braces would be scanned as PostgreSQL array literals
+	db := newDryRunDB(t, gz.DriverMySQL)
+	assert.Error(t, db.Create(&arrayStringModel{Tags: ArrayString{"{a", "b}"}}).Error)
+
+	db = newDryRunDB(t, gz.DriverPostgres)
+	tx := db.Create(&arrayStringModel{Tags: ArrayString{"{a", "b}"}})
+	assert.NoError(t, tx.Error)
+	assert.Contains(t, tx.Statement.Vars, `{"{a","b}"}`)
+}
+
+func TestArrayString_ScanArrayLiteral(t *testing.T) {
+	cases := []struct {
+		literal  string
+		expected ArrayString
+	}{
+		{literal: "{}", expected: ArrayString{}},
+		{literal: "{test}", expected: ArrayString{"test"}},
+		{literal: "{a,b,c}", expected: ArrayString{"a", "b", "c"}},
+		{literal: `{a,"b,c",NULL}`, expected: ArrayString{"a", "b,c", ""}},
+		{literal: `{"",""}`, expected: ArrayString{"", ""}},
+		{literal: `{"back\\slash"}`, expected: ArrayString{`back\slash`}},
+	}
+	for _, c := range cases {
+		var arrstr ArrayString
+		assert.NoError(t, arrstr.Scan(c.literal), c.literal)
+		assert.Equal(t, c.expected, arrstr, c.literal)
+	}
+
+	var arrstr ArrayString
+	assert.Error(t, arrstr.Scan(`{"unterminated}`))
+	assert.Error(t, arrstr.Scan(`{"a"b}`))
+}
@@ -0,0 +1,299 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gazebo-web/gz-go/v10"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidMigration is returned when a migration is missing a version or an Up function.
+	ErrInvalidMigration = errors.New("invalid migration")
+	// ErrDuplicateMigration is returned when two migrations have the same version.
+	ErrDuplicateMigration = errors.New("duplicate migration version")
+	// ErrUnknownMigration is returned when reverting an applied migration that is not known by the Migrator.
+	ErrUnknownMigration = errors.New("unknown migration")
+	// ErrIrreversibleMigration is returned when reverting a migration that does not have a Down function.
+	ErrIrreversibleMigration = errors.New("migration cannot be reverted")
+	// ErrNoAppliedMigrations is returned when reverting a migration and no migrations have been applied.
+	ErrNoAppliedMigrations = errors.New("no migrations have been applied")
+	// ErrMigrationLocked is returned when the migration lock cannot be acquired before the lock timeout expires.
+	ErrMigrationLocked = errors.New("migration lock is held by another process")
+)
+
+const (
+	// migrationLockName is the name of the database lock used to prevent concurrent migrations.
+	migrationLockName = "schema_migrations"
+	// migrationLockKey is the key of the PostgreSQL advisory lock used to prevent concurrent migrations.
+	migrationLockKey int64 = 0x736368656d61
+	// migrationLockPollInterval is the amount of time to wait between attempts to acquire the PostgreSQL advisory lock.
+	migrationLockPollInterval = time.Second
+	// defaultMigrationLockTimeout is the default amount of time to wait for the migration lock.
+	defaultMigrationLockTimeout = time.Minute
+)
+
+// Migration is a versioned change to the database schema or data.
+type Migration struct {
+	// Version identifies the migration. Migrations are applied in ascending version order.
+	// Using the creation timestamp of the migration as version (e.g. 20240102150405) is recommended.
+	Version uint64
+	// Name contains a short description of the migration.
+	Name string
+	// Up applies the migration.
+	Up func(tx *gorm.DB) error
+	// Down reverts the changes applied by Up. Migrations without a Down function cannot be reverted.
+	Down func(tx *gorm.DB) error
+}
+
+// SchemaMigration is the record of an applied Migration.
+type SchemaMigration struct {
+	Version   uint64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// TableName returns the name of the table used to keep track of applied migrations.
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigratorOption contains logic that can be passed to NewMigrator to modify a Migrator.
+type MigratorOption func(m *Migrator)
+
+// LockTimeout sets the amount of time a Migrator waits for other processes to finish migrating before failing with
+// ErrMigrationLocked. The default timeout is one minute.
+func LockTimeout(timeout time.Duration) MigratorOption {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+// Migrator applies and reverts versioned migrations, keeping track of the applied migrations in the
+// schema_migrations table.
+//
+// Operations that change the database hold a database lock while they run, so only one process migrates the
+// database at a time. Locking is supported on MySQL and PostgreSQL. Other databases are migrated without a lock.
+type Migrator struct {
+	db          *gorm.DB
+	migrations  []Migration
+	lockTimeout time.Duration
+}
+
+// NewMigrator initializes a new Migrator that runs the given migrations in db.
+// It returns ErrInvalidMigration or ErrDuplicateMigration if the list of migrations is not valid.
+func NewMigrator(db *gorm.DB, migrations []Migration, opts ...MigratorOption) (*Migrator, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	for i, migration := range sorted {
+		if migration.Version == 0 || migration.Up == nil {
+			return nil, fmt.Errorf("%w: migration %d %q requires a version and an Up function", ErrInvalidMigration, migration.Version, migration.Name)
+		}
+		if i > 0 && sorted[i-1].Version == migration.Version {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateMigration, migration.Version)
+		}
+	}
+
+	m := &Migrator{
+		db:          db,
+		migrations:  sorted,
+		lockTimeout: defaultMigrationLockTimeout,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// Up applies all pending migrations in ascending version order. Migrations that have a lower version than an already
+// applied migration are also applied.
+//
+// Each migration runs in its own transaction together with its schema_migrations record. Note that some databases,
+// such as MySQL, implicitly commit schema changes, so a failed migration can be partially applied.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(tx *gorm.DB) error {
+		pending, err := m.pending(tx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			log.Printf("Migrator: Applying migration %d %s\n", migration.Version, migration.Name)
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				if err := migration.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					AppliedAt: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				log.Printf("Migrator: Error while applying migration %d %s, error: %s\n", migration.Version, migration.Name, err)
+				return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Down reverts the most recently applied migration.
+// It returns ErrNoAppliedMigrations if there are no applied migrations, ErrUnknownMigration if the applied migration
+// is not part of the Migrator migrations, and ErrIrreversibleMigration if the migration does not have a Down function.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(tx *gorm.DB) error {
+		var last SchemaMigration
+		err := tx.Order("version DESC").Take(&last).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNoAppliedMigrations
+		}
+		if err != nil {
+			return err
+		}
+
+		i := sort.Search(len(m.migrations), func(i int) bool {
+			return m.migrations[i].Version >= last.Version
+		})
+		if i == len(m.migrations) || m.migrations[i].Version != last.Version {
+			return fmt.Errorf("%w: %d %s", ErrUnknownMigration, last.Version, last.Name)
+		}
+		migration := m.migrations[i]
+		if migration.Down == nil {
+			return fmt.Errorf("%w: %d %s", ErrIrreversibleMigration, migration.Version, migration.Name)
+		}
+
+		log.Printf("Migrator: Reverting migration %d %s\n", migration.Version, migration.Name)
+		err = tx.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{Version: migration.Version}).Error
+		})
+		if err != nil {
+			log.Printf("Migrator: Error while reverting migration %d %s, error: %s\n", migration.Version, migration.Name, err)
+			return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		return nil
+	})
+}
+
+// Pending returns the migrations that would be applied by Up, in the order they would be applied. It does not change
+// the database, and can be used to perform a dry run.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	tx := m.db.WithContext(ctx)
+	if !tx.Migrator().HasTable(&SchemaMigration{}) {
+		out := make([]Migration, len(m.migrations))
+		copy(out, m.migrations)
+		return out, nil
+	}
+	return m.pending(tx)
+}
+
+// Applied returns the records of the applied migrations in ascending version order.
+func (m *Migrator) Applied(ctx context.Context) ([]SchemaMigration, error) {
+	tx := m.db.WithContext(ctx)
+	if !tx.Migrator().HasTable(&SchemaMigration{}) {
+		return nil, nil
+	}
+	var applied []SchemaMigration
+	if err := tx.Order("version ASC").Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// pending returns the migrations that have not been applied yet.
+func (m *Migrator) pending(tx *gorm.DB) ([]Migration, error) {
+	var versions []uint64
+	if err := tx.Model(&SchemaMigration{}).Pluck("version", &versions).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[uint64]bool, len(versions))
+	for _, v := range versions {
+		applied[v] = true
+	}
+	var out []Migration
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			out = append(out, migration)
+		}
+	}
+	return out, nil
+}
+
+// withLock calls fn while holding the migration lock. The schema_migrations table is created if it doesn't exist.
+// fn receives a database session bound to the single connection that holds the lock.
+func (m *Migrator) withLock(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(tx *gorm.DB) error {
+		if err := m.lock(tx); err != nil {
+			return err
+		}
+		defer func() {
+			if err := m.unlock(tx); err != nil {
+				log.Println("Migrator: Error while releasing the migration lock, error:", err)
+			}
+		}()
+
+		if err := tx.AutoMigrate(&SchemaMigration{}); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// lock acquires the migration lock in the connection used by tx.
+func (m *Migrator) lock(tx *gorm.DB) error {
+	switch tx.Dialector.Name() {
+	case gz.DriverMySQL:
+		var acquired *int
+		err := tx.Raw("SELECT GET_LOCK(?, ?)", migrationLockName, int(m.lockTimeout.Seconds())).Scan(&acquired).Error
+		if err != nil {
+			return err
+		}
+		if acquired == nil || *acquired != 1 {
+			return ErrMigrationLocked
+		}
+	case gz.DriverPostgres:
+		// PostgreSQL advisory locks don't support timeouts, so the lock is polled until the timeout expires.
+		deadline := time.Now().Add(m.lockTimeout)
+		for {
+			var acquired bool
+			if err := tx.Raw("SELECT pg_try_advisory_lock(?)", migrationLockKey).Scan(&acquired).Error; err != nil {
+				return err
+			}
+			if acquired {
+				return nil
+			}
+			if !time.Now().Before(deadline) {
+				return ErrMigrationLocked
+			}
+			select {
+			case <-tx.Statement.Context.Done():
+				return tx.Statement.Context.Err()
+			case <-time.After(migrationLockPollInterval):
+			}
+		}
+	}
+	return nil
+}
+
+// unlock releases the migration lock held by the connection used by tx.
+func (m *Migrator) unlock(tx *gorm.DB) error {
+	switch tx.Dialector.Name() {
+	case gz.DriverMySQL:
+		var released *int
+		return tx.Raw("SELECT RELEASE_LOCK(?)", migrationLockName).Scan(&released).Error
+	case gz.DriverPostgres:
+		var released bool
+		return tx.Raw("SELECT pg_advisory_unlock(?)", migrationLockKey).Scan(&released).Error
+	}
+	return nil
+}
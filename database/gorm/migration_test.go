@@ -0,0 +1,199 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+func noopMigration(tx *gorm.DB) error {
+	return nil
+}
+
+func TestNewMigrator(t *testing.T) {
+	m, err := NewMigrator(nil, []Migration{
+		{Version: 3, Name: "third", Up: noopMigration},
+		{Version: 1, Name: "first", Up: noopMigration},
+		{Version: 2, Name: "second", Up: noopMigration},
+	})
+	require.NoError(t, err)
+	require.Len(t, m.migrations, 3)
+	assert.Equal(t, uint64(1), m.migrations[0].Version)
+	assert.Equal(t, uint64(2), m.migrations[1].Version)
+	assert.Equal(t, uint64(3), m.migrations[2].Version)
+	assert.Equal(t, defaultMigrationLockTimeout, m.lockTimeout)
+
+	_, err = NewMigrator(nil, []Migration{{Version: 1, Up: noopMigration}, {Version: 1, Up: noopMigration}})
+	assert.ErrorIs(t, err, ErrDuplicateMigration)
+
+	_, err = NewMigrator(nil, []Migration{{Version: 0, Up: noopMigration}})
+	assert.ErrorIs(t, err, ErrInvalidMigration)
+
+	_, err = NewMigrator(nil, []Migration{{Version: 1}})
+	assert.ErrorIs(t, err, ErrInvalidMigration)
+}
+
+type migrationTestModel struct {
+	ID   uint
+	Name string
+}
+
+func (migrationTestModel) TableName() string {
+	return "migration_test_model"
+}
+
+func TestMigrator(t *testing.T) {
+	suite.Run(t, new(MigratorTestSuite))
+}
+
+type MigratorTestSuite struct {
+	suite.Suite
+	db         *gorm.DB
+	ctx        context.Context
+	migrations []Migration
+}
+
+func (s *MigratorTestSuite) SetupSuite() {
+	var err error
+	s.db, err = GetTestDBFromEnvVars()
+	s.Require().NoError(err)
+	s.ctx = context.Background()
+}
+
+func (s *MigratorTestSuite) SetupTest() {
+	s.Require().NoError(s.db.Migrator().DropTable(&SchemaMigration{}, &migrationTestModel{}))
+	s.migrations = []Migration{
+		{
+			Version: 1,
+			Name:    "create migration_test_model",
+			Up: func(tx *gorm.DB) error {
+				return tx.Migrator().CreateTable(&migrationTestModel{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&migrationTestModel{})
+			},
+		},
+		{
+			Version: 2,
+			Name:    "insert default entry",
+			Up: func(tx *gorm.DB) error {
+				return tx.Create(&migrationTestModel{Name: "default"}).Error
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Where("name = ?", "default").Delete(&migrationTestModel{}).Error
+			},
+		},
+	}
+}
+
+func (s *MigratorTestSuite) TearDownSuite() {
+	s.Require().NoError(s.db.Migrator().DropTable(&SchemaMigration{}, &migrationTestModel{}))
+	sqlDb, err := s.db.DB()
+	s.Require().NoError(err)
+	s.Require().NoError(sqlDb.Close())
+}
+
+func (s *MigratorTestSuite) TestUp() {
+	m, err := NewMigrator(s.db, s.migrations)
+	s.Require().NoError(err)
+
+	pending, err := m.Pending(s.ctx)
+	s.Require().NoError(err)
+	s.Assert().Len(pending, 2)
+	// Pending doesn't change the database
+	s.Assert().False(s.db.Migrator().HasTable(&SchemaMigration{}))
+
+	s.Require().NoError(m.Up(s.ctx))
+	var count int64
+	s.Require().NoError(s.db.Model(&migrationTestModel{}).Count(&count).Error)
+	s.Assert().Equal(int64(1), count)
+
+	applied, err := m.Applied(s.ctx)
+	s.Require().NoError(err)
+	s.Require().Len(applied, 2)
+	s.Assert().Equal(uint64(1), applied[0].Version)
+	s.Assert().Equal("insert default entry", applied[1].Name)
+
+	// Applied migrations are not applied again
+	s.Require().NoError(m.Up(s.ctx))
+	s.Require().NoError(s.db.Model(&migrationTestModel{}).Count(&count).Error)
+	s.Assert().Equal(int64(1), count)
+
+	pending, err = m.Pending(s.ctx)
+	s.Require().NoError(err)
+	s.Assert().Empty(pending)
+}
+
+func (s *MigratorTestSuite) TestUp_Error() {
+	errMigration := errors.New("migration error")
+	s.migrations = append(s.migrations, Migration{
+		Version: 3,
+		Name:    "fail",
+		Up: func(tx *gorm.DB) error {
+			return errMigration
+		},
+	})
+	m, err := NewMigrator(s.db, s.migrations)
+	s.Require().NoError(err)
+
+	s.Assert().ErrorIs(m.Up(s.ctx), errMigration)
+
+	applied, err := m.Applied(s.ctx)
+	s.Require().NoError(err)
+	s.Assert().Len(applied, 2)
+}
+
+func (s *MigratorTestSuite) TestDown() {
+	m, err := NewMigrator(s.db, s.migrations)
+	s.Require().NoError(err)
+	s.Assert().ErrorIs(m.Down(s.ctx), ErrNoAppliedMigrations)
+	s.Require().NoError(m.Up(s.ctx))
+
+	s.Require().NoError(m.Down(s.ctx))
+	var count int64
+	s.Require().NoError(s.db.Model(&migrationTestModel{}).Count(&count).Error)
+	s.Assert().Zero(count)
+
+	s.Require().NoError(m.Down(s.ctx))
+	s.Assert().False(s.db.Migrator().HasTable(&migrationTestModel{}))
+
+	pending, err := m.Pending(s.ctx)
+	s.Require().NoError(err)
+	s.Assert().Len(pending, 2)
+}
+
+func (s *MigratorTestSuite) TestDown_Irreversible() {
+	s.migrations[1].Down = nil
+	m, err := NewMigrator(s.db, s.migrations)
+	s.Require().NoError(err)
+	s.Require().NoError(m.Up(s.ctx))
+
+	s.Assert().ErrorIs(m.Down(s.ctx), ErrIrreversibleMigration)
+
+	// Migrators that don't know the last applied migration cannot revert it
+	m, err = NewMigrator(s.db, s.migrations[:1])
+	s.Require().NoError(err)
+	s.Assert().ErrorIs(m.Down(s.ctx), ErrUnknownMigration)
+}
+
+func (s *MigratorTestSuite) TestLock() {
+	m, err := NewMigrator(s.db, s.migrations, LockTimeout(0))
+	s.Require().NoError(err)
+
+	// Hold the lock from a different connection
+	s.Require().NoError(s.db.Connection(func(tx *gorm.DB) error {
+		s.Require().NoError(m.lock(tx))
+		defer func() {
+			s.Require().NoError(m.unlock(tx))
+		}()
+		s.Assert().ErrorIs(m.Up(s.ctx), ErrMigrationLocked)
+		return nil
+	}))
+
+	s.Require().NoError(m.Up(s.ctx))
+}
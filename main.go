@@ -2,17 +2,18 @@ package gz
 
 // Import this file's dependencies
 import (
-	"database/sql"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gazebo-web/gz-go/v10/monitoring"
@@ -84,8 +85,58 @@ func (s *Server) IsUsingSSL() bool {
 	return s.SSLCert != "" && s.SSLKey != ""
 }
 
+const (
+	// DriverMySQL is the name of the MySQL database driver.
+	DriverMySQL = "mysql"
+	// DriverPostgres is the name of the PostgreSQL database driver.
+	DriverPostgres = "postgres"
+)
+
+// DialectorFunc returns a Gorm dialector that connects to the database identified by dsn.
+// Gorm database drivers provide an Open function with this signature, such as mysql.Open and postgres.Open.
+type DialectorFunc func(dsn string) gorm.Dialector
+
+// ErrUnsupportedDriver is returned when connecting to a database configured with a driver that doesn't have a
+// registered dialector.
+var ErrUnsupportedDriver = errors.New("unsupported database driver")
+
+var (
+	// dialectors contains the dialectors used to connect to each database driver.
+	dialectors = map[string]DialectorFunc{
+		DriverMySQL: mysql.Open,
+	}
+	// dialectorsLock guards dialectors.
+	dialectorsLock sync.RWMutex
+)
+
+// RegisterDialector sets the Gorm dialector used to connect to databases configured with the given driver.
+// The MySQL dialector is registered by default. Applications connecting to PostgreSQL must register the Gorm
+// PostgreSQL driver before initializing the database:
+//
+//	gz.RegisterDialector(gz.DriverPostgres, postgres.Open)
+func RegisterDialector(driver string, fn DialectorFunc) {
+	dialectorsLock.Lock()
+	defer dialectorsLock.Unlock()
+	dialectors[driver] = fn
+}
+
+// getDialector returns the dialector registered for the given driver. It returns ErrUnsupportedDriver if no dialector
+// has been registered.
+func getDialector(driver string) (DialectorFunc, error) {
+	dialectorsLock.RLock()
+	defer dialectorsLock.RUnlock()
+	fn, ok := dialectors[driver]
+	if !ok {
+		return nil, fmt.Errorf("%w: no dialector registered for %s", ErrUnsupportedDriver, driver)
+	}
+	return fn, nil
+}
+
 // DatabaseConfig contains information about a database connection
 type DatabaseConfig struct {
+	// Driver is the database driver used to connect to the database. Supported values are DriverMySQL and
+	// DriverPostgres. If empty, DriverMySQL is used.
+	Driver string
 	// Username to login to a database.
 	UserName string
 	// Password to login to a database.
@@ -107,6 +158,45 @@ type DatabaseConfig struct {
 	EnableLog bool
 }
 
+// driver returns the database driver of the configuration, defaulting to DriverMySQL.
+func (cfg *DatabaseConfig) driver() string {
+	if len(cfg.Driver) == 0 {
+		return DriverMySQL
+	}
+	return cfg.Driver
+}
+
+// DSN returns the data source name used to connect to the configured database.
+func (cfg *DatabaseConfig) DSN() (string, error) {
+	return cfg.dsn(cfg.Name)
+}
+
+// dsn returns the data source name used to connect to the given database of the configured server.
+// If name is empty, the DSN connects to the server without selecting a database.
+func (cfg *DatabaseConfig) dsn(name string) (string, error) {
+	switch cfg.driver() {
+	case DriverMySQL:
+		return fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8&parseTime=True&loc=UTC",
+			cfg.UserName, cfg.Password, cfg.Address, name), nil
+	case DriverPostgres:
+		// PostgreSQL connections always target a database, the default "postgres" database is used to connect to the
+		// server.
+		if len(name) == 0 {
+			name = "postgres"
+		}
+		u := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(cfg.UserName, cfg.Password),
+			Host:     cfg.Address,
+			Path:     name,
+			RawQuery: "TimeZone=UTC",
+		}
+		return u.String(), nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedDriver, cfg.Driver)
+	}
+}
+
 // gServer is an internal pointer to the Server.
 var gServer *Server
 
@@ -237,6 +327,7 @@ func ReadRollbarLogVerbosityEnvVar() int {
 }
 
 // NewDatabaseConfigFromEnvVars returns a DatabaseConfig object from the following env vars:
+// - IGN_DB_DRIVER - (Optional) Database driver, either "mysql" or "postgres". Defaults to "mysql".
 // - IGN_DB_USERNAME
 // - IGN_DB_PASSWORD
 // - IGN_DB_ADDRESS
@@ -246,6 +337,14 @@ func NewDatabaseConfigFromEnvVars() (DatabaseConfig, error) {
 	dbConfig := DatabaseConfig{}
 	var err error
 
+	// Get the database driver
+	if dbConfig.Driver, err = ReadEnvVar("IGN_DB_DRIVER"); err != nil {
+		dbConfig.Driver = DriverMySQL
+	}
+	if dbConfig.Driver != DriverMySQL && dbConfig.Driver != DriverPostgres {
+		return dbConfig, fmt.Errorf("invalid IGN_DB_DRIVER env variable %q. Database connection will not work", dbConfig.Driver)
+	}
+
 	// Get the database username
 	if dbConfig.UserName, err = ReadEnvVar("IGN_DB_USERNAME"); err != nil {
 		errMsg := "Missing IGN_DB_USERNAME env variable. Database connection will not work"
@@ -394,20 +493,24 @@ func (s *Server) initTests() {
 }
 
 // connectDb is called to establish a db connection. The target database is created if it doesn't exist.
-func connectDb(driver, dsn string, cfg *DatabaseConfig) (*gorm.DB, error) {
-
-	// Queries
-	queryCreate := fmt.Sprintf("CREATE DATABASE %s", cfg.Name)
-	queryUse := fmt.Sprintf("USE %s", cfg.Name)
+func connectDb(cfg *DatabaseConfig) (*gorm.DB, error) {
+	open, err := getDialector(cfg.driver())
+	if err != nil {
+		return nil, err
+	}
 
-	// Open db connection
-	sqlDB, err := sql.Open(driver, dsn)
+	// Connect to the server to create the database
+	dsn, err := cfg.dsn("")
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(open(dsn), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("unable to connect to the database: %w", err)
 	}
 
 	// Execute db creation
-	_, err = sqlDB.Exec(queryCreate)
+	err = db.Exec(fmt.Sprintf("CREATE DATABASE %s", cfg.Name)).Error
 
 	// If the step before does not throw any errors, it means that the database was successfully created
 	// In the other hand, if it throws an error, it means that the database already exists
@@ -415,25 +518,14 @@ func connectDb(driver, dsn string, cfg *DatabaseConfig) (*gorm.DB, error) {
 		log.Printf("[SUCCESS] Database %s was successfully created. Trying to connect once again...\n", cfg.Name)
 	}
 
-	// We have ensured that the database was created, let's use it.
-	_, err = sqlDB.Exec(queryUse)
-
-	// If there was an error, it means that the database is not available
-	if err != nil {
-		return nil, errors.New("unable to interact with the new database")
-	}
-
 	// Close and reopen the DB to the correct database.
-	_ = sqlDB.Close()
-	dsn = fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8&parseTime=True&loc=UTC", cfg.UserName, cfg.Password, cfg.Address, cfg.Name)
-	sqlDB, err = sql.Open(driver, dsn)
-	if err != nil {
-		return nil, fmt.Errorf("unable to connect to the database: %w", err)
+	if sqlDB, err := db.DB(); err == nil {
+		_ = sqlDB.Close()
 	}
-
-	db, err := gorm.Open(mysql.New(mysql.Config{
-		Conn: sqlDB,
-	}), &gorm.Config{})
+	if dsn, err = cfg.DSN(); err != nil {
+		return nil, err
+	}
+	db, err = gorm.Open(open(dsn), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("unable to connect to database: %w", err)
 	}
@@ -442,23 +534,20 @@ func connectDb(driver, dsn string, cfg *DatabaseConfig) (*gorm.DB, error) {
 }
 
 // InitDbWithCfg initialize the database connection based on the given cfg.
+// Failed connections are retried, except when the configured driver is not supported, in which case
+// ErrUnsupportedDriver is returned immediately.
 func InitDbWithCfg(cfg *DatabaseConfig) (*gorm.DB, error) {
-	// Connect to the database
-	url := fmt.Sprintf("%s:%s@tcp(%s)/?charset=utf8&parseTime=True&loc=UTC",
-		cfg.UserName, cfg.Password, cfg.Address)
-
 	var err error
 	var db *gorm.DB
 
 	for i := 0; i < 10; i++ {
-		db, err = connectDb("mysql", url, cfg)
+		db, err = connectDb(cfg)
 
-		if err == nil {
+		if err == nil || errors.Is(err, ErrUnsupportedDriver) {
 			break
 		}
 
-		log.Printf("Attempt[%d] to connect to the database failed.\n", i)
-		log.Println(url)
+		log.Printf("Attempt[%d] to connect to the %s database at %s failed.\n", i, cfg.driver(), cfg.Address)
 		log.Println(err)
 		time.Sleep(5 * time.Second)
 	}
@@ -103,6 +103,16 @@ func ParsePageTokenToTime(token string) (time.Time, error) {
 	return result, err
 }
 
+// ParsePageToken decodes the given base64 token and unmarshals its contents into out.
+// It is the counterpart of NewPageToken.
+func ParsePageToken(token string, out encoding.TextUnmarshaler) error {
+	value, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return err
+	}
+	return out.UnmarshalText(value)
+}
+
 // GetNextPageTokenFromTime generates a page token. This function should be used when generating a next page token
 // based on a `time.Time` type field.
 func GetNextPageTokenFromTime(t time.Time) string {
@@ -104,6 +104,22 @@ func TestParsePageTokenToTime(t *testing.T) {
 	assert.True(t, result.Equal(updatedAt))
 }
 
+func TestParsePageToken(t *testing.T) {
+	// Not base64
+	var result time.Time
+	assert.Error(t, ParsePageToken("not base64!", &result))
+
+	// Not a date
+	assert.Error(t, ParsePageToken(base64.StdEncoding.EncodeToString([]byte("12345")), &result))
+
+	// A valid token should be unmarshalled into the given value.
+	updatedAt := time.Now()
+	token := NewPageToken(updatedAt)
+
+	require.NoError(t, ParsePageToken(token, &result))
+	assert.True(t, result.Equal(updatedAt))
+}
+
 func TestGetNextPageToken(t *testing.T) {
 	var zero time.Time
 	assert.Empty(t, GetNextPageTokenFromTime(zero))
@@ -0,0 +1,14 @@
+package repository
+
+import "context"
+
+// Aggregator is implemented by Repository implementations that support aggregation queries.
+// Aggregations are computed by the persistence layer, without loading the matching entries.
+type Aggregator interface {
+	// Sum returns the sum of the values of field in the entries that match filters.
+	// It returns 0 if no entries match filters.
+	Sum(ctx context.Context, field string, filters ...Filter) (float64, error)
+	// Avg returns the average of the values of field in the entries that match filters.
+	// It returns ErrNotFound if no entries match filters.
+	Avg(ctx context.Context, field string, filters ...Filter) (float64, error)
+}
@@ -0,0 +1,386 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/gazebo-web/gz-go/v10/middleware"
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"github.com/gazebo-web/gz-go/v10/structs"
+)
+
+// fieldID is the name of the field used to read entries after they have been updated.
+const fieldID = "id"
+
+// ErrUnidentifiedEntry is returned when a changed entry cannot be identified by its GetID method.
+// Repositories whose models are not identified by GetID, such as Firestore repositories, cannot be audited.
+var ErrUnidentifiedEntry = errors.New("audited entries must be identified by GetID")
+
+// Operation identifies the kind of change recorded by an Entry.
+type Operation string
+
+const (
+	// OperationCreate is recorded when entries are created.
+	OperationCreate Operation = "create"
+	// OperationUpsert is recorded when an entry is upserted.
+	OperationUpsert Operation = "upsert"
+	// OperationUpdate is recorded when entries are updated.
+	OperationUpdate Operation = "update"
+	// OperationDelete is recorded when entries are deleted.
+	OperationDelete Operation = "delete"
+)
+
+// Entry is an audit log entry. It records a single change performed by a Repository operation.
+type Entry struct {
+	// Actor contains the subject that performed the operation. It is empty if the actor could not be identified.
+	Actor string
+	// Timestamp contains the date and time at which the operation was performed.
+	Timestamp time.Time
+	// Operation contains the kind of operation performed.
+	Operation Operation
+	// Resource contains the table/collection name of the changed entries.
+	Resource string
+	// Filters contains the selection criteria used by update operations.
+	Filters []repository.Filter
+	// Changes contains the state of every changed entry.
+	Changes []Change
+}
+
+// Change contains the difference between the state of an entry before and after an operation.
+// Entries are converted into maps using structs.ToMap.
+type Change struct {
+	// ID contains the identifier of the changed entry.
+	ID uint `json:"id"`
+	// Before contains the fields of the entry that were changed by the operation, with their previous values.
+	// It is nil for created entries.
+	Before map[string]any `json:"before,omitempty"`
+	// After contains the fields of the entry that were changed by the operation, with their new values.
+	// It is nil for deleted entries.
+	After map[string]any `json:"after,omitempty"`
+}
+
+// Sink stores audit log entries.
+type Sink interface {
+	// Write stores the given entry. ctx is the context of the audited operation.
+	Write(ctx context.Context, entry Entry) error
+}
+
+// ActorFunc returns the subject performing an operation from the operation context.
+type ActorFunc func(ctx context.Context) (string, error)
+
+// Option contains logic that can be passed to NewRepository to modify the audited Repository.
+type Option func(r *auditRepository)
+
+// WithActor sets the function used to identify the subject performing an operation.
+// By default, the subject is extracted with middleware.ExtractGRPCAuthSubject.
+func WithActor(actor ActorFunc) Option {
+	return func(r *auditRepository) {
+		r.actor = actor
+	}
+}
+
+// WithTransactioner sets the repository.Transactioner used to run audited operations. Reading the state of the entries
+// before and after they are changed, performing the change and writing the audit log entry are run inside a single
+// transaction. Pass the Transactioner of the audited Repository implementation, for example sql.NewTransactioner.
+func WithTransactioner(tr repository.Transactioner) Option {
+	return func(r *auditRepository) {
+		r.transactioner = tr
+	}
+}
+
+// NewRepository initializes a new repository.Repository that records every change performed in the given Repository
+// in sink.
+//
+// where converts a repository.Expression into an Option of the given Repository, and it's used to read the state of
+// entries before and after they are changed. Pass the WhereExpression Option of the Repository implementation, for
+// example sql.WhereExpression. Audited updates only support filters defined with repository.NewFilter.
+//
+// Audited updates and deletes read every affected entry before changing it, and return repository.ErrNoFilter when
+// called without filters instead of reading the whole table.
+//
+// Changed entries are identified by their GetID method, and read back after updates using an "id" field. Operations
+// on entries with a zero ID, such as entries of Firestore repositories, return ErrUnidentifiedEntry.
+//
+// Entries are written after the operation succeeds. Unless a repository.Transactioner is set with WithTransactioner,
+// the state of the entries is read in separate operations, and concurrent changes may be recorded as part of the
+// audited operation. An error returned by the sink is returned by the operation, but the change is only reverted if a
+// Transactioner is set and the Sink participates in its transactions, such as the one returned by NewSQLSink.
+//
+// The returned Repository implements repository.ConditionalUpdater if the given Repository implements it. It also
+// implements repository.FirstOrCreator, which returns repository.ErrUnsupportedOperation if the given Repository does
+// not implement it.
+func NewRepository(repo repository.Repository, sink Sink, where func(expr repository.Expression) repository.Option, opts ...Option) repository.Repository {
+	r := &auditRepository{
+		Repository: repo,
+		sink:       sink,
+		where:      where,
+		actor:      middleware.ExtractGRPCAuthSubject,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if _, ok := repo.(repository.ConditionalUpdater); ok {
+		return &conditionalAuditRepository{auditRepository: r}
+	}
+	return r
+}
+
+// auditRepository implements repository.Repository by wrapping a Repository. Read operations are forwarded to the
+// wrapped Repository by the embedded interface.
+type auditRepository struct {
+	repository.Repository
+	sink  Sink
+	where func(expr repository.Expression) repository.Option
+	actor ActorFunc
+	// transactioner is used to run audited operations inside a transaction. It is nil if operations should not be run
+	// inside a transaction.
+	transactioner repository.Transactioner
+}
+
+// Ensure that auditRepository implements the repository.FirstOrCreator interface.
+var _ repository.FirstOrCreator = (*auditRepository)(nil)
+
+// FirstOrCreate inserts a new entry if the given filters don't find any existing record.
+// The operation is only recorded if the entry was created.
+func (r *auditRepository) FirstOrCreate(ctx context.Context, entity repository.Model, filters ...repository.Filter) error {
+	_, err := r.FirstOrCreateAndReport(ctx, entity, filters...)
+	return err
+}
+
+// FirstOrCreateAndReport inserts a new entry if the given filters don't find any existing record, and returns true if
+// the entry was created. The operation is only recorded if the entry was created.
+func (r *auditRepository) FirstOrCreateAndReport(ctx context.Context, entity repository.Model, filters ...repository.Filter) (bool, error) {
+	creator, ok := r.Repository.(repository.FirstOrCreator)
+	if !ok {
+		return false, repository.ErrUnsupportedOperation
+	}
+	var created bool
+	err := r.transaction(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = creator.FirstOrCreateAndReport(ctx, entity, filters...)
+		if err != nil || !created {
+			return err
+		}
+		return r.recordCreate(ctx, OperationCreate, []repository.Model{entity})
+	})
+	return created, err
+}
+
+// Create inserts a single entry.
+func (r *auditRepository) Create(ctx context.Context, entity repository.Model) (repository.Model, error) {
+	result, err := r.CreateBulk(ctx, []repository.Model{entity})
+	if err != nil {
+		return nil, err
+	}
+	return result[0], nil
+}
+
+// CreateBulk creates multiple entries with a single operation.
+func (r *auditRepository) CreateBulk(ctx context.Context, entities []repository.Model) ([]repository.Model, error) {
+	var created []repository.Model
+	err := r.transaction(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = r.Repository.CreateBulk(ctx, entities)
+		if err != nil {
+			return err
+		}
+		return r.recordCreate(ctx, OperationCreate, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Upsert inserts a single entry, or updates the existing entry that conflicts with it.
+// Only the state of the entry after the operation is recorded.
+func (r *auditRepository) Upsert(ctx context.Context, entity repository.Model, conflictFields ...string) error {
+	return r.transaction(ctx, func(ctx context.Context) error {
+		if err := r.Repository.Upsert(ctx, entity, conflictFields...); err != nil {
+			return err
+		}
+		return r.recordCreate(ctx, OperationUpsert, []repository.Model{entity})
+	})
+}
+
+// Update updates all model entries that match the provided filters with the given data.
+func (r *auditRepository) Update(ctx context.Context, data interface{}, filters ...repository.Filter) error {
+	return r.update(ctx, filters, func(ctx context.Context) error {
+		return r.Repository.Update(ctx, data, filters...)
+	})
+}
+
+// Delete removes all the model entries that match filters.
+func (r *auditRepository) Delete(ctx context.Context, opts ...repository.Option) error {
+	if len(opts) == 0 {
+		return repository.ErrNoFilter
+	}
+	return r.transaction(ctx, func(ctx context.Context) error {
+		ids, before, err := r.snapshot(ctx, opts...)
+		if err != nil {
+			return err
+		}
+		if err := r.Repository.Delete(ctx, opts...); err != nil {
+			return err
+		}
+		changes := make([]Change, len(ids))
+		for i, id := range ids {
+			changes[i] = Change{ID: id, Before: before[id]}
+		}
+		return r.record(ctx, OperationDelete, nil, changes)
+	})
+}
+
+// transaction runs fn inside a transaction of the Transactioner set with WithTransactioner. If no Transactioner has
+// been set, fn is run with the given context.
+func (r *auditRepository) transaction(ctx context.Context, fn repository.TransactionFunc) error {
+	if r.transactioner == nil {
+		return fn(ctx)
+	}
+	return r.transactioner.WithTransaction(ctx, fn)
+}
+
+// update records the changes performed by fn in the entries that match filters.
+func (r *auditRepository) update(ctx context.Context, filters []repository.Filter, fn repository.TransactionFunc) error {
+	if len(filters) == 0 {
+		return repository.ErrNoFilter
+	}
+	opts := make([]repository.Option, len(filters))
+	for i, f := range filters {
+		if f.Expression == nil {
+			return fmt.Errorf("%w: audited updates require filter expressions", repository.ErrUnsupportedExpression)
+		}
+		opts[i] = r.where(f.Expression)
+	}
+	return r.transaction(ctx, func(ctx context.Context) error {
+		ids, before, err := r.snapshot(ctx, opts...)
+		if err != nil {
+			return err
+		}
+		if err := fn(ctx); err != nil {
+			return err
+		}
+
+		var after map[uint]map[string]any
+		if len(ids) > 0 {
+			if _, after, err = r.snapshot(ctx, r.where(repository.In(fieldID, ids))); err != nil {
+				return err
+			}
+		}
+		changes := make([]Change, 0, len(ids))
+		for _, id := range ids {
+			b, a := diff(before[id], after[id])
+			if len(b) == 0 && len(a) == 0 {
+				continue
+			}
+			changes = append(changes, Change{ID: id, Before: b, After: a})
+		}
+		return r.record(ctx, OperationUpdate, filters, changes)
+	})
+}
+
+// recordCreate records the state of the given entities after they have been created.
+func (r *auditRepository) recordCreate(ctx context.Context, op Operation, entities []repository.Model) error {
+	changes := make([]Change, len(entities))
+	for i, entity := range entities {
+		after, err := structs.ToMap(entity)
+		if err != nil {
+			return err
+		}
+		if entity.GetID() == 0 {
+			return ErrUnidentifiedEntry
+		}
+		changes[i] = Change{ID: entity.GetID(), After: after}
+	}
+	return r.record(ctx, op, nil, changes)
+}
+
+// record writes a new audit log entry to the sink.
+func (r *auditRepository) record(ctx context.Context, op Operation, filters []repository.Filter, changes []Change) error {
+	// Operations performed without an identified actor are still recorded.
+	actor, err := r.actor(ctx)
+	if err != nil {
+		actor = ""
+	}
+	return r.sink.Write(ctx, Entry{
+		Actor:     actor,
+		Timestamp: time.Now(),
+		Operation: op,
+		Resource:  r.Model().TableName(),
+		Filters:   filters,
+		Changes:   changes,
+	})
+}
+
+// snapshot reads the entries found using the given options, and converts them into maps using structs.ToMap.
+// It returns the identifiers of the entries in the order they were found, and the maps indexed by identifier.
+func (r *auditRepository) snapshot(ctx context.Context, opts ...repository.Option) ([]uint, map[uint]map[string]any, error) {
+	out := reflect.New(reflect.SliceOf(reflect.TypeOf(r.Model())))
+	if err := r.Repository.Find(ctx, out.Interface(), opts...); err != nil {
+		return nil, nil, err
+	}
+	entries := out.Elem()
+	ids := make([]uint, entries.Len())
+	values := make(map[uint]map[string]any, entries.Len())
+	for i := 0; i < entries.Len(); i++ {
+		entry, ok := entries.Index(i).Interface().(repository.Model)
+		if !ok {
+			return nil, nil, repository.ErrInvalidModelType
+		}
+		m, err := structs.ToMap(entry)
+		if err != nil {
+			return nil, nil, err
+		}
+		if entry.GetID() == 0 {
+			return nil, nil, ErrUnidentifiedEntry
+		}
+		ids[i] = entry.GetID()
+		values[ids[i]] = m
+	}
+	return ids, values, nil
+}
+
+// conditionalAuditRepository extends auditRepository with support for conditional updates.
+type conditionalAuditRepository struct {
+	*auditRepository
+}
+
+// UpdateIf updates all the entries that match filters with data, only if every one of them meets precondition.
+func (r *conditionalAuditRepository) UpdateIf(ctx context.Context, data interface{}, precondition repository.Precondition, filters ...repository.Filter) error {
+	return r.update(ctx, filters, func(ctx context.Context) error {
+		return r.Repository.(repository.ConditionalUpdater).UpdateIf(ctx, data, precondition, filters...)
+	})
+}
+
+// diff returns the values of before and after that are different. Nested maps are compared recursively.
+func diff(before, after map[string]any) (map[string]any, map[string]any) {
+	b := make(map[string]any)
+	a := make(map[string]any)
+	for k, v := range before {
+		w, ok := after[k]
+		if !ok {
+			b[k] = v
+			continue
+		}
+		vm, vok := v.(map[string]any)
+		wm, wok := w.(map[string]any)
+		if vok && wok {
+			if db, da := diff(vm, wm); len(db) > 0 || len(da) > 0 {
+				b[k], a[k] = db, da
+			}
+			continue
+		}
+		if !reflect.DeepEqual(v, w) {
+			b[k], a[k] = v, w
+		}
+	}
+	for k, w := range after {
+		if _, ok := before[k]; !ok {
+			a[k] = w
+		}
+	}
+	return b, a
+}
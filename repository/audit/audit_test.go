@@ -0,0 +1,293 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gazebo-web/gz-go/v10/middleware"
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"github.com/gazebo-web/gz-go/v10/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/metadata"
+)
+
+type Test struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Value     int       `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (t Test) TableName() string {
+	return "test"
+}
+
+func (t Test) GetID() uint {
+	return t.ID
+}
+
+// fakeRepository hides the optional interfaces implemented by the wrapped repository.Repository.
+type fakeRepository struct {
+	repository.Repository
+}
+
+type fakeSink struct {
+	entries []Entry
+	err     error
+}
+
+func (s *fakeSink) Write(ctx context.Context, entry Entry) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+// Document is a model whose entries are not identified by GetID, such as the models used with Firestore.
+type Document struct {
+	ID   string
+	Name string
+}
+
+func (Document) TableName() string {
+	return "documents"
+}
+
+func (Document) GetID() uint {
+	return 0
+}
+
+// fakeTransactioner counts the number of transactions that were run.
+type fakeTransactioner struct {
+	transactions int
+}
+
+func (tr *fakeTransactioner) WithTransaction(ctx context.Context, fn repository.TransactionFunc) error {
+	tr.transactions++
+	return fn(ctx)
+}
+
+func TestRepository(t *testing.T) {
+	suite.Run(t, new(RepositoryTestSuite))
+}
+
+type RepositoryTestSuite struct {
+	suite.Suite
+	ctx        context.Context
+	sink       *fakeSink
+	wrapped    repository.Repository
+	repository repository.Repository
+}
+
+func (s *RepositoryTestSuite) SetupTest() {
+	s.ctx = metadata.NewIncomingContext(context.Background(), metadata.MD{})
+	s.ctx = middleware.InjectGRPCAuthSubject(s.ctx, "auth0|test")
+	s.sink = &fakeSink{}
+	s.wrapped = memory.NewRepository(&Test{})
+	s.repository = NewRepository(s.wrapped, s.sink, memory.Where)
+
+	_, err := s.wrapped.CreateBulk(s.ctx, []repository.Model{
+		&Test{Name: "Test1", Value: 1},
+		&Test{Name: "Test2", Value: 2},
+		&Test{Name: "Test3", Value: 3},
+	})
+	s.Require().NoError(err)
+}
+
+// assertChanges asserts that actual contains the expected changes. Timestamps are not compared.
+func (s *RepositoryTestSuite) assertChanges(expected []Change, actual []Change) {
+	for _, c := range actual {
+		delete(c.Before, "UpdatedAt")
+		delete(c.After, "UpdatedAt")
+	}
+	s.Assert().Equal(expected, actual)
+}
+
+func (s *RepositoryTestSuite) TestImplementsConditionalUpdater() {
+	s.Assert().Implements((*repository.ConditionalUpdater)(nil), s.repository)
+}
+
+func (s *RepositoryTestSuite) TestCreate() {
+	now := time.Now()
+	_, err := s.repository.Create(s.ctx, &Test{Name: "Test4", Value: 4})
+	s.Require().NoError(err)
+
+	s.Require().Len(s.sink.entries, 1)
+	entry := s.sink.entries[0]
+	s.Assert().Equal("auth0|test", entry.Actor)
+	s.Assert().WithinDuration(now, entry.Timestamp, time.Second)
+	s.Assert().Equal(OperationCreate, entry.Operation)
+	s.Assert().Equal("test", entry.Resource)
+	s.Assert().Empty(entry.Filters)
+	s.assertChanges([]Change{
+		{ID: 4, After: map[string]any{"ID": uint(4), "Name": "Test4", "Value": 4}},
+	}, entry.Changes)
+}
+
+func (s *RepositoryTestSuite) TestFirstOrCreate() {
+	s.Require().NoError(s.repository.FirstOrCreate(s.ctx, &Test{Name: "Test1"}, repository.NewFilter(repository.Eq("name", "Test1"))))
+	s.Assert().Empty(s.sink.entries)
+
+	s.Require().NoError(s.repository.FirstOrCreate(s.ctx, &Test{Name: "Test4"}, repository.NewFilter(repository.Eq("name", "Test4"))))
+	s.Require().Len(s.sink.entries, 1)
+	s.Assert().Equal(OperationCreate, s.sink.entries[0].Operation)
+}
+
+func (s *RepositoryTestSuite) TestFirstOrCreate_Unsupported() {
+	r := NewRepository(&fakeRepository{Repository: s.wrapped}, s.sink, memory.Where)
+	err := r.FirstOrCreate(s.ctx, &Test{Name: "Test4"}, repository.NewFilter(repository.Eq("name", "Test4")))
+	s.Assert().ErrorIs(err, repository.ErrUnsupportedOperation)
+	s.Assert().Empty(s.sink.entries)
+}
+
+func (s *RepositoryTestSuite) TestUpdate() {
+	filter := repository.NewFilter(repository.Gte("value", 2))
+	s.Require().NoError(s.repository.Update(s.ctx, map[string]interface{}{"name": "Updated", "value": 3}, filter))
+
+	s.Require().Len(s.sink.entries, 1)
+	entry := s.sink.entries[0]
+	s.Assert().Equal(OperationUpdate, entry.Operation)
+	s.Assert().Equal([]repository.Filter{filter}, entry.Filters)
+	// Test3 already had value 3, so only its name changed.
+	s.assertChanges([]Change{
+		{
+			ID:     2,
+			Before: map[string]any{"Name": "Test2", "Value": 2},
+			After:  map[string]any{"Name": "Updated", "Value": 3},
+		},
+		{
+			ID:     3,
+			Before: map[string]any{"Name": "Test3"},
+			After:  map[string]any{"Name": "Updated"},
+		},
+	}, entry.Changes)
+}
+
+func (s *RepositoryTestSuite) TestUpdateIf() {
+	filter := repository.NewFilter(repository.Eq("name", "Test1"))
+	var stored Test
+	s.Require().NoError(s.wrapped.FindOne(s.ctx, &stored, filter))
+
+	updater := s.repository.(repository.ConditionalUpdater)
+	s.Require().NoError(updater.UpdateIf(s.ctx, map[string]interface{}{"value": 10}, repository.IfUpdatedAt(stored.UpdatedAt), filter))
+
+	s.Require().Len(s.sink.entries, 1)
+	s.assertChanges([]Change{
+		{ID: 1, Before: map[string]any{"Value": 1}, After: map[string]any{"Value": 10}},
+	}, s.sink.entries[0].Changes)
+}
+
+func (s *RepositoryTestSuite) TestUpdate_TemplateFilter() {
+	err := s.repository.Update(s.ctx, map[string]interface{}{"value": 10}, repository.Filter{Template: "name = ?", Values: []interface{}{"Test1"}})
+	s.Assert().ErrorIs(err, repository.ErrUnsupportedExpression)
+	s.Assert().Empty(s.sink.entries)
+}
+
+func (s *RepositoryTestSuite) TestDelete() {
+	s.Require().NoError(s.repository.Delete(s.ctx, memory.Where(repository.Lt("value", 3))))
+
+	s.Require().Len(s.sink.entries, 1)
+	entry := s.sink.entries[0]
+	s.Assert().Equal(OperationDelete, entry.Operation)
+	s.assertChanges([]Change{
+		{ID: 1, Before: map[string]any{"ID": uint(1), "Name": "Test1", "Value": 1}},
+		{ID: 2, Before: map[string]any{"ID": uint(2), "Name": "Test2", "Value": 2}},
+	}, entry.Changes)
+
+	count, err := s.wrapped.Count(s.ctx)
+	s.Require().NoError(err)
+	s.Assert().Equal(uint64(1), count)
+}
+
+func (s *RepositoryTestSuite) TestNoFilter() {
+	s.Assert().ErrorIs(s.repository.Delete(s.ctx), repository.ErrNoFilter)
+	s.Assert().ErrorIs(s.repository.Update(s.ctx, map[string]any{"value": 0}), repository.ErrNoFilter)
+	s.Assert().Empty(s.sink.entries)
+
+	count, err := s.wrapped.Count(s.ctx)
+	s.Require().NoError(err)
+	s.Assert().Equal(uint64(3), count)
+}
+
+func (s *RepositoryTestSuite) TestActor() {
+	// Operations without an actor are still recorded
+	_, err := s.repository.Create(context.Background(), &Test{Name: "Test4"})
+	s.Require().NoError(err)
+	s.Require().Len(s.sink.entries, 1)
+	s.Assert().Empty(s.sink.entries[0].Actor)
+
+	s.repository = NewRepository(s.wrapped, s.sink, memory.Where, WithActor(func(ctx context.Context) (string, error) {
+		return "system", nil
+	}))
+	_, err = s.repository.Create(context.Background(), &Test{Name: "Test5"})
+	s.Require().NoError(err)
+	s.Require().Len(s.sink.entries, 2)
+	s.Assert().Equal("system", s.sink.entries[1].Actor)
+}
+
+func (s *RepositoryTestSuite) TestTransactioner() {
+	tr := &fakeTransactioner{}
+	s.repository = NewRepository(s.wrapped, s.sink, memory.Where, WithTransactioner(tr))
+
+	_, err := s.repository.Create(s.ctx, &Test{Name: "Test4"})
+	s.Require().NoError(err)
+	s.Require().NoError(s.repository.FirstOrCreate(s.ctx, &Test{Name: "Test5"}, repository.NewFilter(repository.Eq("name", "Test5"))))
+	s.Require().NoError(s.repository.Update(s.ctx, map[string]interface{}{"value": 10}, repository.NewFilter(repository.Eq("name", "Test1"))))
+	s.Require().NoError(s.repository.Delete(s.ctx, memory.Where(repository.Eq("name", "Test2"))))
+
+	s.Assert().Equal(4, tr.transactions)
+	s.Assert().Len(s.sink.entries, 4)
+}
+
+func (s *RepositoryTestSuite) TestUnidentifiedEntry() {
+	s.repository = NewRepository(memory.NewRepository(&Document{}), s.sink, memory.Where)
+
+	_, err := s.repository.Create(s.ctx, &Document{ID: "a", Name: "Test"})
+	s.Assert().ErrorIs(err, ErrUnidentifiedEntry)
+
+	err = s.repository.Update(s.ctx, map[string]interface{}{"name": "Updated"}, repository.NewFilter(repository.Eq("name", "Test")))
+	s.Assert().ErrorIs(err, ErrUnidentifiedEntry)
+	s.Assert().Empty(s.sink.entries)
+}
+
+func (s *RepositoryTestSuite) TestSinkError() {
+	s.sink.err = errors.New("sink error")
+	_, err := s.repository.Create(s.ctx, &Test{Name: "Test4"})
+	s.Assert().ErrorIs(err, s.sink.err)
+}
+
+func (s *RepositoryTestSuite) TestRepositorySink() {
+	records := memory.NewRepository(&Record{})
+	s.repository = NewRepository(s.wrapped, NewRepositorySink(records), memory.Where)
+
+	filter := repository.NewFilter(repository.Eq("name", "Test1"))
+	s.Require().NoError(s.repository.Update(s.ctx, map[string]interface{}{"value": 10}, filter))
+
+	var out []Record
+	s.Require().NoError(records.Find(s.ctx, &out))
+	s.Require().Len(out, 1)
+	s.Assert().Equal("auth0|test", out[0].Actor)
+	s.Assert().Equal(OperationUpdate, out[0].Operation)
+	s.Assert().Equal("test", out[0].Resource)
+	s.Assert().JSONEq(`[{"Template":"","Values":null,"Expression":{"Field":"name","Operator":"==","Value":"Test1"}}]`, out[0].Filters)
+	var changes []Change
+	s.Require().NoError(json.Unmarshal([]byte(out[0].Changes), &changes))
+	s.Require().Len(changes, 1)
+	s.Assert().Equal(uint(1), changes[0].ID)
+	s.Assert().Equal(float64(1), changes[0].Before["Value"])
+	s.Assert().Equal(float64(10), changes[0].After["Value"])
+}
+
+func TestDiff(t *testing.T) {
+	before, after := diff(
+		map[string]any{"a": 1, "b": "x", "c": map[string]any{"d": 1, "e": 2}, "f": true},
+		map[string]any{"a": 1, "b": "y", "c": map[string]any{"d": 1, "e": 3}, "g": false},
+	)
+	assert.Equal(t, map[string]any{"b": "x", "c": map[string]any{"e": 2}, "f": true}, before)
+	assert.Equal(t, map[string]any{"b": "y", "c": map[string]any{"e": 3}, "g": false}, after)
+}
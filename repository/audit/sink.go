@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"github.com/gazebo-web/gz-go/v10/repository/sql"
+	"gorm.io/gorm"
+)
+
+// Record is the persisted representation of an Entry. It is used by the Sink returned by NewRepositorySink.
+// Filters and Changes are stored as JSON documents.
+//
+// Migrate this model before using NewSQLSink, for example with gorm.MigrateModels.
+type Record struct {
+	// ID contains the primary key identifier.
+	ID uint `json:"id" gorm:"primaryKey"`
+	// Actor contains the subject that performed the operation.
+	Actor string `json:"actor" gorm:"size:255;index"`
+	// Timestamp contains the date and time at which the operation was performed.
+	Timestamp time.Time `json:"timestamp" gorm:"index"`
+	// Operation contains the kind of operation performed.
+	Operation Operation `json:"operation" gorm:"size:16"`
+	// Resource contains the table/collection name of the changed entries.
+	Resource string `json:"resource" gorm:"size:255;index"`
+	// Filters contains the JSON-encoded selection criteria of the operation.
+	Filters string `json:"filters" gorm:"type:text"`
+	// Changes contains the JSON-encoded list of changes performed by the operation.
+	Changes string `json:"changes" gorm:"type:text"`
+}
+
+// TableName returns the audit log table name.
+func (Record) TableName() string {
+	return "audit_log"
+}
+
+// GetID returns the unique identifier for this Record.
+func (r Record) GetID() uint {
+	return r.ID
+}
+
+// NewRepositorySink initializes a new Sink that stores entries as Record entries in the given Repository.
+// The Repository must have been initialized with a Record model.
+func NewRepositorySink(repo repository.Repository) Sink {
+	return &repositorySink{
+		repository: repo,
+	}
+}
+
+// NewSQLSink initializes a new Sink that stores entries in the audit log table of the given database.
+// Entries are written in the transaction carried by the operation context, if any.
+func NewSQLSink(db *gorm.DB) Sink {
+	return NewRepositorySink(sql.NewRepository(db, &Record{}))
+}
+
+// repositorySink implements Sink using a repository.Repository.
+type repositorySink struct {
+	repository repository.Repository
+}
+
+// Write stores the given entry as a Record.
+func (s *repositorySink) Write(ctx context.Context, entry Entry) error {
+	filters, err := json.Marshal(entry.Filters)
+	if err != nil {
+		return err
+	}
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return err
+	}
+	_, err = s.repository.Create(ctx, &Record{
+		Actor:     entry.Actor,
+		Timestamp: entry.Timestamp,
+		Operation: entry.Operation,
+		Resource:  entry.Resource,
+		Filters:   string(filters),
+		Changes:   string(changes),
+	})
+	return err
+}
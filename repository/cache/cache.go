@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Cache stores the serialized results of repository operations.
+// Implementations are expected to be safe for concurrent use. Failures to read or write values should be treated as
+// cache misses.
+type Cache interface {
+	// Get returns the value stored for key. It returns false if the value is not present.
+	Get(ctx context.Context, key string) ([]byte, bool)
+	// Set stores value for key.
+	Set(ctx context.Context, key string, value []byte)
+}
+
+// NewRepository initializes a new read-through caching repository.Repository that performs all of its operations
+// using the given Repository.
+//
+// The results of FindOne, Last and Count are stored in c, keyed on the model table name and the operation filters.
+// Results are serialized using encoding/gob, only exported fields are cached. Results that cannot be serialized, such
+// as models with interface fields of unregistered types, are not cached. Errors, including repository.ErrNotFound, are
+// not cached. Find is not cached because its options cannot be used as a cache key.
+//
+// Operations that run inside a transaction, detected with repository.InTransaction, neither read from nor populate the
+// cache, as they may observe uncommitted changes.
+//
+// Cached results are invalidated when entries are created, updated or deleted through the returned Repository. Changes
+// performed inside a transaction invalidate cached results after the transaction is committed, see
+// repository.AfterCommit. Each Repository instance caches its results under its own keys, changes performed by other
+// Repository instances are not detected until cached values are evicted from c.
+//
+// The returned Repository implements repository.ConditionalUpdater if the given Repository implements it.
+// It also implements repository.FirstOrCreator, which returns repository.ErrUnsupportedOperation if the given
+// Repository does not implement it.
+func NewRepository(repo repository.Repository, c Cache) repository.Repository {
+	r := &cacheRepository{
+		Repository: repo,
+		cache:      c,
+		instance:   uuid.NewV4().String(),
+	}
+	if _, ok := repo.(repository.ConditionalUpdater); ok {
+		return &conditionalCacheRepository{cacheRepository: r}
+	}
+	return r
+}
+
+// cacheRepository implements repository.Repository by wrapping a Repository.
+type cacheRepository struct {
+	repository.Repository
+	cache Cache
+	// instance is part of every cache key, and it prevents repositories sharing the same Cache from reading each
+	// other's values.
+	instance string
+	// generation is part of every cache key, and it's incremented when entries change. This invalidates all the
+	// values previously cached by this repository without having to remove them from the cache.
+	generation atomic.Uint64
+}
+
+// FindOne filters entries and stores the first filtered entry in output.
+func (r *cacheRepository) FindOne(ctx context.Context, output repository.Model, filters ...repository.Filter) error {
+	return r.read(ctx, "find_one", filters, output, func() error {
+		return r.Repository.FindOne(ctx, output, filters...)
+	})
+}
+
+// Last gets the last record ordered by primary key desc.
+func (r *cacheRepository) Last(ctx context.Context, output repository.Model, filters ...repository.Filter) error {
+	return r.read(ctx, "last", filters, output, func() error {
+		return r.Repository.Last(ctx, output, filters...)
+	})
+}
+
+// Count counts all the model entries that match filters.
+func (r *cacheRepository) Count(ctx context.Context, filters ...repository.Filter) (uint64, error) {
+	var count uint64
+	err := r.read(ctx, "count", filters, &count, func() (err error) {
+		count, err = r.Repository.Count(ctx, filters...)
+		return err
+	})
+	return count, err
+}
+
+// FirstOrCreate inserts a new entry if the given filters don't find any existing record.
+func (r *cacheRepository) FirstOrCreate(ctx context.Context, entity repository.Model, filters ...repository.Filter) error {
+	defer r.invalidate(ctx)
+	return r.Repository.FirstOrCreate(ctx, entity, filters...)
+}
+
+// FirstOrCreateAndReport inserts a new entry if the given filters don't find any existing record, and returns true if
+// the entry was created.
+func (r *cacheRepository) FirstOrCreateAndReport(ctx context.Context, entity repository.Model, filters ...repository.Filter) (bool, error) {
+	creator, ok := r.Repository.(repository.FirstOrCreator)
+	if !ok {
+		return false, repository.ErrUnsupportedOperation
+	}
+	defer r.invalidate(ctx)
+	return creator.FirstOrCreateAndReport(ctx, entity, filters...)
+}
+
+// Create inserts a single entry.
+func (r *cacheRepository) Create(ctx context.Context, entity repository.Model) (repository.Model, error) {
+	defer r.invalidate(ctx)
+	return r.Repository.Create(ctx, entity)
+}
+
+// CreateBulk creates multiple entries with a single operation.
+func (r *cacheRepository) CreateBulk(ctx context.Context, entities []repository.Model) ([]repository.Model, error) {
+	defer r.invalidate(ctx)
+	return r.Repository.CreateBulk(ctx, entities)
+}
+
+// Upsert inserts a single entry, or updates the existing entry that conflicts with it.
+func (r *cacheRepository) Upsert(ctx context.Context, entity repository.Model, conflictFields ...string) error {
+	defer r.invalidate(ctx)
+	return r.Repository.Upsert(ctx, entity, conflictFields...)
+}
+
+// Update updates all model entries that match the provided filters with the given data.
+func (r *cacheRepository) Update(ctx context.Context, data interface{}, filters ...repository.Filter) error {
+	defer r.invalidate(ctx)
+	return r.Repository.Update(ctx, data, filters...)
+}
+
+// Delete removes all the model entries that match filters.
+func (r *cacheRepository) Delete(ctx context.Context, opts ...repository.Option) error {
+	defer r.invalidate(ctx)
+	return r.Repository.Delete(ctx, opts...)
+}
+
+// read returns the cached result of an operation in output. If the result is not cached, it calls fn to fill output
+// and caches it.
+func (r *cacheRepository) read(ctx context.Context, op string, filters []repository.Filter, output interface{}, fn func() error) error {
+	if repository.InTransaction(ctx) {
+		return fn()
+	}
+	key, err := r.key(op, filters)
+	if err != nil {
+		// Filters that cannot be serialized are not cached.
+		return fn()
+	}
+	if value, ok := r.cache.Get(ctx, key); ok && decode(value, output) == nil {
+		return nil
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	if value, err := encode(output); err == nil {
+		r.cache.Set(ctx, key, value)
+	}
+	return nil
+}
+
+// encode serializes the value pointed to by v.
+func encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decode deserializes value into the value pointed to by output. The value pointed to by output is replaced as a
+// whole, fields that are not present in value are reset to their zero value. output is not modified if value cannot
+// be deserialized.
+func decode(value []byte, output interface{}) error {
+	v := reflect.ValueOf(output)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("cannot decode into %T", output)
+	}
+	decoded := reflect.New(v.Elem().Type())
+	if err := gob.NewDecoder(bytes.NewReader(value)).DecodeValue(decoded); err != nil {
+		return err
+	}
+	v.Elem().Set(decoded.Elem())
+	return nil
+}
+
+// key returns the cache key of an operation.
+func (r *cacheRepository) key(op string, filters []repository.Filter) (string, error) {
+	f, err := json.Marshal(filters)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%s:%d:%s:%s", r.Model().TableName(), r.instance, r.generation.Load(), op, f), nil
+}
+
+// invalidate invalidates all the values cached by this repository. If ctx carries a transaction, values are invalidated
+// after the transaction is committed, as values cached before that may contain the state prior to the transaction.
+func (r *cacheRepository) invalidate(ctx context.Context) {
+	repository.AfterCommit(ctx, func() {
+		r.generation.Add(1)
+	})
+}
+
+// conditionalCacheRepository extends cacheRepository with support for conditional updates.
+type conditionalCacheRepository struct {
+	*cacheRepository
+}
+
+// UpdateIf updates all the entries that match filters with data, only if every one of them meets precondition.
+func (r *conditionalCacheRepository) UpdateIf(ctx context.Context, data interface{}, precondition repository.Precondition, filters ...repository.Filter) error {
+	defer r.invalidate(ctx)
+	return r.Repository.(repository.ConditionalUpdater).UpdateIf(ctx, data, precondition, filters...)
+}
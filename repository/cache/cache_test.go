@@ -0,0 +1,264 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"github.com/gazebo-web/gz-go/v10/repository/memory"
+	"github.com/stretchr/testify/suite"
+)
+
+type Test struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Value     int       `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (t Test) TableName() string {
+	return "test"
+}
+
+func (t Test) GetID() uint {
+	return t.ID
+}
+
+// countingRepository counts the read operations performed by the wrapped repository.
+type countingRepository struct {
+	repository.Repository
+	reads int
+}
+
+func (r *countingRepository) FindOne(ctx context.Context, output repository.Model, filters ...repository.Filter) error {
+	r.reads++
+	return r.Repository.FindOne(ctx, output, filters...)
+}
+
+func (r *countingRepository) Count(ctx context.Context, filters ...repository.Filter) (uint64, error) {
+	r.reads++
+	return r.Repository.Count(ctx, filters...)
+}
+
+func (r *countingRepository) UpdateIf(ctx context.Context, data interface{}, precondition repository.Precondition, filters ...repository.Filter) error {
+	return r.Repository.(repository.ConditionalUpdater).UpdateIf(ctx, data, precondition, filters...)
+}
+
+// stagingRepository defers the updates performed inside a transaction until the transaction is committed,
+// simulating the isolation of uncommitted changes provided by databases.
+type stagingRepository struct {
+	repository.Repository
+	lock   sync.Mutex
+	staged []func(ctx context.Context) error
+}
+
+func (r *stagingRepository) Update(ctx context.Context, data interface{}, filters ...repository.Filter) error {
+	if !repository.InTransaction(ctx) {
+		return r.Repository.Update(ctx, data, filters...)
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.staged = append(r.staged, func(ctx context.Context) error {
+		return r.Repository.Update(ctx, data, filters...)
+	})
+	return nil
+}
+
+func (r *stagingRepository) WithTransaction(ctx context.Context, fn repository.TransactionFunc) error {
+	txCtx := repository.ContextWithTransaction(ctx)
+	if err := fn(txCtx); err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, update := range r.staged {
+		if err := update(ctx); err != nil {
+			return err
+		}
+	}
+	r.staged = nil
+	repository.Committed(txCtx)
+	return nil
+}
+
+func TestRepository(t *testing.T) {
+	suite.Run(t, new(RepositoryTestSuite))
+}
+
+type RepositoryTestSuite struct {
+	suite.Suite
+	ctx        context.Context
+	wrapped    *countingRepository
+	repository repository.Repository
+}
+
+func (s *RepositoryTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.wrapped = &countingRepository{Repository: memory.NewRepository(&Test{})}
+	s.repository = NewRepository(s.wrapped, NewLRU(100, time.Minute))
+
+	_, err := s.wrapped.CreateBulk(s.ctx, []repository.Model{
+		&Test{Name: "Test1", Value: 1},
+		&Test{Name: "Test2", Value: 2},
+		&Test{Name: "Test3", Value: 3},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *RepositoryTestSuite) TestImplementsConditionalUpdater() {
+	s.Assert().Implements((*repository.ConditionalUpdater)(nil), s.repository)
+	s.Assert().NotImplements((*repository.ConditionalUpdater)(nil), NewRepository(struct{ repository.Repository }{s.wrapped}, NewLRU(1, 0)))
+}
+
+func (s *RepositoryTestSuite) TestFindOne() {
+	filter := repository.NewFilter(repository.Eq("id", 2))
+
+	for i := 0; i < 3; i++ {
+		var out Test
+		s.Require().NoError(s.repository.FindOne(s.ctx, &out, filter))
+		s.Assert().Equal(uint(2), out.ID)
+		s.Assert().Equal("Test2", out.Name)
+	}
+	s.Assert().Equal(1, s.wrapped.reads)
+
+	// Results are cached per filter
+	var out Test
+	s.Require().NoError(s.repository.FindOne(s.ctx, &out, repository.NewFilter(repository.Eq("id", 3))))
+	s.Assert().Equal("Test3", out.Name)
+	s.Assert().Equal(2, s.wrapped.reads)
+
+	// Modifying a result doesn't modify the cached value
+	out.Name = "Modified"
+	s.Require().NoError(s.repository.FindOne(s.ctx, &out, repository.NewFilter(repository.Eq("id", 3))))
+	s.Assert().Equal("Test3", out.Name)
+}
+
+func (s *RepositoryTestSuite) TestFindOne_ResetsOutput() {
+	filter := repository.NewFilter(repository.Eq("id", 1))
+	var out Test
+	s.Require().NoError(s.repository.FindOne(s.ctx, &out, filter))
+	s.Require().NoError(s.repository.Update(s.ctx, map[string]interface{}{"value": 0}, filter))
+	s.Require().NoError(s.repository.FindOne(s.ctx, &out, filter))
+
+	// Fields with zero values in the cached result are not kept from the previous output
+	out = Test{Name: "Previous", Value: 5, UpdatedAt: time.Now().Add(time.Hour)}
+	s.Require().NoError(s.repository.FindOne(s.ctx, &out, filter))
+	s.Assert().Equal(2, s.wrapped.reads)
+	s.Assert().Equal("Test1", out.Name)
+	s.Assert().Zero(out.Value)
+	s.Assert().True(out.UpdatedAt.Before(time.Now()))
+}
+
+func (s *RepositoryTestSuite) TestTransaction() {
+	ctx := repository.ContextWithTransaction(s.ctx)
+	filter := repository.NewFilter(repository.Eq("id", 1))
+	for i := 0; i < 2; i++ {
+		var out Test
+		s.Require().NoError(s.repository.FindOne(ctx, &out, filter))
+		s.Assert().Equal("Test1", out.Name)
+	}
+	s.Assert().Equal(2, s.wrapped.reads)
+
+	// Results read inside a transaction are not cached
+	var out Test
+	s.Require().NoError(s.repository.FindOne(s.ctx, &out, filter))
+	s.Assert().Equal(3, s.wrapped.reads)
+}
+
+func (s *RepositoryTestSuite) TestTransaction_ConcurrentRead() {
+	staging := &stagingRepository{Repository: s.wrapped}
+	repo := NewRepository(staging, NewLRU(100, time.Minute))
+	filter := repository.NewFilter(repository.Eq("id", 1))
+
+	err := staging.WithTransaction(s.ctx, func(ctx context.Context) error {
+		if err := repo.Update(ctx, map[string]interface{}{"name": "Updated"}, filter); err != nil {
+			return err
+		}
+		// A concurrent reader doesn't observe the uncommitted update, and caches the previous state of the entry.
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			var out Test
+			s.Assert().NoError(repo.FindOne(s.ctx, &out, filter))
+			s.Assert().Equal("Test1", out.Name)
+		}()
+		<-done
+		return nil
+	})
+	s.Require().NoError(err)
+
+	// Values cached before the transaction is committed are invalidated
+	var out Test
+	s.Require().NoError(repo.FindOne(s.ctx, &out, filter))
+	s.Assert().Equal("Updated", out.Name)
+}
+
+func (s *RepositoryTestSuite) TestSharedCache() {
+	c := NewLRU(100, time.Minute)
+	first := NewRepository(s.wrapped, c)
+	second := NewRepository(s.wrapped, c)
+	filter := repository.NewFilter(repository.Eq("id", 1))
+
+	var out Test
+	s.Require().NoError(first.FindOne(s.ctx, &out, filter))
+	s.Require().NoError(first.Update(s.ctx, map[string]interface{}{"name": "Updated"}, filter))
+
+	// Repositories sharing a Cache don't read each other's values
+	s.Require().NoError(second.FindOne(s.ctx, &out, filter))
+	s.Assert().Equal("Updated", out.Name)
+	s.Assert().Equal(2, s.wrapped.reads)
+}
+
+func (s *RepositoryTestSuite) TestFindOne_NotFound() {
+	filter := repository.NewFilter(repository.Eq("id", 10))
+	for i := 0; i < 2; i++ {
+		var out Test
+		s.Assert().ErrorIs(s.repository.FindOne(s.ctx, &out, filter), repository.ErrNotFound)
+	}
+	s.Assert().Equal(2, s.wrapped.reads)
+}
+
+func (s *RepositoryTestSuite) TestCount() {
+	for i := 0; i < 2; i++ {
+		count, err := s.repository.Count(s.ctx, repository.NewFilter(repository.Gt("value", 1)))
+		s.Require().NoError(err)
+		s.Assert().Equal(uint64(2), count)
+	}
+	s.Assert().Equal(1, s.wrapped.reads)
+}
+
+func (s *RepositoryTestSuite) TestInvalidate() {
+	filter := repository.NewFilter(repository.Eq("id", 1))
+	assertName := func(expected string, reads int) {
+		var out Test
+		s.Require().NoError(s.repository.FindOne(s.ctx, &out, filter))
+		s.Assert().Equal(expected, out.Name)
+		s.Assert().Equal(reads, s.wrapped.reads)
+	}
+	assertCount := func(expected uint64) {
+		count, err := s.repository.Count(s.ctx)
+		s.Require().NoError(err)
+		s.Assert().Equal(expected, count)
+	}
+
+	assertName("Test1", 1)
+	assertCount(3)
+
+	s.Require().NoError(s.repository.Update(s.ctx, map[string]interface{}{"name": "Updated"}, filter))
+	assertName("Updated", 3)
+
+	_, err := s.repository.Create(s.ctx, &Test{Name: "Test4"})
+	s.Require().NoError(err)
+	assertCount(4)
+
+	s.Require().NoError(s.repository.Delete(s.ctx, memory.Where(repository.Eq("name", "Test4"))))
+	assertCount(3)
+
+	var stored Test
+	s.Require().NoError(s.wrapped.Repository.FindOne(s.ctx, &stored, filter))
+	updater := s.repository.(repository.ConditionalUpdater)
+	s.Require().NoError(updater.UpdateIf(s.ctx, map[string]interface{}{"name": "Conditional"}, repository.IfUpdatedAt(stored.UpdatedAt), filter))
+	assertName("Conditional", 6)
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// NewLRU initializes a new in-process Cache that holds up to size values, evicting the least recently used value
+// when it's full. Values expire ttl after they are set. If ttl is 0, values never expire.
+func NewLRU(size int, ttl time.Duration) Cache {
+	return &lru{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// lru implements Cache using a least recently used eviction policy.
+type lru struct {
+	size    int
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order contains the cached values, sorted from the most recently used to the least recently used.
+	order *list.List
+	now   func() time.Time
+}
+
+// lruEntry is a value stored in the lru cache.
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// Get returns the value stored for key. It returns false if the value is not present or has expired.
+func (c *lru) Get(ctx context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		c.remove(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.value, true
+}
+
+// Set stores value for key, evicting the least recently used value if the cache is full.
+func (c *lru) Set(ctx context.Context, key string, value []byte) {
+	if c.size <= 0 {
+		return
+	}
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = c.now().Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(el)
+		return
+	}
+	for c.order.Len() >= c.size {
+		c.remove(c.order.Back())
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+}
+
+// remove removes the given element from the cache.
+func (c *lru) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*lruEntry).key)
+}
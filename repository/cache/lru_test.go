@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2, 0)
+
+	c.Set(ctx, "a", []byte("1"))
+	c.Set(ctx, "b", []byte("2"))
+	value, ok := c.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+
+	// b is the least recently used value
+	c.Set(ctx, "c", []byte("3"))
+	_, ok = c.Get(ctx, "b")
+	assert.False(t, ok)
+	_, ok = c.Get(ctx, "a")
+	assert.True(t, ok)
+	_, ok = c.Get(ctx, "c")
+	assert.True(t, ok)
+
+	// Overwriting a value doesn't evict other values
+	c.Set(ctx, "c", []byte("4"))
+	value, ok = c.Get(ctx, "c")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("4"), value)
+	_, ok = c.Get(ctx, "a")
+	assert.True(t, ok)
+}
+
+func TestLRU_TTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(2, time.Minute).(*lru)
+	c.now = func() time.Time {
+		return now
+	}
+
+	c.Set(ctx, "a", []byte("1"))
+	now = now.Add(30 * time.Second)
+	_, ok := c.Get(ctx, "a")
+	assert.True(t, ok)
+
+	now = now.Add(30 * time.Second)
+	_, ok = c.Get(ctx, "a")
+	assert.False(t, ok)
+	assert.Empty(t, c.entries)
+}
+
+func TestLRU_Empty(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(0, 0)
+	c.Set(ctx, "a", []byte("1"))
+	_, ok := c.Get(ctx, "a")
+	assert.False(t, ok)
+}
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrAborted represents an error when a listener aborts a Repository operation.
+var ErrAborted = errors.New("operation aborted by listener")
+
+// Event is a change event emitted by an EventRepository.
+// Events emitted before an operation is performed are named BeforeX, and events emitted after an operation succeeds
+// are named AfterX.
+type Event interface {
+	isEvent()
+}
+
+// BeforeCreate is emitted before entities are created.
+type BeforeCreate struct {
+	// Model contains the repository model.
+	Model Model
+	// Entities contains the entities that will be created.
+	Entities []Model
+}
+
+// AfterCreate is emitted after entities are created.
+type AfterCreate struct {
+	// Model contains the repository model.
+	Model Model
+	// Entities contains the created entities.
+	Entities []Model
+}
+
+// BeforeUpsert is emitted before an entity is upserted.
+type BeforeUpsert struct {
+	// Model contains the repository model.
+	Model Model
+	// Entity contains the entity that will be upserted.
+	Entity Model
+	// ConflictFields contains the fields used to find the existing entry.
+	ConflictFields []string
+}
+
+// AfterUpsert is emitted after an entity is upserted.
+type AfterUpsert struct {
+	// Model contains the repository model.
+	Model Model
+	// Entity contains the upserted entity.
+	Entity Model
+	// ConflictFields contains the fields used to find the existing entry.
+	ConflictFields []string
+}
+
+// BeforeUpdate is emitted before entries are updated.
+type BeforeUpdate struct {
+	// Model contains the repository model.
+	Model Model
+	// Data contains the update data.
+	Data interface{}
+	// Filters contains the selection criteria for the entries to update.
+	Filters []Filter
+	// Precondition contains the precondition of conditional updates. It is nil for regular updates.
+	Precondition Precondition
+}
+
+// AfterUpdate is emitted after entries are updated.
+type AfterUpdate struct {
+	// Model contains the repository model.
+	Model Model
+	// Data contains the update data.
+	Data interface{}
+	// Filters contains the selection criteria for the updated entries.
+	Filters []Filter
+	// Precondition contains the precondition of conditional updates. It is nil for regular updates.
+	Precondition Precondition
+}
+
+// BeforeDelete is emitted before entries are deleted.
+type BeforeDelete struct {
+	// Model contains the repository model.
+	Model Model
+	// Options contains the options used to select the entries to delete.
+	Options []Option
+}
+
+// AfterDelete is emitted after entries are deleted.
+type AfterDelete struct {
+	// Model contains the repository model.
+	Model Model
+	// Options contains the options used to select the deleted entries.
+	Options []Option
+}
+
+func (BeforeCreate) isEvent() {}
+func (AfterCreate) isEvent()  {}
+func (BeforeUpsert) isEvent() {}
+func (AfterUpsert) isEvent()  {}
+func (BeforeUpdate) isEvent() {}
+func (AfterUpdate) isEvent()  {}
+func (BeforeDelete) isEvent() {}
+func (AfterDelete) isEvent()  {}
+
+// Listener handles the events emitted by an EventRepository.
+//
+// Listeners are called synchronously, in registration order, with the context of the operation. An error returned by
+// a listener of a Before event aborts the operation, and no further listeners are called. An error returned by a
+// listener of an After event is returned by the operation, but the change is not reverted. Use a Transactioner to make
+// After listeners part of the operation.
+type Listener func(ctx context.Context, event Event) error
+
+// EventRepository is a Repository that emits events when entries are created, updated or deleted.
+type EventRepository interface {
+	Repository
+	ConditionalUpdater
+	FirstOrCreator
+	// Listen registers a listener that receives every event emitted by the repository.
+	// Use On to register listeners for a single event type.
+	Listen(listener Listener)
+}
+
+// On registers a listener for events of type E in the given EventRepository.
+//
+//	On(repo, func(ctx context.Context, event AfterCreate) error {
+//		// ...
+//	})
+func On[E Event](repo EventRepository, listener func(ctx context.Context, event E) error) {
+	repo.Listen(func(ctx context.Context, event Event) error {
+		if e, ok := event.(E); ok {
+			return listener(ctx, e)
+		}
+		return nil
+	})
+}
+
+// NewEventRepository initializes a new EventRepository that performs all of its operations using the given Repository.
+//
+// FirstOrCreate is only supported if repository implements FirstOrCreator, and UpdateIf is only supported if
+// repository implements ConditionalUpdater. Otherwise, they return ErrUnsupportedOperation.
+func NewEventRepository(repository Repository) EventRepository {
+	return &eventRepository{
+		Repository: repository,
+	}
+}
+
+// eventRepository implements EventRepository by wrapping a Repository. Read operations are forwarded to the wrapped
+// Repository by the embedded interface.
+type eventRepository struct {
+	Repository
+	mu        sync.RWMutex
+	listeners []Listener
+}
+
+// Listen registers a listener that receives every event emitted by the repository.
+func (r *eventRepository) Listen(listener Listener) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, listener)
+}
+
+// FirstOrCreate inserts a new entry if the given filters don't find any existing record.
+// BeforeCreate is emitted before the operation, as the entry may be created. AfterCreate is only emitted if the entry
+// was created.
+//
+// FirstOrCreate is only supported if the wrapped Repository implements FirstOrCreator, otherwise it returns
+// ErrUnsupportedOperation.
+func (r *eventRepository) FirstOrCreate(ctx context.Context, entity Model, filters ...Filter) error {
+	_, err := r.FirstOrCreateAndReport(ctx, entity, filters...)
+	return err
+}
+
+// FirstOrCreateAndReport inserts a new entry if the given filters don't find any existing record, and returns true if
+// the entry was created. Events are emitted as described in FirstOrCreate.
+func (r *eventRepository) FirstOrCreateAndReport(ctx context.Context, entity Model, filters ...Filter) (bool, error) {
+	creator, ok := r.Repository.(FirstOrCreator)
+	if !ok {
+		return false, ErrUnsupportedOperation
+	}
+	entities := []Model{entity}
+	if err := r.before(ctx, BeforeCreate{Model: r.Model(), Entities: entities}); err != nil {
+		return false, err
+	}
+	created, err := creator.FirstOrCreateAndReport(ctx, entity, filters...)
+	if err != nil || !created {
+		return created, err
+	}
+	return true, r.after(ctx, AfterCreate{Model: r.Model(), Entities: entities})
+}
+
+// Create inserts a single entry.
+func (r *eventRepository) Create(ctx context.Context, entity Model) (Model, error) {
+	result, err := r.CreateBulk(ctx, []Model{entity})
+	if err != nil {
+		return nil, err
+	}
+	return result[0], nil
+}
+
+// CreateBulk creates multiple entries with a single operation.
+func (r *eventRepository) CreateBulk(ctx context.Context, entities []Model) ([]Model, error) {
+	if err := r.before(ctx, BeforeCreate{Model: r.Model(), Entities: entities}); err != nil {
+		return nil, err
+	}
+	created, err := r.Repository.CreateBulk(ctx, entities)
+	if err != nil {
+		return nil, err
+	}
+	return created, r.after(ctx, AfterCreate{Model: r.Model(), Entities: created})
+}
+
+// Upsert inserts a single entry, or updates the existing entry that conflicts with it.
+func (r *eventRepository) Upsert(ctx context.Context, entity Model, conflictFields ...string) error {
+	if err := r.before(ctx, BeforeUpsert{Model: r.Model(), Entity: entity, ConflictFields: conflictFields}); err != nil {
+		return err
+	}
+	if err := r.Repository.Upsert(ctx, entity, conflictFields...); err != nil {
+		return err
+	}
+	return r.after(ctx, AfterUpsert{Model: r.Model(), Entity: entity, ConflictFields: conflictFields})
+}
+
+// Update updates all model entries that match the provided filters with the given data.
+func (r *eventRepository) Update(ctx context.Context, data interface{}, filters ...Filter) error {
+	if err := r.before(ctx, BeforeUpdate{Model: r.Model(), Data: data, Filters: filters}); err != nil {
+		return err
+	}
+	if err := r.Repository.Update(ctx, data, filters...); err != nil {
+		return err
+	}
+	return r.after(ctx, AfterUpdate{Model: r.Model(), Data: data, Filters: filters})
+}
+
+// UpdateIf updates all the entries that match filters with data, only if every one of them meets precondition.
+func (r *eventRepository) UpdateIf(ctx context.Context, data interface{}, precondition Precondition, filters ...Filter) error {
+	updater, ok := r.Repository.(ConditionalUpdater)
+	if !ok {
+		return ErrUnsupportedOperation
+	}
+	if err := r.before(ctx, BeforeUpdate{Model: r.Model(), Data: data, Filters: filters, Precondition: precondition}); err != nil {
+		return err
+	}
+	if err := updater.UpdateIf(ctx, data, precondition, filters...); err != nil {
+		return err
+	}
+	return r.after(ctx, AfterUpdate{Model: r.Model(), Data: data, Filters: filters, Precondition: precondition})
+}
+
+// Delete removes all the model entries that match filters.
+func (r *eventRepository) Delete(ctx context.Context, opts ...Option) error {
+	if err := r.before(ctx, BeforeDelete{Model: r.Model(), Options: opts}); err != nil {
+		return err
+	}
+	if err := r.Repository.Delete(ctx, opts...); err != nil {
+		return err
+	}
+	return r.after(ctx, AfterDelete{Model: r.Model(), Options: opts})
+}
+
+// before emits an event before an operation is performed. It returns ErrAborted if a listener aborts the operation.
+func (r *eventRepository) before(ctx context.Context, event Event) error {
+	if err := r.emit(ctx, event); err != nil {
+		return fmt.Errorf("%w: %w", ErrAborted, err)
+	}
+	return nil
+}
+
+// after emits an event after an operation succeeds.
+func (r *eventRepository) after(ctx context.Context, event Event) error {
+	return r.emit(ctx, event)
+}
+
+// emit calls every listener with the given event, and stops at the first listener that returns an error.
+func (r *eventRepository) emit(ctx context.Context, event Event) error {
+	r.mu.RLock()
+	listeners := make([]Listener, len(r.listeners))
+	copy(listeners, r.listeners)
+	r.mu.RUnlock()
+
+	for _, listener := range listeners {
+		if err := listener(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventRepository_Create(t *testing.T) {
+	ctx := context.Background()
+	repo := NewEventRepository(&fakeRepository{})
+
+	var events []Event
+	repo.Listen(func(ctx context.Context, event Event) error {
+		events = append(events, event)
+		return nil
+	})
+	var created []Model
+	On(repo, func(ctx context.Context, event AfterCreate) error {
+		created = append(created, event.Entities...)
+		return nil
+	})
+
+	entity := &typedTestModel{Name: "test"}
+	_, err := repo.Create(ctx, entity)
+	require.NoError(t, err)
+
+	require.Len(t, events, 2)
+	assert.IsType(t, BeforeCreate{}, events[0])
+	assert.IsType(t, AfterCreate{}, events[1])
+	assert.Equal(t, &typedTestModel{}, events[0].(BeforeCreate).Model)
+	require.Len(t, created, 1)
+	assert.Same(t, entity, created[0])
+	assert.Equal(t, uint(1), created[0].GetID())
+
+	// FirstOrCreate only emits AfterCreate if the entry was created
+	events = nil
+	created = nil
+	require.NoError(t, repo.FirstOrCreate(ctx, &typedTestModel{Name: "test"}))
+	require.Len(t, events, 1)
+	assert.IsType(t, BeforeCreate{}, events[0])
+	assert.Empty(t, created)
+
+	repo = NewEventRepository(&fakeRepository{})
+	On(repo, func(ctx context.Context, event AfterCreate) error {
+		created = append(created, event.Entities...)
+		return nil
+	})
+	ok, err := repo.FirstOrCreateAndReport(ctx, &typedTestModel{Name: "test"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Len(t, created, 1)
+}
+
+func TestEventRepository_UpdateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	repo := NewEventRepository(&fakeRepository{})
+
+	var events []Event
+	repo.Listen(func(ctx context.Context, event Event) error {
+		events = append(events, event)
+		return nil
+	})
+
+	filter := NewFilter(Eq("name", "test"))
+	require.NoError(t, repo.Update(ctx, map[string]interface{}{"name": "updated"}, filter))
+	require.NoError(t, repo.Upsert(ctx, &typedTestModel{Name: "test"}, "name"))
+	require.NoError(t, repo.Delete(ctx))
+
+	expected := []Event{
+		BeforeUpdate{Model: &typedTestModel{}, Data: map[string]interface{}{"name": "updated"}, Filters: []Filter{filter}},
+		AfterUpdate{Model: &typedTestModel{}, Data: map[string]interface{}{"name": "updated"}, Filters: []Filter{filter}},
+		BeforeUpsert{Model: &typedTestModel{}, Entity: &typedTestModel{ID: 1, Name: "test"}, ConflictFields: []string{"name"}},
+		AfterUpsert{Model: &typedTestModel{}, Entity: &typedTestModel{ID: 1, Name: "test"}, ConflictFields: []string{"name"}},
+		BeforeDelete{Model: &typedTestModel{}},
+		AfterDelete{Model: &typedTestModel{}},
+	}
+	assert.Equal(t, expected, events)
+
+	// The wrapped repository doesn't support conditional updates
+	assert.ErrorIs(t, repo.UpdateIf(ctx, map[string]interface{}{}, IfVersion(1), filter), ErrUnsupportedOperation)
+}
+
+func TestEventRepository_Abort(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeRepository{}
+	repo := NewEventRepository(fake)
+
+	errForbidden := errors.New("forbidden")
+	On(repo, func(ctx context.Context, event BeforeCreate) error {
+		return errForbidden
+	})
+	var after bool
+	On(repo, func(ctx context.Context, event AfterCreate) error {
+		after = true
+		return nil
+	})
+
+	_, err := repo.Create(ctx, &typedTestModel{Name: "test"})
+	assert.ErrorIs(t, err, ErrAborted)
+	assert.ErrorIs(t, err, errForbidden)
+	assert.Empty(t, fake.data)
+	assert.False(t, after)
+}
+
+func TestEventRepository_AfterError(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeRepository{}
+	repo := NewEventRepository(fake)
+
+	errListener := errors.New("listener error")
+	On(repo, func(ctx context.Context, event AfterDelete) error {
+		return errListener
+	})
+
+	fake.data = []typedTestModel{{ID: 1}}
+	err := repo.Delete(ctx)
+	assert.ErrorIs(t, err, errListener)
+	assert.NotErrorIs(t, err, ErrAborted)
+	assert.Empty(t, fake.data)
+}
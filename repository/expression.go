@@ -0,0 +1,149 @@
+package repository
+
+// Expression is a backend-agnostic filter expression.
+// Expressions are composable, and each Repository implementation is responsible for translating them into queries
+// for their specific persistence layer.
+//
+// Use the Eq, Neq, Lt, Lte, Gt, Gte, In, Contains, IsNull, IsNotNull, And, Or and Not functions to create expressions.
+type Expression interface {
+	// isExpression is a dummy method used to identify filter expressions.
+	isExpression()
+}
+
+// Operator is a comparison operator used in a Comparison expression.
+type Operator string
+
+const (
+	// OperatorEqual matches values equal to the given value.
+	OperatorEqual Operator = "=="
+	// OperatorNotEqual matches values different from the given value.
+	OperatorNotEqual Operator = "!="
+	// OperatorLessThan matches values lower than the given value.
+	OperatorLessThan Operator = "<"
+	// OperatorLessThanOrEqual matches values lower than or equal to the given value.
+	OperatorLessThanOrEqual Operator = "<="
+	// OperatorGreaterThan matches values greater than the given value.
+	OperatorGreaterThan Operator = ">"
+	// OperatorGreaterThanOrEqual matches values greater than or equal to the given value.
+	OperatorGreaterThanOrEqual Operator = ">="
+	// OperatorIn matches values that are part of the given slice of values.
+	OperatorIn Operator = "in"
+	// OperatorNotIn matches values that are not part of the given slice of values.
+	OperatorNotIn Operator = "not-in"
+	// OperatorContains matches values that contain the given value. SQL implementations perform a substring match,
+	// while Firestore matches arrays that contain the given value as an element. Refer to Contains for more details.
+	OperatorContains Operator = "contains"
+	// OperatorIsNull matches null values. The value of the comparison is ignored.
+	OperatorIsNull Operator = "is-null"
+	// OperatorIsNotNull matches non-null values. The value of the comparison is ignored.
+	OperatorIsNotNull Operator = "is-not-null"
+)
+
+// Comparison is an Expression that compares the value of a field against a given value.
+type Comparison struct {
+	// Field contains the name of the field (column, document field) to compare.
+	Field string
+	// Operator contains the comparison operator.
+	Operator Operator
+	// Value contains the value to compare the field against.
+	Value any
+}
+
+func (Comparison) isExpression() {}
+
+// Conjunction is an Expression that matches entries when all of its expressions match. It's the result of And.
+type Conjunction []Expression
+
+func (Conjunction) isExpression() {}
+
+// Disjunction is an Expression that matches entries when any of its expressions match. It's the result of Or.
+type Disjunction []Expression
+
+func (Disjunction) isExpression() {}
+
+// Negation is an Expression that matches entries when its expression does not match. It's the result of Not.
+type Negation struct {
+	Expression Expression
+}
+
+func (Negation) isExpression() {}
+
+// Eq matches entries where field is equal to value.
+func Eq(field string, value any) Expression {
+	return Comparison{Field: field, Operator: OperatorEqual, Value: value}
+}
+
+// Neq matches entries where field is not equal to value.
+func Neq(field string, value any) Expression {
+	return Comparison{Field: field, Operator: OperatorNotEqual, Value: value}
+}
+
+// Lt matches entries where field is lower than value.
+func Lt(field string, value any) Expression {
+	return Comparison{Field: field, Operator: OperatorLessThan, Value: value}
+}
+
+// Lte matches entries where field is lower than or equal to value.
+func Lte(field string, value any) Expression {
+	return Comparison{Field: field, Operator: OperatorLessThanOrEqual, Value: value}
+}
+
+// Gt matches entries where field is greater than value.
+func Gt(field string, value any) Expression {
+	return Comparison{Field: field, Operator: OperatorGreaterThan, Value: value}
+}
+
+// Gte matches entries where field is greater than or equal to value.
+func Gte(field string, value any) Expression {
+	return Comparison{Field: field, Operator: OperatorGreaterThanOrEqual, Value: value}
+}
+
+// In matches entries where field is equal to any of the given values.
+// values should not be empty, some implementations do not support empty lists of values.
+//
+//	In("name", []string{"Andrew", "John"})
+func In[T any](field string, values []T) Expression {
+	return Comparison{Field: field, Operator: OperatorIn, Value: values}
+}
+
+// Contains matches entries where field contains value.
+//
+// The meaning of "contains" depends on the implementation:
+//
+//	SQL: field is a string that contains value as a substring.
+//	Firestore: field is an array that contains value as an element.
+func Contains(field string, value any) Expression {
+	return Comparison{Field: field, Operator: OperatorContains, Value: value}
+}
+
+// IsNull matches entries where field is null.
+func IsNull(field string) Expression {
+	return Comparison{Field: field, Operator: OperatorIsNull}
+}
+
+// IsNotNull matches entries where field is not null.
+func IsNotNull(field string) Expression {
+	return Comparison{Field: field, Operator: OperatorIsNotNull}
+}
+
+// And matches entries that match all the given expressions.
+func And(expressions ...Expression) Expression {
+	return Conjunction(expressions)
+}
+
+// Or matches entries that match any of the given expressions.
+func Or(expressions ...Expression) Expression {
+	return Disjunction(expressions)
+}
+
+// Not matches entries that don't match the given expression.
+func Not(expression Expression) Expression {
+	return Negation{Expression: expression}
+}
+
+// NewFilter initializes a new Filter from the given Expression.
+//
+//	repository.FindOne(ctx, &out, NewFilter(Eq("name", "Andrew")))
+func NewFilter(expression Expression) Filter {
+	return Filter{Expression: expression}
+}
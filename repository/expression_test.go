@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpressions(t *testing.T) {
+	assert.Equal(t, Comparison{Field: "name", Operator: OperatorEqual, Value: "test"}, Eq("name", "test"))
+	assert.Equal(t, Comparison{Field: "name", Operator: OperatorNotEqual, Value: "test"}, Neq("name", "test"))
+	assert.Equal(t, Comparison{Field: "value", Operator: OperatorLessThan, Value: 1}, Lt("value", 1))
+	assert.Equal(t, Comparison{Field: "value", Operator: OperatorLessThanOrEqual, Value: 1}, Lte("value", 1))
+	assert.Equal(t, Comparison{Field: "value", Operator: OperatorGreaterThan, Value: 1}, Gt("value", 1))
+	assert.Equal(t, Comparison{Field: "value", Operator: OperatorGreaterThanOrEqual, Value: 1}, Gte("value", 1))
+	assert.Equal(t, Comparison{Field: "value", Operator: OperatorIn, Value: []int{1, 2}}, In("value", []int{1, 2}))
+	assert.Equal(t, Comparison{Field: "name", Operator: OperatorContains, Value: "es"}, Contains("name", "es"))
+	assert.Equal(t, Comparison{Field: "name", Operator: OperatorIsNull}, IsNull("name"))
+	assert.Equal(t, Comparison{Field: "name", Operator: OperatorIsNotNull}, IsNotNull("name"))
+
+	and := And(Eq("a", 1), Eq("b", 2))
+	assert.Equal(t, Conjunction{Eq("a", 1), Eq("b", 2)}, and)
+	assert.Equal(t, Disjunction{and, Eq("c", 3)}, Or(and, Eq("c", 3)))
+	assert.Equal(t, Negation{Expression: and}, Not(and))
+}
+
+func TestNewFilter(t *testing.T) {
+	f := NewFilter(Eq("name", "test"))
+	assert.Empty(t, f.Template)
+	assert.Empty(t, f.Values)
+	assert.Equal(t, Eq("name", "test"), f.Expression)
+}
@@ -13,4 +13,8 @@ type Filter struct {
 	// The values are replaced in the order they are defined in the template.
 	// Example: `["Test", 33]`
 	Values []interface{}
+	// Expression contains a backend-agnostic filter expression. If set, Template and Values are ignored.
+	// Unlike Template, expressions are supported by all Repository implementations.
+	// Example: `And(Eq("name", "Test"), Eq("age", 33))`
+	Expression Expression
 }
@@ -0,0 +1,149 @@
+package firestore
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/gazebo-web/gz-go/v10/repository"
+)
+
+// newEntityFilter translates a repository.Expression into a firestore.EntityFilter.
+//
+// Negations are pushed down to the comparisons they contain, as Firestore does not support a NOT operator.
+// Note that negated comparisons in Firestore don't match documents where the field is not defined.
+//
+// It returns repository.ErrUnsupportedExpression if expr cannot be translated into a Firestore filter.
+func newEntityFilter(expr repository.Expression) (firestore.EntityFilter, error) {
+	switch e := expr.(type) {
+	case repository.Comparison:
+		return newPropertyFilter(e)
+	case repository.Conjunction:
+		filters, err := newEntityFilters(e)
+		if err != nil {
+			return nil, err
+		}
+		return firestore.AndFilter{Filters: filters}, nil
+	case repository.Disjunction:
+		filters, err := newEntityFilters(e)
+		if err != nil {
+			return nil, err
+		}
+		return firestore.OrFilter{Filters: filters}, nil
+	case repository.Negation:
+		negated, err := negate(e.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return newEntityFilter(negated)
+	default:
+		return nil, fmt.Errorf("%w: %T", repository.ErrUnsupportedExpression, expr)
+	}
+}
+
+// newEntityFilters translates a list of repository.Expression into a list of firestore.EntityFilter.
+func newEntityFilters(list []repository.Expression) ([]firestore.EntityFilter, error) {
+	if len(list) == 0 {
+		return nil, fmt.Errorf("%w: empty logical expression", repository.ErrUnsupportedExpression)
+	}
+	filters := make([]firestore.EntityFilter, len(list))
+	for i, e := range list {
+		var err error
+		if filters[i], err = newEntityFilter(e); err != nil {
+			return nil, err
+		}
+	}
+	return filters, nil
+}
+
+// newPropertyFilter translates a repository.Comparison into a firestore.PropertyFilter.
+//
+// repository.OperatorContains is translated into an "array-contains" filter: it matches documents where the field is
+// an array that contains the given value, unlike SQL implementations where it performs a substring match.
+func newPropertyFilter(c repository.Comparison) (firestore.PropertyFilter, error) {
+	f := firestore.PropertyFilter{
+		Path:     c.Field,
+		Operator: string(c.Operator),
+		Value:    c.Value,
+	}
+	switch c.Operator {
+	case repository.OperatorEqual, repository.OperatorNotEqual, repository.OperatorLessThan,
+		repository.OperatorLessThanOrEqual, repository.OperatorGreaterThan, repository.OperatorGreaterThanOrEqual,
+		repository.OperatorIn, repository.OperatorNotIn:
+	case repository.OperatorContains:
+		f.Operator = "array-contains"
+	case repository.OperatorIsNull:
+		f.Operator = "=="
+		f.Value = nil
+	case repository.OperatorIsNotNull:
+		f.Operator = "!="
+		f.Value = nil
+	default:
+		return f, fmt.Errorf("%w: operator %q", repository.ErrUnsupportedExpression, c.Operator)
+	}
+	return f, nil
+}
+
+// negate returns the negation of the given repository.Expression without using repository.Negation.
+// It returns repository.ErrUnsupportedExpression if the expression contains a comparison that cannot be negated.
+func negate(expr repository.Expression) (repository.Expression, error) {
+	switch e := expr.(type) {
+	case repository.Comparison:
+		op, err := negateOperator(e.Operator)
+		if err != nil {
+			return nil, err
+		}
+		e.Operator = op
+		return e, nil
+	case repository.Conjunction:
+		out := make(repository.Disjunction, len(e))
+		for i, inner := range e {
+			var err error
+			if out[i], err = negate(inner); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case repository.Disjunction:
+		out := make(repository.Conjunction, len(e))
+		for i, inner := range e {
+			var err error
+			if out[i], err = negate(inner); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case repository.Negation:
+		return e.Expression, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", repository.ErrUnsupportedExpression, expr)
+	}
+}
+
+// negateOperator returns the operator that matches the opposite set of values of the given operator.
+// Firestore does not provide a "not-array-contains" operator, so repository.OperatorContains cannot be negated.
+func negateOperator(op repository.Operator) (repository.Operator, error) {
+	switch op {
+	case repository.OperatorEqual:
+		return repository.OperatorNotEqual, nil
+	case repository.OperatorNotEqual:
+		return repository.OperatorEqual, nil
+	case repository.OperatorLessThan:
+		return repository.OperatorGreaterThanOrEqual, nil
+	case repository.OperatorLessThanOrEqual:
+		return repository.OperatorGreaterThan, nil
+	case repository.OperatorGreaterThan:
+		return repository.OperatorLessThanOrEqual, nil
+	case repository.OperatorGreaterThanOrEqual:
+		return repository.OperatorLessThan, nil
+	case repository.OperatorIn:
+		return repository.OperatorNotIn, nil
+	case repository.OperatorNotIn:
+		return repository.OperatorIn, nil
+	case repository.OperatorIsNull:
+		return repository.OperatorIsNotNull, nil
+	case repository.OperatorIsNotNull:
+		return repository.OperatorIsNull, nil
+	default:
+		return "", fmt.Errorf("%w: operator %q cannot be negated", repository.ErrUnsupportedExpression, op)
+	}
+}
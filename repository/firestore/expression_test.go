@@ -0,0 +1,83 @@
+package firestore
+
+import (
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mustEntityFilter translates expr into a firestore.EntityFilter, failing the test if the translation fails.
+func mustEntityFilter(t *testing.T, expr repository.Expression) firestore.EntityFilter {
+	f, err := newEntityFilter(expr)
+	require.NoError(t, err)
+	return f
+}
+
+func TestNewEntityFilter_Comparison(t *testing.T) {
+	assert.Equal(t, firestore.PropertyFilter{Path: "Name", Operator: "==", Value: "test"}, mustEntityFilter(t, repository.Eq("Name", "test")))
+	assert.Equal(t, firestore.PropertyFilter{Path: "Value", Operator: "<", Value: 1}, mustEntityFilter(t, repository.Lt("Value", 1)))
+	assert.Equal(t, firestore.PropertyFilter{Path: "Tags", Operator: "array-contains", Value: "a"}, mustEntityFilter(t, repository.Contains("Tags", "a")))
+	assert.Equal(t, firestore.PropertyFilter{Path: "Name", Operator: "==", Value: nil}, mustEntityFilter(t, repository.IsNull("Name")))
+	assert.Equal(t, firestore.PropertyFilter{Path: "Value", Operator: "in", Value: []int{1, 2}}, mustEntityFilter(t, repository.In("Value", []int{1, 2})))
+}
+
+func TestNewEntityFilter_Logical(t *testing.T) {
+	expr := repository.And(
+		repository.Eq("Name", "test"),
+		repository.Or(repository.Lt("Value", 1), repository.Gt("Value", 2)),
+	)
+	expected := firestore.AndFilter{Filters: []firestore.EntityFilter{
+		firestore.PropertyFilter{Path: "Name", Operator: "==", Value: "test"},
+		firestore.OrFilter{Filters: []firestore.EntityFilter{
+			firestore.PropertyFilter{Path: "Value", Operator: "<", Value: 1},
+			firestore.PropertyFilter{Path: "Value", Operator: ">", Value: 2},
+		}},
+	}}
+	assert.Equal(t, expected, mustEntityFilter(t, expr))
+}
+
+func TestNewEntityFilter_Not(t *testing.T) {
+	// Negations are pushed down using De Morgan's laws
+	expr := repository.Not(repository.And(
+		repository.Eq("Name", "test"),
+		repository.Or(repository.Lt("Value", 1), repository.In("Value", []int{5, 6})),
+	))
+	expected := firestore.OrFilter{Filters: []firestore.EntityFilter{
+		firestore.PropertyFilter{Path: "Name", Operator: "!=", Value: "test"},
+		firestore.AndFilter{Filters: []firestore.EntityFilter{
+			firestore.PropertyFilter{Path: "Value", Operator: ">=", Value: 1},
+			firestore.PropertyFilter{Path: "Value", Operator: "not-in", Value: []int{5, 6}},
+		}},
+	}}
+	assert.Equal(t, expected, mustEntityFilter(t, expr))
+
+	// Double negations cancel each other
+	assert.Equal(t, mustEntityFilter(t, repository.Eq("Name", "test")), mustEntityFilter(t, repository.Not(repository.Not(repository.Eq("Name", "test")))))
+
+	// Negating null checks
+	assert.Equal(t, firestore.PropertyFilter{Path: "Name", Operator: "!=", Value: nil}, mustEntityFilter(t, repository.Not(repository.IsNull("Name"))))
+}
+
+func TestNewEntityFilter_Unsupported(t *testing.T) {
+	// Contains cannot be negated in firestore
+	_, err := newEntityFilter(repository.Not(repository.Contains("Tags", "a")))
+	assert.ErrorIs(t, err, repository.ErrUnsupportedExpression)
+
+	_, err = newEntityFilter(repository.Not(repository.Or(repository.Eq("Name", "test"), repository.Contains("Tags", "a"))))
+	assert.ErrorIs(t, err, repository.ErrUnsupportedExpression)
+
+	_, err = newEntityFilter(nil)
+	assert.ErrorIs(t, err, repository.ErrUnsupportedExpression)
+
+	_, err = newEntityFilter(repository.And())
+	assert.ErrorIs(t, err, repository.ErrUnsupportedExpression)
+
+	_, err = newEntityFilter(repository.Or())
+	assert.ErrorIs(t, err, repository.ErrUnsupportedExpression)
+
+	_, err = newEntityFilter(repository.Comparison{Field: "Name", Operator: "like", Value: "test"})
+	assert.ErrorIs(t, err, repository.ErrUnsupportedExpression)
+}
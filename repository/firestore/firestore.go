@@ -2,12 +2,35 @@ package firestore
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"cloud.google.com/go/firestore"
-	"github.com/gazebo-web/gz-go/v10/errors"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
 	"github.com/gazebo-web/gz-go/v10/reflect"
 	"github.com/gazebo-web/gz-go/v10/repository"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrInvalidUpdateData is returned when Update receives data that is not a map[string]interface{}.
+	ErrInvalidUpdateData = errors.New("update data must be a map[string]interface{}")
+)
+
+const (
+	// fieldCreatedAt is the name of the document field that contains the creation timestamp of a Model.
+	fieldCreatedAt = "created_at"
+	// fieldUpdatedAt is the name of the document field that contains the last update timestamp of a Model.
+	fieldUpdatedAt = "updated_at"
+	// aliasCount is the alias used to get the result of count aggregation queries.
+	aliasCount = "count"
+	// aliasSum is the alias used to get the result of sum aggregation queries.
+	aliasSum = "sum"
+	// aliasAvg is the alias used to get the result of average aggregation queries.
+	aliasAvg = "avg"
 )
 
 // firestoreRepository implements Repository using the firestore client.
@@ -15,19 +38,182 @@ type firestoreRepository[T repository.Model] struct {
 	client *firestore.Client
 }
 
-// FirstOrCreate is not implemented.
+// FirstOrCreate inserts a new entry if the given filters don't find any existing record.
+// The lookup and the creation of the entry are performed inside a transaction. If ctx already carries a transaction,
+// it will be used instead of starting a new one.
+//
+//	entity: must be a pointer to a Model implementation. Results will be saved in this argument if the record exists.
 func (r *firestoreRepository[T]) FirstOrCreate(ctx context.Context, entity repository.Model, filters ...repository.Filter) error {
-	return errors.ErrMethodNotImplemented
+	_, err := r.FirstOrCreateAndReport(ctx, entity, filters...)
+	return err
+}
+
+// FirstOrCreateAndReport inserts a new entry if the given filters don't find any existing record, and returns true if
+// the entry was created. The operation is performed as described in FirstOrCreate.
+func (r *firestoreRepository[T]) FirstOrCreateAndReport(ctx context.Context, entity repository.Model, filters ...repository.Filter) (bool, error) {
+	col := r.collection()
+	q, err := r.setQueryFilters(col.Query, filters)
+	if err != nil {
+		return false, err
+	}
+	var created bool
+	err = NewTransactioner(r.client).WithTransaction(ctx, func(ctx context.Context) error {
+		// The transaction function can be retried, created is only set by the last attempt.
+		created = false
+		tx := transactionFromContext(ctx)
+		docs, err := tx.Documents(q.Limit(1)).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(docs) > 0 {
+			return r.decode(docs[0], entity)
+		}
+		ref := r.newDocumentRef(col, entity)
+		r.prepareCreate(entity, ref, time.Now())
+		created = true
+		return tx.Create(ref, entity)
+	})
+	if err != nil {
+		return false, err
+	}
+	return created, nil
 }
 
-// Create is not implemented.
+// Create inserts a single entry. If entity embeds Model, its ID and timestamps are populated.
+// A new document ID is generated unless the entity already contains one.
+//
+//	entity: The entry to insert.
 func (r *firestoreRepository[T]) Create(ctx context.Context, entity repository.Model) (repository.Model, error) {
-	return nil, errors.ErrMethodNotImplemented
+	ref := r.newDocumentRef(r.collection(), entity)
+	r.prepareCreate(entity, ref, time.Now())
+	if tx := transactionFromContext(ctx); tx != nil {
+		if err := tx.Create(ref, entity); err != nil {
+			return nil, err
+		}
+		return entity, nil
+	}
+	if _, err := ref.Create(ctx, entity); err != nil {
+		return nil, err
+	}
+	return entity, nil
 }
 
-// CreateBulk is not implemented.
+// CreateBulk creates multiple entries using a firestore.BulkWriter.
+// Firestore bulk writes are not atomic, entries that were created before a failure are not removed.
+// If ctx carries a transaction, entries are created as part of the transaction instead.
+//
+//	entities: should be a slice of the same data structure implementing repository.Model.
 func (r *firestoreRepository[T]) CreateBulk(ctx context.Context, entities []repository.Model) ([]repository.Model, error) {
-	return nil, errors.ErrMethodNotImplemented
+	if len(entities) == 0 {
+		return entities, nil
+	}
+	col := r.collection()
+	now := time.Now()
+	if tx := transactionFromContext(ctx); tx != nil {
+		for _, entity := range entities {
+			ref := r.newDocumentRef(col, entity)
+			r.prepareCreate(entity, ref, now)
+			if err := tx.Create(ref, entity); err != nil {
+				return nil, err
+			}
+		}
+		return entities, nil
+	}
+	writer := r.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, len(entities))
+	for i, entity := range entities {
+		ref := r.newDocumentRef(col, entity)
+		r.prepareCreate(entity, ref, now)
+		job, err := writer.Create(ref, entity)
+		if err != nil {
+			writer.End()
+			return nil, err
+		}
+		jobs[i] = job
+	}
+	writer.End()
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return nil, err
+		}
+	}
+	return entities, nil
+}
+
+// Upsert inserts a single document, or updates the existing document that conflicts with it.
+// The lookup and the write are performed inside a transaction. If ctx already carries a transaction, it will be used
+// instead of starting a new one.
+//
+// Existing documents are updated using Set with merge: the fields of entity overwrite the fields of the document, and
+// any other document fields are kept. The creation timestamp of existing documents is preserved.
+//
+//	entity: must be a pointer to a struct implementing repository.Model.
+//	conflictFields: document fields used to find the existing document. If empty, the document ID of entity is used,
+//	  and a new document is created if entity doesn't have one.
+func (r *firestoreRepository[T]) Upsert(ctx context.Context, entity repository.Model, conflictFields ...string) error {
+	fields := documentFields(entity)
+	if len(fields) == 0 {
+		return repository.ErrInvalidModelType
+	}
+	paths := make([]firestore.FieldPath, 0, len(fields))
+	for field := range fields {
+		paths = append(paths, firestore.FieldPath{field})
+	}
+
+	col := r.collection()
+	q := col.Query
+	for _, field := range conflictFields {
+		value, ok := fields[field]
+		if !ok {
+			return fmt.Errorf("unknown conflict field %s", field)
+		}
+		q = q.Where(field, "==", value)
+	}
+
+	return NewTransactioner(r.client).WithTransaction(ctx, func(ctx context.Context) error {
+		tx := transactionFromContext(ctx)
+		existing, err := r.findConflict(col, tx, q, entity, conflictFields)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if existing == nil {
+			ref := r.newDocumentRef(col, entity)
+			r.prepareCreate(entity, ref, now)
+			return tx.Set(ref, entity)
+		}
+		if d, ok := entity.(document); ok {
+			d.setDocumentID(existing.Ref.ID)
+			if createdAt, ok := existing.Data()[fieldCreatedAt].(time.Time); ok {
+				d.setCreatedAt(createdAt)
+			}
+			d.setTimestamps(now)
+		}
+		return tx.Set(existing.Ref, entity, firestore.Merge(paths...))
+	})
+}
+
+// findConflict returns the document that conflicts with entity in an Upsert operation, or nil if there is none.
+func (r *firestoreRepository[T]) findConflict(col *firestore.CollectionRef, tx *firestore.Transaction, q firestore.Query, entity repository.Model, conflictFields []string) (*firestore.DocumentSnapshot, error) {
+	if len(conflictFields) > 0 {
+		docs, err := tx.Documents(q.Limit(1)).GetAll()
+		if err != nil || len(docs) == 0 {
+			return nil, err
+		}
+		return docs[0], nil
+	}
+	d, ok := entity.(document)
+	if !ok || len(d.GetDocumentID()) == 0 {
+		return nil, nil
+	}
+	doc, err := tx.Get(col.Doc(d.GetDocumentID()))
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
 }
 
 // Find filters entries and stores filtered entries in output.
@@ -35,17 +221,18 @@ func (r *firestoreRepository[T]) CreateBulk(ctx context.Context, entities []repo
 //	output: will contain the result of the query. It must be a pointer to a slice.
 //	options: configuration options for the search.
 func (r *firestoreRepository[T]) Find(ctx context.Context, output interface{}, options ...repository.Option) error {
-	col := r.client.Collection(r.Model().TableName())
-	r.applyOptions(&col.Query, options...)
-	iter := col.Documents(ctx)
-	docs, err := iter.GetAll()
+	col := r.collection()
+	if err := r.applyOptions(&col.Query, options...); err != nil {
+		return err
+	}
+	docs, err := r.documents(ctx, col.Query).GetAll()
 	if err != nil {
 		return err
 	}
 
-	var element T
 	for _, doc := range docs {
-		if err := doc.DataTo(&element); err != nil {
+		var element T
+		if err := r.decode(doc, &element); err != nil {
 			continue
 		}
 
@@ -57,19 +244,175 @@ func (r *firestoreRepository[T]) Find(ctx context.Context, output interface{}, o
 	return nil
 }
 
-// FindOne is not implemented.
+// Iterate calls fn with every entry found using the given options. Documents are read using the query iterator, and
+// each entry passed to fn is a pointer to a new instance of the repository model.
+func (r *firestoreRepository[T]) Iterate(ctx context.Context, fn repository.IterateFunc, options ...repository.Option) error {
+	col := r.collection()
+	if err := r.applyOptions(&col.Query, options...); err != nil {
+		return err
+	}
+	iter := r.documents(ctx, col.Query)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var element T
+		if err := r.decode(doc, &element); err != nil {
+			return err
+		}
+		entry, ok := any(&element).(repository.Model)
+		if !ok {
+			entry = element
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// FindOne filters entries and stores the first filtered entry in output. It returns repository.ErrNotFound if no
+// entries match the given filters.
+//
+//	output: must be a pointer to a Model implementation.
+//	filters: must contain filter expressions, filter templates are not supported.
 func (r *firestoreRepository[T]) FindOne(ctx context.Context, output repository.Model, filters ...repository.Filter) error {
-	return errors.ErrMethodNotImplemented
+	if len(filters) == 0 {
+		return repository.ErrNoFilter
+	}
+	q, err := r.setQueryFilters(r.collection().Query, filters)
+	if err != nil {
+		return err
+	}
+	return r.first(ctx, q, output)
 }
 
-// Last is not implemented.
+// Last gets the last record ordered by creation date desc. It returns repository.ErrNotFound if no entries match the
+// given filters.
+//
+//	output: must be a pointer to a Model implementation.
+//	filters: must contain filter expressions, filter templates are not supported.
 func (r *firestoreRepository[T]) Last(ctx context.Context, output repository.Model, filters ...repository.Filter) error {
-	return errors.ErrMethodNotImplemented
+	if len(filters) == 0 {
+		return repository.ErrNoFilter
+	}
+	q, err := r.setQueryFilters(r.collection().Query, filters)
+	if err != nil {
+		return err
+	}
+	return r.first(ctx, q.OrderBy(fieldCreatedAt, firestore.Desc), output)
 }
 
-// Update is not implemented.
+// Update updates all model entries that match the provided filters with the given data.
+// If the repository model embeds Model, the update timestamp of every entry is updated as well.
+//
+//	data: must be a map[string]interface{}, where keys are document field paths.
+//	filters: must contain filter expressions, filter templates are not supported.
 func (r *firestoreRepository[T]) Update(ctx context.Context, data interface{}, filters ...repository.Filter) error {
-	return errors.ErrMethodNotImplemented
+	if len(filters) == 0 {
+		return repository.ErrNoFilter
+	}
+	values, ok := data.(map[string]interface{})
+	if !ok {
+		return ErrInvalidUpdateData
+	}
+	q, err := r.setQueryFilters(r.collection().Query, filters)
+	if err != nil {
+		return err
+	}
+	updates := r.newUpdates(values)
+
+	iter := r.documents(ctx, q)
+	defer iter.Stop()
+
+	// Writes inside a transaction are buffered and sent when the transaction is committed.
+	if tx := transactionFromContext(ctx); tx != nil {
+		return iterateDocuments(iter, func(doc *firestore.DocumentSnapshot) error {
+			return tx.Update(doc.Ref, updates)
+		})
+	}
+
+	writer := r.client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	err = iterateDocuments(iter, func(doc *firestore.DocumentSnapshot) error {
+		job, err := writer.Update(doc.Ref, updates)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, job)
+		return nil
+	})
+	writer.End()
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateIf updates all the documents that match filters with data, only if every one of them meets precondition.
+// If any of the documents does not meet the precondition, no documents are updated and repository.ErrConflict is
+// returned.
+//
+// Documents are read and updated inside a transaction, and each update is sent with a precondition on the last update
+// time of the document that was read. If ctx already carries a transaction, it will be used instead of starting a new
+// one.
+//
+//	data: must be a map[string]interface{}. Documents updated with a repository.VersionPrecondition get their
+//	  version incremented.
+//	precondition: the condition that documents must meet in order to be updated.
+//	filters: selection criteria for documents that should be updated.
+func (r *firestoreRepository[T]) UpdateIf(ctx context.Context, data interface{}, precondition repository.Precondition, filters ...repository.Filter) error {
+	if len(filters) == 0 {
+		return repository.ErrNoFilter
+	}
+	values, ok := data.(map[string]interface{})
+	if !ok {
+		return ErrInvalidUpdateData
+	}
+	q, err := r.setQueryFilters(r.collection().Query, filters)
+	if err != nil {
+		return err
+	}
+	updates := r.newUpdates(values)
+	switch p := precondition.(type) {
+	case repository.VersionPrecondition:
+		updates = append(updates, firestore.Update{Path: p.Field, Value: firestore.Increment(1)})
+	case repository.UpdatedAtPrecondition:
+	default:
+		return fmt.Errorf("unsupported precondition %T", precondition)
+	}
+
+	err = NewTransactioner(r.client).WithTransaction(ctx, func(ctx context.Context) error {
+		tx := transactionFromContext(ctx)
+		docs, err := tx.Documents(q).GetAll()
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			if !meetsPrecondition(doc, precondition) {
+				return repository.ErrConflict
+			}
+		}
+		for _, doc := range docs {
+			if err := tx.Update(doc.Ref, updates, firestore.LastUpdateTime(doc.UpdateTime)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if status.Code(err) == codes.FailedPrecondition {
+		return repository.ErrConflict
+	}
+	return err
 }
 
 // Delete deletes all the entities that match the given options.
@@ -81,10 +424,26 @@ func (r *firestoreRepository[T]) Update(ctx context.Context, data interface{}, f
 // to implement soft deletes.
 //
 // Delete does not remove all the records at once, it will perform the document removal in small batches. This mechanism
-// prevents running into out-of-memory errors.
+// prevents running into out-of-memory errors. If ctx carries a transaction, all the matching documents are deleted
+// as part of the transaction instead.
 func (r *firestoreRepository[T]) Delete(ctx context.Context, opts ...repository.Option) error {
-	col := r.client.Collection(r.Model().TableName())
-	r.applyOptions(&col.Query, opts...)
+	col := r.collection()
+	if err := r.applyOptions(&col.Query, opts...); err != nil {
+		return err
+	}
+
+	if tx := transactionFromContext(ctx); tx != nil {
+		docs, err := tx.Documents(col.Query).GetAll()
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			if err := tx.Delete(doc.Ref); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 
 	err := r.deleteBatch(ctx, col, 30)
 	if err != nil {
@@ -132,9 +491,84 @@ func (r *firestoreRepository[T]) deleteBatch(ctx context.Context, col *firestore
 	return nil
 }
 
-// Count is not implemented.
+// Count counts all the model entries that match filters using an aggregation query.
+//
+//	filters: must contain filter expressions, filter templates are not supported.
 func (r *firestoreRepository[T]) Count(ctx context.Context, filters ...repository.Filter) (uint64, error) {
-	return 0, errors.ErrMethodNotImplemented
+	v, err := r.aggregate(ctx, filters, aliasCount, func(aq *firestore.AggregationQuery) *firestore.AggregationQuery {
+		return aq.WithCount(aliasCount)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return uint64(v.GetIntegerValue()), nil
+}
+
+// Sum returns the sum of the values of field in the entries that match filters using an aggregation query.
+// Non-numeric values are ignored.
+//
+//	filters: must contain filter expressions, filter templates are not supported.
+func (r *firestoreRepository[T]) Sum(ctx context.Context, field string, filters ...repository.Filter) (float64, error) {
+	v, err := r.aggregate(ctx, filters, aliasSum, func(aq *firestore.AggregationQuery) *firestore.AggregationQuery {
+		return aq.WithSum(field, aliasSum)
+	})
+	if err != nil {
+		return 0, err
+	}
+	sum, _ := aggregationNumber(v)
+	return sum, nil
+}
+
+// Avg returns the average of the values of field in the entries that match filters using an aggregation query.
+// Non-numeric values are ignored.
+//
+//	filters: must contain filter expressions, filter templates are not supported.
+func (r *firestoreRepository[T]) Avg(ctx context.Context, field string, filters ...repository.Filter) (float64, error) {
+	v, err := r.aggregate(ctx, filters, aliasAvg, func(aq *firestore.AggregationQuery) *firestore.AggregationQuery {
+		return aq.WithAvg(field, aliasAvg)
+	})
+	if err != nil {
+		return 0, err
+	}
+	avg, ok := aggregationNumber(v)
+	if !ok {
+		return 0, repository.ErrNotFound
+	}
+	return avg, nil
+}
+
+// aggregate runs the aggregation query returned by with over the entries that match filters, and returns the result
+// identified by alias. If ctx carries a transaction, the query is run as part of the transaction.
+func (r *firestoreRepository[T]) aggregate(ctx context.Context, filters []repository.Filter, alias string, with func(aq *firestore.AggregationQuery) *firestore.AggregationQuery) (*firestorepb.Value, error) {
+	q, err := r.setQueryFilters(r.collection().Query, filters)
+	if err != nil {
+		return nil, err
+	}
+	aq := with(q.NewAggregationQuery())
+	if tx := transactionFromContext(ctx); tx != nil {
+		aq = aq.Transaction(tx)
+	}
+	res, err := aq.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := res[alias].(*firestorepb.Value)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s aggregation result: %v", alias, res[alias])
+	}
+	return v, nil
+}
+
+// aggregationNumber returns the numeric value of an aggregation result. It returns false if the result is null.
+func aggregationNumber(v *firestorepb.Value) (float64, bool) {
+	switch x := v.GetValueType().(type) {
+	case *firestorepb.Value_IntegerValue:
+		return float64(x.IntegerValue), true
+	case *firestorepb.Value_DoubleValue:
+		return x.DoubleValue, true
+	default:
+		return 0, false
+	}
 }
 
 // Model returns this repository's model.
@@ -143,10 +577,147 @@ func (r *firestoreRepository[T]) Model() repository.Model {
 	return baseModel
 }
 
-func (r *firestoreRepository[T]) applyOptions(q *firestore.Query, opts ...repository.Option) {
+// applyOptions applies operation options to a firestore query. It returns the error held by the first option that
+// cannot be applied.
+func (r *firestoreRepository[T]) applyOptions(q *firestore.Query, opts ...repository.Option) error {
 	for _, opt := range opts {
+		if o, ok := opt.(invalidOption); ok {
+			return o.err
+		}
 		opt.(Option)(q)
 	}
+	return nil
+}
+
+// collection returns the collection reference of this repository's model.
+func (r *firestoreRepository[T]) collection() *firestore.CollectionRef {
+	return r.client.Collection(r.Model().TableName())
+}
+
+// setQueryFilters applies the given filters to a firestore query.
+// Firestore queries only support filters with an Expression, it returns repository.ErrUnsupportedExpression otherwise.
+func (r *firestoreRepository[T]) setQueryFilters(q firestore.Query, filters []repository.Filter) (firestore.Query, error) {
+	for _, f := range filters {
+		if f.Expression == nil {
+			return q, fmt.Errorf("%w: firestore filters must contain an expression", repository.ErrUnsupportedExpression)
+		}
+		ef, err := newEntityFilter(f.Expression)
+		if err != nil {
+			return q, err
+		}
+		q = q.WhereEntity(ef)
+	}
+	return q, nil
+}
+
+// documents returns an iterator over the documents returned by the given query. If ctx carries a transaction, the
+// query is run as part of the transaction.
+func (r *firestoreRepository[T]) documents(ctx context.Context, q firestore.Query) *firestore.DocumentIterator {
+	if tx := transactionFromContext(ctx); tx != nil {
+		return tx.Documents(q)
+	}
+	return q.Documents(ctx)
+}
+
+// iterateDocuments calls fn with every document returned by iter, until iter is exhausted or fn returns an error.
+func iterateDocuments(iter *firestore.DocumentIterator, fn func(doc *firestore.DocumentSnapshot) error) error {
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+}
+
+// first stores the first document returned by the given query in output.
+// It returns repository.ErrNotFound if the query returns no documents.
+func (r *firestoreRepository[T]) first(ctx context.Context, q firestore.Query, output repository.Model) error {
+	iter := r.documents(ctx, q.Limit(1))
+	defer iter.Stop()
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return r.decode(doc, output)
+}
+
+// decode populates output with the content of the given document. output must be a pointer.
+// If output embeds Model, the document ID is populated as well.
+func (r *firestoreRepository[T]) decode(doc *firestore.DocumentSnapshot, output any) error {
+	if err := doc.DataTo(output); err != nil {
+		return err
+	}
+	if d, ok := output.(document); ok {
+		d.setDocumentID(doc.Ref.ID)
+	}
+	return nil
+}
+
+// newDocumentRef returns the document reference where the given entity will be created. If the entity already contains
+// a document ID, it will be used. Otherwise, a new document ID is generated.
+func (r *firestoreRepository[T]) newDocumentRef(col *firestore.CollectionRef, entity repository.Model) *firestore.DocumentRef {
+	if d, ok := entity.(document); ok && len(d.GetDocumentID()) > 0 {
+		return col.Doc(d.GetDocumentID())
+	}
+	return col.NewDoc()
+}
+
+// prepareCreate populates the ID and timestamps of an entity that embeds Model before it gets created.
+func (r *firestoreRepository[T]) prepareCreate(entity repository.Model, ref *firestore.DocumentRef, t time.Time) {
+	if d, ok := entity.(document); ok {
+		d.setDocumentID(ref.ID)
+		d.setTimestamps(t)
+	}
+}
+
+// newUpdates converts the given values into a list of document updates.
+// The update timestamp is added if the model embeds Model and values doesn't set it.
+func (r *firestoreRepository[T]) newUpdates(values map[string]interface{}) []firestore.Update {
+	updates := make([]firestore.Update, 0, len(values)+2)
+	for path, value := range values {
+		updates = append(updates, firestore.Update{Path: path, Value: value})
+	}
+	if _, set := values[fieldUpdatedAt]; r.hasTimestamps() && !set {
+		updates = append(updates, firestore.Update{Path: fieldUpdatedAt, Value: time.Now()})
+	}
+	return updates
+}
+
+// meetsPrecondition returns true if doc meets the given precondition.
+func meetsPrecondition(doc *firestore.DocumentSnapshot, precondition repository.Precondition) bool {
+	switch p := precondition.(type) {
+	case repository.VersionPrecondition:
+		v, err := doc.DataAt(p.Field)
+		if err != nil {
+			return false
+		}
+		version, ok := v.(int64)
+		return ok && version >= 0 && uint64(version) == p.Version
+	case repository.UpdatedAtPrecondition:
+		v, err := doc.DataAt(fieldUpdatedAt)
+		if err != nil {
+			return false
+		}
+		t, ok := v.(time.Time)
+		return ok && t.Equal(p.UpdatedAt)
+	default:
+		return false
+	}
+}
+
+// hasTimestamps returns true if this repository's model embeds Model.
+func (r *firestoreRepository[T]) hasTimestamps() bool {
+	_, ok := any(new(T)).(document)
+	return ok
 }
 
 // NewFirestoreRepository initializes a new Repository implementation for Firestore collections.
@@ -155,3 +726,8 @@ func NewFirestoreRepository[T repository.Model](client *firestore.Client) reposi
 		client: client,
 	}
 }
+
+// NewTypedFirestoreRepository initializes a new repository.TypedRepository implementation for Firestore collections.
+func NewTypedFirestoreRepository[T repository.Model](client *firestore.Client) repository.TypedRepository[T] {
+	return repository.NewTypedRepository[T](NewFirestoreRepository[T](client))
+}
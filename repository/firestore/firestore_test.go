@@ -2,14 +2,15 @@ package firestore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	firebase "firebase.google.com/go/v4"
-	"github.com/gazebo-web/gz-go/v10/errors"
 	"github.com/gazebo-web/gz-go/v10/repository"
 	"github.com/stretchr/testify/suite"
 )
@@ -69,21 +70,69 @@ func (suite *FirestoreRepositoryTestSuite) clearFirestoreData() {
 }
 
 func (suite *FirestoreRepositoryTestSuite) TestFirstOrCreate() {
-	err := suite.repository.FirstOrCreate(context.Background(), nil)
-	suite.Assert().Error(err)
-	suite.Assert().ErrorIs(err, errors.ErrMethodNotImplemented)
+	suite.setupMockData()
+
+	// An existing entry should be returned
+	var existing Test
+	suite.Require().NoError(suite.repository.FirstOrCreate(context.Background(), &existing, repository.NewFilter(repository.Eq("Value", 1))))
+	suite.Assert().Equal("test-1", existing.Name)
+	suite.Assert().NotEmpty(existing.ID)
+
+	// A missing entry should be created
+	created := Test{Name: "test-4", Value: 4}
+	suite.Require().NoError(suite.repository.FirstOrCreate(context.Background(), &created, repository.NewFilter(repository.Eq("Value", 4))))
+	suite.Assert().NotEmpty(created.ID)
+	suite.Assert().False(created.CreatedAt.IsZero())
+
+	count, err := suite.repository.Count(context.Background())
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(4), count)
+
+	// Filters must contain expressions
+	err = suite.repository.FirstOrCreate(context.Background(), &Test{}, repository.Filter{Template: "Value = ?", Values: []interface{}{1}})
+	suite.Assert().ErrorIs(err, repository.ErrUnsupportedExpression)
 }
 
 func (suite *FirestoreRepositoryTestSuite) TestCreate() {
-	_, err := suite.repository.Create(context.Background(), nil)
+	suite.clearFirestoreData()
+
+	entity := &Test{Name: "test", Value: 10}
+	created, err := suite.repository.Create(context.Background(), entity)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(entity, created)
+	suite.Assert().NotEmpty(entity.ID)
+	suite.Assert().False(entity.CreatedAt.IsZero())
+	suite.Assert().Equal(entity.CreatedAt, entity.UpdatedAt)
+
+	var found Test
+	suite.Require().NoError(suite.repository.FindOne(context.Background(), &found, repository.NewFilter(repository.Eq("Value", 10))))
+	suite.Assert().Equal(entity.ID, found.ID)
+	suite.Assert().Equal("test", found.Name)
+
+	// Creating an entity with an existing ID should fail
+	_, err = suite.repository.Create(context.Background(), &Test{Model: Model{ID: entity.ID}})
 	suite.Assert().Error(err)
-	suite.Assert().ErrorIs(err, errors.ErrMethodNotImplemented)
 }
 
 func (suite *FirestoreRepositoryTestSuite) TestCreateBulk() {
-	_, err := suite.repository.CreateBulk(context.Background(), nil)
-	suite.Assert().Error(err)
-	suite.Assert().ErrorIs(err, errors.ErrMethodNotImplemented)
+	suite.clearFirestoreData()
+
+	entities := []repository.Model{
+		&Test{Name: "test-1", Value: 1},
+		&Test{Name: "test-2", Value: 2},
+		&Test{Model: Model{ID: "custom"}, Name: "test-3", Value: 3},
+	}
+	created, err := suite.repository.CreateBulk(context.Background(), entities)
+	suite.Require().NoError(err)
+	suite.Require().Len(created, 3)
+	for _, e := range created {
+		suite.Assert().NotEmpty(e.(*Test).ID)
+	}
+	suite.Assert().Equal("custom", created[2].(*Test).ID)
+
+	var found []Test
+	suite.Require().NoError(suite.repository.Find(context.Background(), &found))
+	suite.Assert().Len(found, 3)
 }
 
 func (suite *FirestoreRepositoryTestSuite) TestFind_All() {
@@ -153,6 +202,24 @@ func (suite *FirestoreRepositoryTestSuite) TestFind_Where() {
 	suite.Assert().Equal(1, found[0].Value)
 }
 
+func (suite *FirestoreRepositoryTestSuite) TestFind_WhereExpression() {
+	var found []Test
+
+	suite.setupMockData()
+
+	suite.Require().NoError(suite.repository.Find(context.Background(), &found, WhereExpression(
+		repository.Or(repository.Eq("Value", 1), repository.Gt("Value", 2)),
+	)))
+	suite.Assert().Len(found, 2)
+
+	found = nil
+	suite.Require().NoError(suite.repository.Find(context.Background(), &found, WhereExpression(
+		repository.Not(repository.In("Name", []string{"test-1", "test-2"})),
+	)))
+	suite.Require().Len(found, 1)
+	suite.Assert().Equal("test-3", found[0].Name)
+}
+
 func (suite *FirestoreRepositoryTestSuite) TestFind_Pagination_PageWithStartAfter() {
 	suite.setupMockData()
 
@@ -248,22 +315,196 @@ func (suite *FirestoreRepositoryTestSuite) TestFind_In_EmptyValues() {
 	suite.Assert().Equal(found, expected)
 }
 
+func (suite *FirestoreRepositoryTestSuite) TestUpsert_DocumentID() {
+	suite.clearFirestoreData()
+	ctx := context.Background()
+
+	// Entities without a document ID are created
+	entity := &Test{Name: "test-1", Value: 1}
+	suite.Require().NoError(suite.repository.Upsert(ctx, entity))
+	suite.Require().NotEmpty(entity.ID)
+	createdAt := entity.CreatedAt
+
+	// Set merges the entity into the existing document, keeping other fields and the creation time
+	_, err := suite.fs.Collection("test").Doc(entity.ID).Update(ctx, []firestore.Update{{Path: "Extra", Value: "extra"}})
+	suite.Require().NoError(err)
+
+	updated := &Test{Model: Model{ID: entity.ID}, Name: "test-2", Value: 2}
+	suite.Require().NoError(suite.repository.Upsert(ctx, updated))
+	suite.Assert().True(createdAt.Equal(updated.CreatedAt))
+
+	doc, err := suite.fs.Collection("test").Doc(entity.ID).Get(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("test-2", doc.Data()["Name"])
+	suite.Assert().Equal("extra", doc.Data()["Extra"])
+
+	count, err := suite.repository.Count(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(1), count)
+}
+
+func (suite *FirestoreRepositoryTestSuite) TestUpsert_ConflictFields() {
+	suite.setupMockData()
+	ctx := context.Background()
+
+	entity := &Test{Name: "test-1", Value: 10}
+	suite.Require().NoError(suite.repository.Upsert(ctx, entity, "Name"))
+	suite.Assert().NotEmpty(entity.ID)
+
+	count, err := suite.repository.Count(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(3), count)
+
+	var found Test
+	suite.Require().NoError(suite.repository.FindOne(ctx, &found, repository.NewFilter(repository.Eq("Name", "test-1"))))
+	suite.Assert().Equal(10, found.Value)
+	suite.Assert().Equal(entity.ID, found.ID)
+
+	// Entries that don't conflict are created
+	suite.Require().NoError(suite.repository.Upsert(ctx, &Test{Name: "test-4", Value: 4}, "Name"))
+	count, err = suite.repository.Count(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(4), count)
+
+	// Unknown fields
+	suite.Assert().Error(suite.repository.Upsert(ctx, &Test{}, "Invalid"))
+}
+
+func (suite *FirestoreRepositoryTestSuite) TestIterate() {
+	suite.setupMockData()
+	ctx := context.Background()
+
+	iter, ok := suite.repository.(repository.Iterator)
+	suite.Require().True(ok)
+
+	var names []string
+	err := iter.Iterate(ctx, func(entry repository.Model) error {
+		test, ok := entry.(*Test)
+		suite.Require().True(ok)
+		suite.Assert().NotEmpty(test.ID)
+		names = append(names, test.Name)
+		return nil
+	}, OrderBy(Descending("Value")))
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{"test-3", "test-2", "test-1"}, names)
+
+	// Iteration stops when fn returns an error
+	errStop := errors.New("stop")
+	var count int
+	err = iter.Iterate(ctx, func(entry repository.Model) error {
+		count++
+		return errStop
+	})
+	suite.Assert().ErrorIs(err, errStop)
+	suite.Assert().Equal(1, count)
+}
+
 func (suite *FirestoreRepositoryTestSuite) TestFindOne() {
-	err := suite.repository.FindOne(context.Background(), nil)
-	suite.Assert().Error(err)
-	suite.Assert().ErrorIs(err, errors.ErrMethodNotImplemented)
+	suite.setupMockData()
+
+	var found Test
+	suite.Require().NoError(suite.repository.FindOne(context.Background(), &found, repository.NewFilter(repository.Eq("Name", "test-2"))))
+	suite.Assert().Equal(2, found.Value)
+	suite.Assert().NotEmpty(found.ID)
+
+	// No matches
+	err := suite.repository.FindOne(context.Background(), &found, repository.NewFilter(repository.Eq("Name", "test-20")))
+	suite.Assert().ErrorIs(err, repository.ErrNotFound)
+
+	// No filters
+	err = suite.repository.FindOne(context.Background(), &found)
+	suite.Assert().ErrorIs(err, repository.ErrNoFilter)
 }
 
 func (suite *FirestoreRepositoryTestSuite) TestLast() {
-	err := suite.repository.Last(context.Background(), nil)
-	suite.Assert().Error(err)
-	suite.Assert().ErrorIs(err, errors.ErrMethodNotImplemented)
+	suite.clearFirestoreData()
+
+	for i := 1; i <= 3; i++ {
+		_, err := suite.repository.Create(context.Background(), &Test{
+			Model: Model{CreatedAt: time.Now().Add(time.Duration(i) * time.Minute)},
+			Name:  "test",
+			Value: i,
+		})
+		suite.Require().NoError(err)
+	}
+
+	var last Test
+	suite.Require().NoError(suite.repository.Last(context.Background(), &last, repository.NewFilter(repository.Eq("Name", "test"))))
+	suite.Assert().Equal(3, last.Value)
 }
 
 func (suite *FirestoreRepositoryTestSuite) TestUpdate() {
-	err := suite.repository.Update(context.Background(), nil)
-	suite.Assert().Error(err)
-	suite.Assert().ErrorIs(err, errors.ErrMethodNotImplemented)
+	suite.setupMockData()
+
+	err := suite.repository.Update(context.Background(), map[string]interface{}{"Name": "updated"}, repository.NewFilter(repository.Lt("Value", 3)))
+	suite.Require().NoError(err)
+
+	count, err := suite.repository.Count(context.Background(), repository.NewFilter(repository.Eq("Name", "updated")))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(2), count)
+
+	var found Test
+	suite.Require().NoError(suite.repository.FindOne(context.Background(), &found, repository.NewFilter(repository.Eq("Value", 1))))
+	suite.Assert().Equal("updated", found.Name)
+	suite.Assert().False(found.UpdatedAt.IsZero())
+
+	// Invalid input
+	suite.Assert().ErrorIs(suite.repository.Update(context.Background(), map[string]interface{}{"Name": "updated"}), repository.ErrNoFilter)
+	suite.Assert().ErrorIs(suite.repository.Update(context.Background(), Test{}, repository.NewFilter(repository.Eq("Value", 1))), ErrInvalidUpdateData)
+}
+
+func (suite *FirestoreRepositoryTestSuite) TestUpdateIf_Version() {
+	suite.setupMockData()
+	ctx := context.Background()
+	updater := suite.repository.(repository.ConditionalUpdater)
+	filter := repository.NewFilter(repository.Eq("Value", 1))
+
+	// Documents without a version field don't meet version preconditions
+	err := updater.UpdateIf(ctx, map[string]interface{}{"Name": "updated"}, repository.IfVersion(1), filter)
+	suite.Assert().ErrorIs(err, repository.ErrConflict)
+
+	suite.Require().NoError(suite.repository.Update(ctx, map[string]interface{}{repository.FieldVersion: 1}, filter))
+
+	// Updating with the current version increments the version
+	suite.Require().NoError(updater.UpdateIf(ctx, map[string]interface{}{"Name": "updated"}, repository.IfVersion(1), filter))
+	docs, err := suite.fs.Collection("test").Where("Value", "==", 1).Documents(ctx).GetAll()
+	suite.Require().NoError(err)
+	suite.Require().Len(docs, 1)
+	suite.Assert().Equal(int64(2), docs[0].Data()[repository.FieldVersion])
+	suite.Assert().Equal("updated", docs[0].Data()["Name"])
+
+	// Updating with a stale version returns a conflict
+	err = updater.UpdateIf(ctx, map[string]interface{}{"Name": "stale"}, repository.IfVersion(1), filter)
+	suite.Assert().ErrorIs(err, repository.ErrConflict)
+
+	count, err := suite.repository.Count(ctx, repository.NewFilter(repository.Eq("Name", "stale")))
+	suite.Require().NoError(err)
+	suite.Assert().Zero(count)
+}
+
+func (suite *FirestoreRepositoryTestSuite) TestUpdateIf_UpdatedAt() {
+	suite.setupMockData()
+	ctx := context.Background()
+	updater := suite.repository.(repository.ConditionalUpdater)
+	filter := repository.NewFilter(repository.Eq("Value", 1))
+
+	var read Test
+	suite.Require().NoError(suite.repository.FindOne(ctx, &read, filter))
+
+	// Updating with the last update time succeeds
+	suite.Require().NoError(updater.UpdateIf(ctx, map[string]interface{}{"Name": "updated"}, repository.IfUpdatedAt(read.UpdatedAt), filter))
+
+	// The document was updated after it was read
+	err := updater.UpdateIf(ctx, map[string]interface{}{"Name": "stale"}, repository.IfUpdatedAt(read.UpdatedAt), filter)
+	suite.Assert().ErrorIs(err, repository.ErrConflict)
+
+	var found Test
+	suite.Require().NoError(suite.repository.FindOne(ctx, &found, filter))
+	suite.Assert().Equal("updated", found.Name)
+
+	// Invalid input
+	suite.Assert().ErrorIs(updater.UpdateIf(ctx, map[string]interface{}{"Name": "updated"}, repository.IfUpdatedAt(found.UpdatedAt)), repository.ErrNoFilter)
+	suite.Assert().ErrorIs(updater.UpdateIf(ctx, Test{}, repository.IfUpdatedAt(found.UpdatedAt), filter), ErrInvalidUpdateData)
 }
 
 func (suite *FirestoreRepositoryTestSuite) TestDelete() {
@@ -299,9 +540,97 @@ func (suite *FirestoreRepositoryTestSuite) TestDeleteBatch() {
 }
 
 func (suite *FirestoreRepositoryTestSuite) TestCount() {
-	_, err := suite.repository.Count(context.Background())
-	suite.Assert().Error(err)
-	suite.Assert().ErrorIs(err, errors.ErrMethodNotImplemented)
+	suite.setupMockData()
+
+	count, err := suite.repository.Count(context.Background())
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(3), count)
+
+	count, err = suite.repository.Count(context.Background(), repository.NewFilter(repository.Gt("Value", 1)))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(2), count)
+}
+
+func (suite *FirestoreRepositoryTestSuite) TestAggregations() {
+	suite.setupMockData()
+	ctx := context.Background()
+
+	aggregator, ok := suite.repository.(repository.Aggregator)
+	suite.Require().True(ok)
+
+	sum, err := aggregator.Sum(ctx, "Value")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(float64(6), sum)
+
+	avg, err := aggregator.Avg(ctx, "Value", repository.NewFilter(repository.Gt("Value", 1)))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(2.5, avg)
+
+	// No entries match
+	filter := repository.NewFilter(repository.Gt("Value", 10))
+	sum, err = aggregator.Sum(ctx, "Value", filter)
+	suite.Require().NoError(err)
+	suite.Assert().Zero(sum)
+	_, err = aggregator.Avg(ctx, "Value", filter)
+	suite.Assert().ErrorIs(err, repository.ErrNotFound)
+}
+
+func (suite *FirestoreRepositoryTestSuite) TestWithTransaction_Commit() {
+	suite.setupMockData()
+	tr := NewTransactioner(suite.fs)
+
+	err := tr.WithTransaction(context.Background(), func(ctx context.Context) error {
+		count, err := suite.repository.Count(ctx)
+		if err != nil {
+			return err
+		}
+		if err := suite.repository.Update(ctx, map[string]interface{}{"Value": int(count) + 1}, repository.NewFilter(repository.Eq("Value", 1))); err != nil {
+			return err
+		}
+		_, err = suite.repository.Create(ctx, &Test{Name: "test-5", Value: 5})
+		return err
+	})
+	suite.Require().NoError(err)
+
+	count, err := suite.repository.Count(context.Background(), repository.NewFilter(repository.In("Value", []int{4, 5})))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(2), count)
+}
+
+func (suite *FirestoreRepositoryTestSuite) TestWithTransaction_RollbackOnError() {
+	suite.setupMockData()
+	tr := NewTransactioner(suite.fs)
+
+	expected := errors.New("test error")
+	err := tr.WithTransaction(context.Background(), func(ctx context.Context) error {
+		if err := suite.repository.Delete(ctx, Where("Value", ">", 0)); err != nil {
+			return err
+		}
+		return expected
+	})
+	suite.Require().ErrorIs(err, expected)
+
+	count, err := suite.repository.Count(context.Background())
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(3), count)
+}
+
+func (suite *FirestoreRepositoryTestSuite) TestWithTransaction_RollbackOnPanic() {
+	suite.setupMockData()
+	tr := NewTransactioner(suite.fs)
+
+	suite.Require().Panics(func() {
+		_ = tr.WithTransaction(context.Background(), func(ctx context.Context) error {
+			if _, err := suite.repository.CreateBulk(ctx, []repository.Model{&Test{Name: "test-4", Value: 4}}); err != nil {
+				return err
+			}
+			panic("test panic")
+		})
+	})
+
+	count, err := suite.repository.Count(context.Background())
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(3), count)
 }
 
 func (suite *FirestoreRepositoryTestSuite) setupMockData() {
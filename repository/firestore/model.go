@@ -1,11 +1,18 @@
 package firestore
 
-import "time"
+import (
+	"reflect"
+	"strings"
+	"time"
+)
 
 // Model implements the repository.Model interface for firestore.
 // It provides a set of common generic fields and operations that partially implement the repository.Model interface.
 // To use it, embed it in your application-specific repository.Model implementation.
 type Model struct {
+	// ID contains the document identifier. It is not stored as a document field, the repository populates it when
+	// reading or creating documents. If set before creating a document, it will be used as the document ID.
+	ID string `firestore:"-"`
 	// CreatedAt contains the date and time at which this model has been persisted.
 	CreatedAt time.Time `firestore:"created_at"`
 	// UpdatedAt contains the last date and time when this model has been updated.
@@ -16,3 +23,77 @@ type Model struct {
 func (m Model) GetID() uint {
 	return 0
 }
+
+// GetDocumentID returns the document identifier for this Model.
+func (m Model) GetDocumentID() string {
+	return m.ID
+}
+
+// setDocumentID sets the document identifier for this Model.
+func (m *Model) setDocumentID(id string) {
+	m.ID = id
+}
+
+// setTimestamps sets the creation and update timestamps of a Model that is about to be created.
+// CreatedAt is only set if it doesn't contain a value.
+func (m *Model) setTimestamps(t time.Time) {
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = t
+	}
+	m.UpdatedAt = t
+}
+
+// setCreatedAt sets the creation timestamp of a Model.
+func (m *Model) setCreatedAt(t time.Time) {
+	m.CreatedAt = t
+}
+
+// document is implemented by pointers to types embedding Model. It allows the repository to populate document metadata.
+type document interface {
+	GetDocumentID() string
+	setDocumentID(id string)
+	setTimestamps(t time.Time)
+	setCreatedAt(t time.Time)
+}
+
+// documentFields returns the top-level fields of a struct, or a pointer to a struct, as they are encoded by the
+// firestore client. Fields are named after their firestore tag, or their Go name if they don't have one. Fields of
+// embedded structs are promoted to the top level.
+func documentFields(entity interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	appendDocumentFields(out, reflect.ValueOf(entity))
+	return out
+}
+
+// appendDocumentFields adds the top-level fields of the struct contained in v to out.
+func appendDocumentFields(out map[string]interface{}, v reflect.Value) {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("firestore"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			embedded := v.Field(i)
+			if embedded.Kind() == reflect.Ptr && embedded.IsNil() {
+				continue
+			}
+			if reflect.Indirect(embedded).Kind() == reflect.Struct {
+				appendDocumentFields(out, embedded)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		out[name] = v.Field(i).Interface()
+	}
+}
@@ -0,0 +1,27 @@
+package firestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentFields(t *testing.T) {
+	now := time.Now()
+	type tagged struct {
+		Model
+		Name    string `firestore:"name,omitempty"`
+		Ignored string `firestore:"-"`
+		Value   int
+		private int
+	}
+	expected := map[string]interface{}{
+		"created_at": now,
+		"updated_at": time.Time{},
+		"name":       "test",
+		"Value":      1,
+	}
+	assert.Equal(t, expected, documentFields(&tagged{Model: Model{ID: "id", CreatedAt: now}, Name: "test", Ignored: "a", Value: 1, private: 2}))
+	assert.Empty(t, documentFields(1))
+}
@@ -75,6 +75,30 @@ func Where(field string, op string, value interface{}) repository.Option {
 	})
 }
 
+// WhereExpression filters results based on a backend-agnostic repository.Expression.
+// Multiple WhereExpression options can be passed to a single Repository operation. They are logically ANDed together.
+// Operations receiving an expression that cannot be translated into a Firestore filter return
+// repository.ErrUnsupportedExpression.
+//
+//	Repository.Find(&list, WhereExpression(repository.Or(repository.Eq("Name", "Andrew"), repository.Lt("Value", 2))))
+func WhereExpression(expr repository.Expression) repository.Option {
+	f, err := newEntityFilter(expr)
+	if err != nil {
+		return invalidOption{err: err}
+	}
+	return Option(func(q *firestore.Query) {
+		*q = q.WhereEntity(f)
+	})
+}
+
+// invalidOption is a repository.Option that cannot be applied to a Firestore query.
+// Operations receiving an invalidOption return the error it holds.
+type invalidOption struct {
+	err error
+}
+
+func (o invalidOption) IsOption() {}
+
 // StartAfter initializes a new option that specifies that results should start right after
 // the document with the given field values.
 //
@@ -3,6 +3,7 @@ package firestore
 import (
 	"testing"
 
+	"cloud.google.com/go/firestore"
 	"github.com/gazebo-web/gz-go/v10/repository"
 	"github.com/stretchr/testify/assert"
 )
@@ -62,3 +63,12 @@ func (req *TestPaginationRequest) GetPageSize() int32 {
 func (req *TestPaginationRequest) GetPageToken() string {
 	return req.token
 }
+
+func TestWhereExpression_Unsupported(t *testing.T) {
+	var r firestoreRepository[*Test]
+	var q firestore.Query
+
+	assert.NoError(t, r.applyOptions(&q, WhereExpression(repository.Eq("Name", "test"))))
+	assert.ErrorIs(t, r.applyOptions(&q, WhereExpression(repository.Not(repository.Contains("Tags", "a")))), repository.ErrUnsupportedExpression)
+	assert.ErrorIs(t, r.applyOptions(&q, WhereExpression(nil)), repository.ErrUnsupportedExpression)
+}
@@ -0,0 +1,69 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/firestore"
+	"github.com/gazebo-web/gz-go/v10/repository"
+)
+
+// errTransactionPanic is returned to the firestore client when a transaction function panics in order to roll back
+// the transaction.
+var errTransactionPanic = errors.New("transaction function panicked")
+
+// transactionKey is the context key used to store a Firestore transaction.
+type transactionKey struct{}
+
+// NewTransactioner initializes a new repository.Transactioner implementation for Firestore.
+// Repositories created with NewFirestoreRepository use the transaction carried by the context passed to their
+// operations.
+//
+// Firestore transactions have the following constraints:
+//   - All reads must be performed before any writes.
+//   - Nested transactions are not supported, nested calls to WithTransaction run in the outer transaction.
+//   - The transaction function may be run more than once if the transaction is aborted due to contention.
+func NewTransactioner(client *firestore.Client) repository.Transactioner {
+	return &transactionerFirestore{
+		client: client,
+	}
+}
+
+// transactionerFirestore implements repository.Transactioner using Firestore transactions.
+type transactionerFirestore struct {
+	client *firestore.Client
+}
+
+// WithTransaction runs fn inside a transaction.
+func (t *transactionerFirestore) WithTransaction(ctx context.Context, fn repository.TransactionFunc) error {
+	if transactionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	var panicked any
+	// txCtx contains the context of the last attempt, the only one that can be committed.
+	var txCtx context.Context
+	err := t.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				panicked = p
+				err = errTransactionPanic
+			}
+		}()
+		txCtx = repository.ContextWithTransaction(context.WithValue(ctx, transactionKey{}, tx))
+		return fn(txCtx)
+	})
+	if panicked != nil {
+		panic(panicked)
+	}
+	if err != nil {
+		return err
+	}
+	repository.Committed(txCtx)
+	return nil
+}
+
+// transactionFromContext returns the transaction stored in ctx. It returns nil if ctx doesn't contain a transaction.
+func transactionFromContext(ctx context.Context) *firestore.Transaction {
+	tx, _ := ctx.Value(transactionKey{}).(*firestore.Transaction)
+	return tx
+}
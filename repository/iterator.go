@@ -0,0 +1,16 @@
+package repository
+
+import "context"
+
+// IterateFunc is called by Iterator.Iterate with every entry found.
+type IterateFunc func(entry Model) error
+
+// Iterator is implemented by Repository implementations that can stream the results of a query.
+type Iterator interface {
+	// Iterate calls fn with every entry found using the given options, one entry at a time. Unlike Find, entries are
+	// read from the persistence layer as they are iterated, and not loaded in memory all at once.
+	// Iteration stops at the first error returned by fn, and Iterate returns that error.
+	// options: configuration options for the search. Refer to the implementation's set of options to get a list of
+	// options.
+	Iterate(ctx context.Context, fn IterateFunc, options ...Option) error
+}
@@ -0,0 +1,182 @@
+package memory
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+)
+
+// match returns true if the entry contained in v matches expr. A nil expression matches every entry.
+// Comparisons follow SQL semantics: null values only match null checks.
+func (s *modelSchema) match(v reflect.Value, expr repository.Expression) (bool, error) {
+	switch e := expr.(type) {
+	case nil:
+		return true, nil
+	case repository.Comparison:
+		return s.matchComparison(v, e)
+	case repository.Conjunction:
+		for _, sub := range e {
+			ok, err := s.match(v, sub)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case repository.Disjunction:
+		for _, sub := range e {
+			ok, err := s.match(v, sub)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case repository.Negation:
+		ok, err := s.match(v, e.Expression)
+		return !ok, err
+	default:
+		return false, fmt.Errorf("%w: %T", repository.ErrUnsupportedExpression, expr)
+	}
+}
+
+// matchComparison returns true if the entry contained in v matches the given comparison.
+func (s *modelSchema) matchComparison(v reflect.Value, c repository.Comparison) (bool, error) {
+	f, ok := s.field(v, c.Field)
+	if !ok {
+		return false, fmt.Errorf("%w: unknown field %s", repository.ErrUnsupportedExpression, c.Field)
+	}
+	value, err := fieldValue(f)
+	if err != nil {
+		return false, err
+	}
+
+	switch c.Operator {
+	case repository.OperatorIsNull:
+		return value == nil, nil
+	case repository.OperatorIsNotNull:
+		return value != nil, nil
+	case repository.OperatorEqual:
+		if c.Value == nil {
+			return value == nil, nil
+		}
+		return value != nil && equal(value, c.Value), nil
+	case repository.OperatorNotEqual:
+		if c.Value == nil {
+			return value != nil, nil
+		}
+		return value != nil && !equal(value, c.Value), nil
+	case repository.OperatorLessThan, repository.OperatorLessThanOrEqual,
+		repository.OperatorGreaterThan, repository.OperatorGreaterThanOrEqual:
+		if value == nil || c.Value == nil {
+			return false, nil
+		}
+		cmp, err := compare(value, c.Value)
+		if err != nil {
+			return false, err
+		}
+		switch c.Operator {
+		case repository.OperatorLessThan:
+			return cmp < 0, nil
+		case repository.OperatorLessThanOrEqual:
+			return cmp <= 0, nil
+		case repository.OperatorGreaterThan:
+			return cmp > 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+	case repository.OperatorIn, repository.OperatorNotIn:
+		values := reflect.ValueOf(c.Value)
+		if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+			return false, fmt.Errorf("%w: expected a slice of values, got %T", repository.ErrUnsupportedExpression, c.Value)
+		}
+		if value == nil {
+			return false, nil
+		}
+		found := false
+		for i := 0; i < values.Len() && !found; i++ {
+			found = equal(value, values.Index(i).Interface())
+		}
+		return found == (c.Operator == repository.OperatorIn), nil
+	case repository.OperatorContains:
+		return contains(value, c.Value), nil
+	default:
+		return false, fmt.Errorf("%w: unknown operator %s", repository.ErrUnsupportedExpression, c.Operator)
+	}
+}
+
+// contains returns true if value is a string that contains element as a substring, or a slice that contains element.
+func contains(value interface{}, element interface{}) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		e := reflect.ValueOf(element)
+		return e.Kind() == reflect.String && strings.Contains(v.String(), e.String())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if equal(v.Index(i).Interface(), element) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// equal returns true if a and b are equal. Numbers of different types are compared by value.
+func equal(a, b interface{}) bool {
+	if cmp, err := compare(a, b); err == nil {
+		return cmp == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compare returns -1 if a is less than b, 0 if they are equal and 1 if a is greater than b.
+// It supports numbers, strings, booleans and time.Time values. Numbers of different types are compared by value.
+func compare(a, b interface{}) (int, error) {
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			return x.Cmp(y), nil
+		}
+	}
+	if x, ok := a.(time.Time); ok {
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y), nil
+		}
+	}
+	x, y := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case x.Kind() == reflect.String && y.Kind() == reflect.String:
+		return strings.Compare(x.String(), y.String()), nil
+	case x.Kind() == reflect.Bool && y.Kind() == reflect.Bool:
+		switch {
+		case x.Bool() == y.Bool():
+			return 0, nil
+		case y.Bool():
+			return -1, nil
+		default:
+			return 1, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: cannot compare %T with %T", repository.ErrUnsupportedExpression, a, b)
+}
+
+// toNumber converts integer and floating point values into a big.Float. It returns false if v is not a number.
+func toNumber(v interface{}) (*big.Float, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Float).SetInt64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Float).SetUint64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		if math.IsNaN(rv.Float()) {
+			return nil, false
+		}
+		return new(big.Float).SetFloat64(rv.Float()), true
+	default:
+		return nil, false
+	}
+}
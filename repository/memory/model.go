@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// fieldID is the name of the field used to identify entries.
+	fieldID = "ID"
+	// fieldCreatedAt is the name of the field that contains the creation timestamp of an entry.
+	fieldCreatedAt = "CreatedAt"
+	// fieldUpdatedAt is the name of the field that contains the last update timestamp of an entry.
+	fieldUpdatedAt = "UpdatedAt"
+)
+
+// modelSchema contains the fields of a model type.
+//
+// Fields can be referenced by their Go name, their column name as generated by Gorm, and the names defined in their
+// json, firestore and gorm column tags. This allows using the same field names used with other repository
+// implementations.
+type modelSchema struct {
+	typ    reflect.Type
+	fields map[string][]int
+	// names contains the Go name of every field, in declaration order.
+	names []string
+}
+
+// newModelSchema returns the modelSchema of the given struct type.
+func newModelSchema(t reflect.Type) *modelSchema {
+	s := &modelSchema{
+		typ:    t,
+		fields: make(map[string][]int),
+	}
+	naming := schema.NamingStrategy{}
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		if _, exists := s.fields[f.Name]; exists {
+			continue
+		}
+		s.names = append(s.names, f.Name)
+		names := []string{
+			f.Name,
+			naming.ColumnName("", f.Name),
+			tagName(f.Tag.Get("json")),
+			tagName(f.Tag.Get("firestore")),
+			gormColumn(f.Tag.Get("gorm")),
+		}
+		for _, name := range names {
+			if _, exists := s.fields[name]; name == "" || name == "-" || exists {
+				continue
+			}
+			s.fields[name] = f.Index
+		}
+	}
+	return s
+}
+
+// field returns the field of v with the given name.
+// It returns false if the field doesn't exist or cannot be reached because of a nil embedded pointer.
+func (s *modelSchema) field(v reflect.Value, name string) (reflect.Value, bool) {
+	index, ok := s.fields[name]
+	if !ok {
+		return reflect.Value{}, false
+	}
+	f, err := v.FieldByIndexErr(index)
+	if err != nil {
+		return reflect.Value{}, false
+	}
+	return f, true
+}
+
+// id returns the identifier of the entry contained in v. It returns 0 if the model doesn't have an ID field.
+func (s *modelSchema) id(v reflect.Value) uint64 {
+	f, ok := s.field(v, fieldID)
+	if !ok || !isUint(f.Kind()) {
+		return 0
+	}
+	return f.Uint()
+}
+
+// setID sets the identifier of the entry contained in v. It does nothing if the model doesn't have an ID field.
+func (s *modelSchema) setID(v reflect.Value, id uint64) {
+	if f, ok := s.field(v, fieldID); ok && isUint(f.Kind()) {
+		f.SetUint(id)
+	}
+}
+
+// setTime sets the time field with the given name. It does nothing if the model doesn't have such field.
+func (s *modelSchema) setTime(v reflect.Value, name string, t time.Time) {
+	if f, ok := s.field(v, name); ok && f.Type() == reflect.TypeOf(t) {
+		f.Set(reflect.ValueOf(t))
+	}
+}
+
+// getTime returns the value of the time field with the given name.
+func (s *modelSchema) getTime(v reflect.Value, name string) (time.Time, bool) {
+	f, ok := s.field(v, name)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, ok := f.Interface().(time.Time)
+	return t, ok
+}
+
+// tagName returns the name defined in a json or firestore struct tag.
+func tagName(tag string) string {
+	name, _, _ := strings.Cut(tag, ",")
+	return name
+}
+
+// gormColumn returns the column name defined in a gorm struct tag.
+func gormColumn(tag string) string {
+	for _, setting := range strings.Split(tag, ";") {
+		key, value, found := strings.Cut(setting, ":")
+		if found && strings.EqualFold(strings.TrimSpace(key), "column") {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// isUint returns true if k is an unsigned integer kind.
+func isUint(k reflect.Kind) bool {
+	switch k {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// fieldValue returns the value of a field as it would be stored by a database: nil pointers are returned as nil,
+// non-nil pointers are dereferenced and driver.Valuer implementations are converted into their database value.
+func fieldValue(f reflect.Value) (interface{}, error) {
+	for f.Kind() == reflect.Ptr || f.Kind() == reflect.Interface {
+		if f.IsNil() {
+			return nil, nil
+		}
+		if valuer, ok := f.Interface().(driver.Valuer); ok {
+			return valuer.Value()
+		}
+		f = f.Elem()
+	}
+	if valuer, ok := f.Interface().(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	return f.Interface(), nil
+}
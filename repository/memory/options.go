@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+)
+
+// Option is an in-memory specific repository.Option implementation.
+// It is used to configure in-memory repository operations.
+type Option func(q *query)
+
+func (Option) IsOption() {}
+
+// query contains the configuration of a repository operation.
+type query struct {
+	filters []repository.Expression
+	orders  []OrderByField
+	limit   int
+	offset  int
+}
+
+// newQuery applies the given options to a new query.
+// It returns an error if any of the options is not an in-memory Option.
+func newQuery(opts []repository.Option) (*query, error) {
+	q := &query{limit: -1}
+	for _, opt := range opts {
+		o, ok := opt.(Option)
+		if !ok {
+			return nil, fmt.Errorf("unsupported option %T", opt)
+		}
+		o(q)
+	}
+	return q, nil
+}
+
+// Where filters results based on a repository.Expression.
+// Multiple Where options can be passed to a single Repository operation. They are logically ANDed together.
+func Where(expr repository.Expression) repository.Option {
+	return Option(func(q *query) {
+		q.filters = append(q.filters, expr)
+	})
+}
+
+// MaxResults defines the maximum number of results for an operation that can return multiple results.
+// Passing this Option to a Repository operation overwrites any previous MaxResults options passed.
+func MaxResults(n int) repository.Option {
+	return Option(func(q *query) {
+		q.limit = n
+	})
+}
+
+// Offset defines a number of results to skip before starting to capture values to return.
+// Passing this Option to a Repository operation overwrites any previous Offset options passed.
+func Offset(offset int) repository.Option {
+	return Option(func(q *query) {
+		q.offset = offset
+	})
+}
+
+// OrderByField contains order by information for a field.
+type OrderByField struct {
+	field      string
+	descending bool
+}
+
+// Ascending sorts the passed field in ascending order.
+func Ascending(field string) OrderByField {
+	return OrderByField{field: field}
+}
+
+// Descending sorts the passed field in descending order.
+func Descending(field string) OrderByField {
+	return OrderByField{field: field, descending: true}
+}
+
+// OrderBy sorts results based on fields.
+// Use the Ascending and Descending functions to pass orders to this Option.
+// In situations with multiple orders, they are applied in sequence.
+// Multiple OrderBy options can be passed to a single Repository operation. They are appended to any previous orders.
+func OrderBy(orders ...OrderByField) repository.Option {
+	return Option(func(q *query) {
+		q.orders = append(q.orders, orders...)
+	})
+}
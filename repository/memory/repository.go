@@ -0,0 +1,764 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+)
+
+var (
+	// ErrDuplicateEntry is returned when creating an entry with an ID that is already in use.
+	ErrDuplicateEntry = errors.New("an entry with the same ID already exists")
+	// ErrInvalidUpdateData is returned when an update receives data that cannot be applied to the repository model.
+	ErrInvalidUpdateData = errors.New("invalid update data")
+)
+
+// NewRepository initializes a new repository.Repository implementation that stores entries in memory.
+// It is safe for concurrent use, and it is intended to replace database-backed repositories in unit tests.
+//
+// entity must be a pointer to a struct implementing repository.Model. Entries are identified by an unsigned integer ID
+// field, which is populated when entries are created. CreatedAt and UpdatedAt time.Time fields are maintained as well.
+// Models embedding sql.Model contain all of these fields.
+//
+// Filters must contain a repository.Expression, filter templates are not supported. Fields can be referenced by their
+// Go name, their Gorm column name or the name defined in their json or firestore tags.
+func NewRepository(entity repository.Model) repository.Repository {
+	return &repositoryMemory{
+		entity: entity,
+		schema: newModelSchema(reflect.Indirect(reflect.ValueOf(entity)).Type()),
+	}
+}
+
+// repositoryMemory implements repository.Repository storing entries in a slice.
+type repositoryMemory struct {
+	mu     sync.RWMutex
+	entity repository.Model
+	schema *modelSchema
+	// entries contains the stored entries sorted by ID.
+	entries []reflect.Value
+	lastID  uint64
+}
+
+// Ensure that repositoryMemory implements the repository.Repository interface.
+var _ repository.Repository = (*repositoryMemory)(nil)
+
+// Ensure that repositoryMemory implements the repository.ConditionalUpdater interface.
+var _ repository.ConditionalUpdater = (*repositoryMemory)(nil)
+
+// Ensure that repositoryMemory implements the repository.Iterator interface.
+var _ repository.Iterator = (*repositoryMemory)(nil)
+
+// Ensure that repositoryMemory implements the repository.FirstOrCreator interface.
+var _ repository.FirstOrCreator = (*repositoryMemory)(nil)
+
+// FirstOrCreate inserts a new entry if the given filters don't find any existing record.
+//
+//	entity: must be a pointer to a repository.Model implementation. Results will be saved in this argument if the record exists.
+func (r *repositoryMemory) FirstOrCreate(ctx context.Context, entity repository.Model, filters ...repository.Filter) error {
+	_, err := r.FirstOrCreateAndReport(ctx, entity, filters...)
+	return err
+}
+
+// FirstOrCreateAndReport inserts a new entry if the given filters don't find any existing record, and returns true if
+// the entry was created.
+//
+//	entity: must be a pointer to a repository.Model implementation. Results will be saved in this argument if the record exists.
+func (r *repositoryMemory) FirstOrCreateAndReport(ctx context.Context, entity repository.Model, filters ...repository.Filter) (bool, error) {
+	exprs, err := filterExpressions(filters)
+	if err != nil {
+		return false, err
+	}
+	v, err := r.copyEntity(entity)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	matches, err := r.filter(exprs)
+	if err != nil {
+		return false, err
+	}
+	if len(matches) > 0 {
+		writeBack(entity, clone(r.entries[matches[0]]))
+		return false, nil
+	}
+	if err := r.checkDuplicates([]reflect.Value{v}); err != nil {
+		return false, err
+	}
+	r.insert(v, time.Now())
+	writeBack(entity, v)
+	return true, nil
+}
+
+// Create inserts a single entry.
+//
+//	entity: The entry to insert.
+func (r *repositoryMemory) Create(ctx context.Context, entity repository.Model) (repository.Model, error) {
+	result, err := r.CreateBulk(ctx, []repository.Model{entity})
+	if err != nil {
+		return nil, err
+	}
+	return result[0], nil
+}
+
+// CreateBulk creates multiple entries with a single operation. If any of the entries cannot be created, no entries
+// are created.
+//
+//	entities: should be a slice of pointers to the same data structure implementing repository.Model.
+func (r *repositoryMemory) CreateBulk(ctx context.Context, entities []repository.Model) ([]repository.Model, error) {
+	values := make([]reflect.Value, len(entities))
+	for i, entity := range entities {
+		v, err := r.copyEntity(entity)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkDuplicates(values); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i, v := range values {
+		r.insert(v, now)
+		writeBack(entities[i], v)
+	}
+	return entities, nil
+}
+
+// Upsert inserts a single entry, or replaces the existing entry that conflicts with it.
+// The ID and creation timestamp of existing entries are preserved.
+//
+//	entity: must be a pointer to a repository.Model implementation.
+//	conflictFields: fields that identify the existing entry. If empty, the entry ID is used.
+func (r *repositoryMemory) Upsert(ctx context.Context, entity repository.Model, conflictFields ...string) error {
+	v, err := r.copyEntity(entity)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, err := r.findConflict(v, conflictFields)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if i < 0 {
+		r.insert(v, now)
+		writeBack(entity, v)
+		return nil
+	}
+	existing := r.entries[i]
+	r.schema.setID(v, r.schema.id(existing))
+	if createdAt, ok := r.schema.getTime(existing, fieldCreatedAt); ok {
+		r.schema.setTime(v, fieldCreatedAt, createdAt)
+	}
+	r.schema.setTime(v, fieldUpdatedAt, now)
+	r.entries[i] = clone(v)
+	writeBack(entity, v)
+	return nil
+}
+
+// Find filters entries and stores filtered entries in output.
+//
+//	output: will contain the result of the query. It must be a pointer to a slice of the model type, or of pointers
+//	  to the model type.
+//	options: configuration options for the search.
+func (r *repositoryMemory) Find(ctx context.Context, output interface{}, options ...repository.Option) error {
+	q, err := newQuery(options)
+	if err != nil {
+		return err
+	}
+	out := reflect.ValueOf(output)
+	if out.Kind() != reflect.Ptr || out.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("output must be a pointer to a slice, got %T", output)
+	}
+	elemType := out.Elem().Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if (isPtr && elemType.Elem() != r.schema.typ) || (!isPtr && elemType != r.schema.typ) {
+		return fmt.Errorf("%w: expected a slice of %s, got %T", repository.ErrInvalidModelType, r.schema.typ, output)
+	}
+
+	r.mu.RLock()
+	matches, err := r.filter(q.filters)
+	results := make([]reflect.Value, len(matches))
+	for i, m := range matches {
+		results[i] = clone(r.entries[m])
+	}
+	r.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err := r.sort(results, q.orders); err != nil {
+		return err
+	}
+	if q.offset > 0 {
+		if q.offset >= len(results) {
+			results = nil
+		} else {
+			results = results[q.offset:]
+		}
+	}
+	if q.limit >= 0 && q.limit < len(results) {
+		results = results[:q.limit]
+	}
+
+	slice := reflect.MakeSlice(out.Elem().Type(), 0, len(results))
+	for _, v := range results {
+		if isPtr {
+			v = v.Addr()
+		}
+		slice = reflect.Append(slice, v)
+	}
+	out.Elem().Set(slice)
+	return nil
+}
+
+// Iterate calls fn with every entry found using the given options. Each entry passed to fn is a pointer to a copy of
+// the stored entry, fn can run other operations in the repository.
+func (r *repositoryMemory) Iterate(ctx context.Context, fn repository.IterateFunc, options ...repository.Option) error {
+	out := reflect.New(reflect.SliceOf(reflect.PointerTo(r.schema.typ)))
+	if err := r.Find(ctx, out.Interface(), options...); err != nil {
+		return err
+	}
+	entries := out.Elem()
+	for i := 0; i < entries.Len(); i++ {
+		entry, ok := entries.Index(i).Interface().(repository.Model)
+		if !ok {
+			return repository.ErrInvalidModelType
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindOne filters entries and stores the first filtered entry in output. It returns repository.ErrNotFound if no
+// entries match the filters.
+//
+//	output: must be a pointer to a repository.Model implementation.
+func (r *repositoryMemory) FindOne(ctx context.Context, output repository.Model, filters ...repository.Filter) error {
+	return r.findEdge(output, filters, false)
+}
+
+// Last gets the last entry ordered by ID that matches filters. It returns repository.ErrNotFound if no entries match
+// the filters.
+//
+//	output: must be a pointer to a repository.Model implementation.
+func (r *repositoryMemory) Last(ctx context.Context, output repository.Model, filters ...repository.Filter) error {
+	return r.findEdge(output, filters, true)
+}
+
+// Update updates all the entries that match filters with the given data.
+//
+//	data: must be a map[string]interface{} or a value of the model type. Only the non-zero fields of model values
+//	  are updated.
+//	filters: selection criteria for entries that should be updated. At least one filter must be provided.
+func (r *repositoryMemory) Update(ctx context.Context, data interface{}, filters ...repository.Filter) error {
+	return r.update(data, nil, filters)
+}
+
+// UpdateIf updates all the entries that match filters with data, only if every one of them meets precondition.
+// If any of the entries does not meet the precondition, no entries are updated and repository.ErrConflict is returned.
+func (r *repositoryMemory) UpdateIf(ctx context.Context, data interface{}, precondition repository.Precondition, filters ...repository.Filter) error {
+	switch precondition.(type) {
+	case repository.VersionPrecondition, repository.UpdatedAtPrecondition:
+	default:
+		return fmt.Errorf("unsupported precondition %T", precondition)
+	}
+	return r.update(data, precondition, filters)
+}
+
+// Delete removes all the entries that match the Where options.
+//
+//	options: configuration options for the removal. If no Where options are provided, all the entries are removed.
+func (r *repositoryMemory) Delete(ctx context.Context, opts ...repository.Option) error {
+	q, err := newQuery(opts)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	matches, err := r.filter(q.filters)
+	if err != nil {
+		return err
+	}
+	removed := make(map[int]bool, len(matches))
+	for _, m := range matches {
+		removed[m] = true
+	}
+	entries := make([]reflect.Value, 0, len(r.entries)-len(matches))
+	for i, v := range r.entries {
+		if !removed[i] {
+			entries = append(entries, v)
+		}
+	}
+	r.entries = entries
+	return nil
+}
+
+// Count counts all the entries that match filters.
+func (r *repositoryMemory) Count(ctx context.Context, filters ...repository.Filter) (uint64, error) {
+	exprs, err := filterExpressions(filters)
+	if err != nil {
+		return 0, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	matches, err := r.filter(exprs)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(matches)), nil
+}
+
+// Model returns this repository's model.
+func (r *repositoryMemory) Model() repository.Model {
+	v := reflect.New(r.schema.typ)
+	if reflect.ValueOf(r.entity).Kind() != reflect.Ptr {
+		v = v.Elem()
+	}
+	return v.Interface().(repository.Model)
+}
+
+// findEdge stores the first or last entry that matches filters in output.
+func (r *repositoryMemory) findEdge(output repository.Model, filters []repository.Filter, last bool) error {
+	exprs, err := filterExpressions(filters)
+	if err != nil {
+		return err
+	}
+	out := reflect.ValueOf(output)
+	if out.Kind() != reflect.Ptr || out.Elem().Type() != r.schema.typ {
+		return fmt.Errorf("%w: expected a pointer to %s, got %T", repository.ErrInvalidModelType, r.schema.typ, output)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	matches, err := r.filter(exprs)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return repository.ErrNotFound
+	}
+	i := matches[0]
+	if last {
+		i = matches[len(matches)-1]
+	}
+	out.Elem().Set(clone(r.entries[i]))
+	return nil
+}
+
+// update applies data to all the entries that match filters. If precondition is not nil, every entry must meet it.
+func (r *repositoryMemory) update(data interface{}, precondition repository.Precondition, filters []repository.Filter) error {
+	if len(filters) == 0 {
+		return repository.ErrNoFilter
+	}
+	exprs, err := filterExpressions(filters)
+	if err != nil {
+		return err
+	}
+	assignments, err := r.newAssignments(data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	matches, err := r.filter(exprs)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	updated := make([]reflect.Value, len(matches))
+	for i, m := range matches {
+		v := clone(r.entries[m])
+		if precondition != nil {
+			if err := r.checkPrecondition(v, precondition); err != nil {
+				return err
+			}
+		}
+		if err := r.apply(v, assignments, now); err != nil {
+			return err
+		}
+		updated[i] = v
+	}
+	for i, m := range matches {
+		r.entries[m] = updated[i]
+	}
+	return nil
+}
+
+// assignment contains the new value of a field.
+type assignment struct {
+	name  string
+	index []int
+	value reflect.Value
+}
+
+// newAssignments converts update data into a list of field assignments.
+func (r *repositoryMemory) newAssignments(data interface{}) ([]assignment, error) {
+	if values, ok := data.(map[string]interface{}); ok {
+		out := make([]assignment, 0, len(values))
+		for name, value := range values {
+			index, ok := r.schema.fields[name]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown field %s", ErrInvalidUpdateData, name)
+			}
+			field := r.schema.typ.FieldByIndex(index)
+			v, err := convert(value, field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%w: field %s: %s", ErrInvalidUpdateData, name, err)
+			}
+			out = append(out, assignment{name: field.Name, index: index, value: v})
+		}
+		return out, nil
+	}
+
+	v, err := r.copyEntity(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidUpdateData, err)
+	}
+	var out []assignment
+	for _, name := range r.schema.names {
+		f, ok := r.schema.field(v, name)
+		if name == fieldID || !ok || f.IsZero() {
+			continue
+		}
+		out = append(out, assignment{name: name, index: r.schema.fields[name], value: f})
+	}
+	return out, nil
+}
+
+// apply sets the given assignments on v, and updates its update timestamp unless it is explicitly assigned.
+func (r *repositoryMemory) apply(v reflect.Value, assignments []assignment, now time.Time) error {
+	touched := false
+	for _, a := range assignments {
+		f, err := v.FieldByIndexErr(a.index)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidUpdateData, err)
+		}
+		f.Set(a.value)
+		touched = touched || a.name == fieldUpdatedAt
+	}
+	if !touched {
+		r.schema.setTime(v, fieldUpdatedAt, now)
+	}
+	return nil
+}
+
+// checkPrecondition returns repository.ErrConflict if v doesn't meet precondition. Entries updated with a
+// repository.VersionPrecondition get their version incremented.
+func (r *repositoryMemory) checkPrecondition(v reflect.Value, precondition repository.Precondition) error {
+	switch p := precondition.(type) {
+	case repository.VersionPrecondition:
+		f, ok := r.schema.field(v, p.Field)
+		if !ok {
+			return fmt.Errorf("%w: %s does not contain a %s field", ErrInvalidUpdateData, r.schema.typ, p.Field)
+		}
+		if !equal(f.Interface(), p.Version) {
+			return repository.ErrConflict
+		}
+		switch {
+		case isUint(f.Kind()):
+			f.SetUint(p.Version + 1)
+		case f.CanInt():
+			f.SetInt(int64(p.Version) + 1)
+		default:
+			return fmt.Errorf("%w: the %s field is not an integer", ErrInvalidUpdateData, p.Field)
+		}
+	case repository.UpdatedAtPrecondition:
+		updatedAt, ok := r.schema.getTime(v, fieldUpdatedAt)
+		if !ok || !updatedAt.Equal(p.UpdatedAt) {
+			return repository.ErrConflict
+		}
+	}
+	return nil
+}
+
+// copyEntity returns an addressable copy of the model value contained in entity.
+func (r *repositoryMemory) copyEntity(entity interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(entity)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Type() != r.schema.typ {
+		return reflect.Value{}, fmt.Errorf("%w: expected %s, got %T", repository.ErrInvalidModelType, r.schema.typ, entity)
+	}
+	return clone(v), nil
+}
+
+// checkDuplicates returns ErrDuplicateEntry if any of the given values has an ID that is already in use.
+// r.mu must be locked.
+func (r *repositoryMemory) checkDuplicates(values []reflect.Value) error {
+	seen := make(map[uint64]bool, len(values))
+	for _, v := range values {
+		id := r.schema.id(v)
+		if id == 0 {
+			continue
+		}
+		if seen[id] || r.indexOf(id) >= 0 {
+			return fmt.Errorf("%w: %d", ErrDuplicateEntry, id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// insert stores v, populating its ID and timestamps. r.mu must be locked.
+func (r *repositoryMemory) insert(v reflect.Value, now time.Time) {
+	id := r.schema.id(v)
+	if id == 0 {
+		r.lastID++
+		r.schema.setID(v, r.lastID)
+	} else if id > r.lastID {
+		r.lastID = id
+	}
+	if createdAt, ok := r.schema.getTime(v, fieldCreatedAt); ok && createdAt.IsZero() {
+		r.schema.setTime(v, fieldCreatedAt, now)
+	}
+	r.schema.setTime(v, fieldUpdatedAt, now)
+
+	// Entries are kept sorted by ID, new entries usually have the greatest ID and are appended at the end.
+	i := r.search(r.schema.id(v))
+	r.entries = append(r.entries, reflect.Value{})
+	copy(r.entries[i+1:], r.entries[i:])
+	r.entries[i] = clone(v)
+}
+
+// search returns the index of the first entry with an ID greater than or equal to id. r.mu must be locked.
+func (r *repositoryMemory) search(id uint64) int {
+	return sort.Search(len(r.entries), func(i int) bool {
+		return r.schema.id(r.entries[i]) >= id
+	})
+}
+
+// indexOf returns the index of the entry with the given ID, or -1 if it doesn't exist. r.mu must be locked.
+func (r *repositoryMemory) indexOf(id uint64) int {
+	if i := r.search(id); i < len(r.entries) && r.schema.id(r.entries[i]) == id {
+		return i
+	}
+	return -1
+}
+
+// findConflict returns the index of the entry that has the same conflictFields values as v, or -1 if there is none.
+// If conflictFields is empty, entries are matched by ID. r.mu must be locked.
+func (r *repositoryMemory) findConflict(v reflect.Value, conflictFields []string) (int, error) {
+	if len(conflictFields) == 0 {
+		id := r.schema.id(v)
+		if id == 0 {
+			return -1, nil
+		}
+		return r.indexOf(id), nil
+	}
+	exprs := make([]repository.Expression, len(conflictFields))
+	for i, name := range conflictFields {
+		f, ok := r.schema.field(v, name)
+		if !ok {
+			return -1, fmt.Errorf("unknown conflict field %s", name)
+		}
+		value, err := fieldValue(f)
+		if err != nil {
+			return -1, err
+		}
+		exprs[i] = repository.Eq(name, value)
+	}
+	matches, err := r.filter(exprs)
+	if err != nil || len(matches) == 0 {
+		return -1, err
+	}
+	return matches[0], nil
+}
+
+// filter returns the indexes of the entries that match all the given expressions. r.mu must be locked.
+func (r *repositoryMemory) filter(exprs []repository.Expression) ([]int, error) {
+	expr := repository.And(exprs...)
+	var out []int
+	for i, v := range r.entries {
+		ok, err := r.schema.match(v, expr)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
+
+// sort sorts values using the given orders. Null values are sorted before any other value.
+func (r *repositoryMemory) sort(values []reflect.Value, orders []OrderByField) error {
+	var err error
+	sort.SliceStable(values, func(i, j int) bool {
+		for _, order := range orders {
+			cmp, cmpErr := r.compareField(values[i], values[j], order.field)
+			if cmpErr != nil {
+				err = cmpErr
+				return false
+			}
+			if cmp == 0 {
+				continue
+			}
+			if order.descending {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+	return err
+}
+
+// compareField compares the values of the given field in a and b.
+func (r *repositoryMemory) compareField(a, b reflect.Value, name string) (int, error) {
+	fa, ok := r.schema.field(a, name)
+	if !ok {
+		return 0, fmt.Errorf("unknown order field %s", name)
+	}
+	fb, _ := r.schema.field(b, name)
+	va, err := fieldValue(fa)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := fieldValue(fb)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case va == nil && vb == nil:
+		return 0, nil
+	case va == nil:
+		return -1, nil
+	case vb == nil:
+		return 1, nil
+	}
+	return compare(va, vb)
+}
+
+// filterExpressions returns the expressions contained in filters.
+func filterExpressions(filters []repository.Filter) ([]repository.Expression, error) {
+	exprs := make([]repository.Expression, len(filters))
+	for i, f := range filters {
+		if f.Expression == nil {
+			return nil, fmt.Errorf("%w: filter templates are not supported", repository.ErrUnsupportedExpression)
+		}
+		exprs[i] = f.Expression
+	}
+	return exprs, nil
+}
+
+// convert converts value into a value of type t that can be assigned to a field.
+func convert(value interface{}, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(t), nil
+	}
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(t):
+		return v, nil
+	case t.Kind() == reflect.Ptr:
+		elem, err := convert(value, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(elem)
+		return p, nil
+	case (t.Kind() == reflect.String) != (v.Kind() == reflect.String):
+		return reflect.Value{}, fmt.Errorf("cannot convert %T to %s", value, t)
+	case v.Type().ConvertibleTo(t):
+		return v.Convert(t), nil
+	default:
+		return reflect.Value{}, fmt.Errorf("cannot convert %T to %s", value, t)
+	}
+}
+
+// clone returns an addressable deep copy of v. Pointers, slices, maps and interfaces are copied recursively, so
+// entries stored in the repository don't share memory with the values passed to or returned by it. Unexported struct
+// fields cannot be set using reflection, and they are copied as they are.
+func clone(v reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	c.Set(deepCopy(v, make(map[uintptr]reflect.Value)))
+	return c
+}
+
+// deepCopy returns a deep copy of v. seen contains the copies of the pointers that have already been copied, it's
+// used to preserve pointers that reference the same value and to support cyclic values.
+func deepCopy(v reflect.Value, seen map[uintptr]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		if c, ok := seen[v.Pointer()]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		seen[v.Pointer()] = c
+		c.Elem().Set(deepCopy(v.Elem(), seen))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i), seen))
+			}
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), seen))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), seen))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(deepCopy(iter.Key(), seen), deepCopy(iter.Value(), seen))
+		}
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem(), seen))
+		return c
+	default:
+		return v
+	}
+}
+
+// writeBack stores v in entity if entity is a pointer.
+func writeBack(entity interface{}, v reflect.Value) {
+	if p := reflect.ValueOf(entity); p.Kind() == reflect.Ptr && !p.IsNil() {
+		p.Elem().Set(v)
+	}
+}
@@ -0,0 +1,380 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"github.com/gazebo-web/gz-go/v10/repository/sql"
+	"github.com/stretchr/testify/suite"
+)
+
+type Test struct {
+	sql.Model
+	Name  string   `json:"name"`
+	Value int      `json:"value"`
+	Even  bool     `json:"even"`
+	Tags  []string `json:"tags" gorm:"-"`
+	Score *int     `json:"score"`
+}
+
+func (t Test) TableName() string {
+	return "test"
+}
+
+func TestRepository(t *testing.T) {
+	suite.Run(t, new(RepositoryTestSuite))
+}
+
+type RepositoryTestSuite struct {
+	suite.Suite
+	repository repository.Repository
+}
+
+func (s *RepositoryTestSuite) SetupTest() {
+	s.repository = NewRepository(&Test{})
+
+	entities := make([]repository.Model, 10)
+	for i := range entities {
+		entities[i] = &Test{
+			Name:  fmt.Sprintf("Test%d", i+1),
+			Value: i + 1,
+			Even:  (i+1)%2 == 0,
+			Tags:  []string{fmt.Sprintf("tag-%d", (i+1)%3)},
+		}
+	}
+	_, err := s.repository.CreateBulk(context.Background(), entities)
+	s.Require().NoError(err)
+}
+
+func (s *RepositoryTestSuite) getValues(values []Test) (out []int) {
+	for _, v := range values {
+		out = append(out, v.Value)
+	}
+	return out
+}
+
+func (s *RepositoryTestSuite) TestImplementsInterfaces() {
+	s.Assert().Implements((*repository.Repository)(nil), new(repositoryMemory))
+	s.Assert().Implements((*repository.ConditionalUpdater)(nil), new(repositoryMemory))
+	s.Assert().Implements((*repository.Iterator)(nil), new(repositoryMemory))
+	s.Assert().Implements((*repository.Option)(nil), new(Option))
+}
+
+func (s *RepositoryTestSuite) TestCreate() {
+	ctx := context.Background()
+
+	entity := &Test{Name: "Test11", Value: 11}
+	created, err := s.repository.Create(ctx, entity)
+	s.Require().NoError(err)
+	s.Assert().Same(entity, created)
+	s.Assert().Equal(uint(11), entity.ID)
+	s.Assert().False(entity.CreatedAt.IsZero())
+	s.Assert().False(entity.UpdatedAt.IsZero())
+
+	// Stored entries are not affected by changes to the created entity
+	entity.Name = "changed"
+	var found Test
+	s.Require().NoError(s.repository.FindOne(ctx, &found, repository.NewFilter(repository.Eq("id", 11))))
+	s.Assert().Equal("Test11", found.Name)
+
+	// IDs must be unique
+	_, err = s.repository.Create(ctx, &Test{Model: sql.Model{ID: 1}})
+	s.Assert().ErrorIs(err, ErrDuplicateEntry)
+
+	// Entities must have the repository model type
+	_, err = s.repository.Create(ctx, &struct{ Test }{})
+	s.Assert().ErrorIs(err, repository.ErrInvalidModelType)
+}
+
+func (s *RepositoryTestSuite) TestCreateBulk_Atomic() {
+	_, err := s.repository.CreateBulk(context.Background(), []repository.Model{
+		&Test{Name: "new"},
+		&Test{Model: sql.Model{ID: 20}},
+		&Test{Model: sql.Model{ID: 20}},
+	})
+	s.Assert().ErrorIs(err, ErrDuplicateEntry)
+
+	count, err := s.repository.Count(context.Background())
+	s.Require().NoError(err)
+	s.Assert().Equal(uint64(10), count)
+}
+
+func (s *RepositoryTestSuite) TestFirstOrCreate() {
+	ctx := context.Background()
+
+	existing := Test{Name: "Test1"}
+	s.Require().NoError(s.repository.FirstOrCreate(ctx, &existing, repository.NewFilter(repository.Eq("name", "Test1"))))
+	s.Assert().Equal(uint(1), existing.ID)
+	s.Assert().Equal(1, existing.Value)
+
+	created := Test{Name: "Test11"}
+	s.Require().NoError(s.repository.FirstOrCreate(ctx, &created, repository.NewFilter(repository.Eq("name", "Test11"))))
+	s.Assert().Equal(uint(11), created.ID)
+
+	creator := s.repository.(repository.FirstOrCreator)
+	ok, err := creator.FirstOrCreateAndReport(ctx, &Test{Name: "Test11"}, repository.NewFilter(repository.Eq("name", "Test11")))
+	s.Require().NoError(err)
+	s.Assert().False(ok)
+
+	ok, err = creator.FirstOrCreateAndReport(ctx, &Test{Name: "Test12"}, repository.NewFilter(repository.Eq("name", "Test12")))
+	s.Require().NoError(err)
+	s.Assert().True(ok)
+}
+
+func (s *RepositoryTestSuite) TestUpsert() {
+	ctx := context.Background()
+
+	var existing Test
+	s.Require().NoError(s.repository.FindOne(ctx, &existing, repository.NewFilter(repository.Eq("name", "Test1"))))
+
+	// Entries are matched by ID
+	entity := &Test{Model: sql.Model{ID: existing.ID}, Name: "Test1", Value: 100}
+	s.Require().NoError(s.repository.Upsert(ctx, entity))
+	s.Assert().True(existing.CreatedAt.Equal(entity.CreatedAt))
+
+	// Entries are matched by conflict fields
+	entity = &Test{Name: "Test1", Value: 200}
+	s.Require().NoError(s.repository.Upsert(ctx, entity, "name"))
+	s.Assert().Equal(existing.ID, entity.ID)
+
+	var found Test
+	s.Require().NoError(s.repository.FindOne(ctx, &found, repository.NewFilter(repository.Eq("name", "Test1"))))
+	s.Assert().Equal(200, found.Value)
+
+	// New entries are inserted
+	s.Require().NoError(s.repository.Upsert(ctx, &Test{Name: "Test11"}, "name"))
+	count, err := s.repository.Count(ctx)
+	s.Require().NoError(err)
+	s.Assert().Equal(uint64(11), count)
+}
+
+func (s *RepositoryTestSuite) TestFind() {
+	ctx := context.Background()
+
+	var out []Test
+	s.Require().NoError(s.repository.Find(ctx, &out))
+	s.Assert().Equal([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, s.getValues(out))
+
+	// Filters
+	s.Require().NoError(s.repository.Find(ctx, &out, Where(repository.Eq("even", true)), Where(repository.Lte("value", 6))))
+	s.Assert().Equal([]int{2, 4, 6}, s.getValues(out))
+
+	s.Require().NoError(s.repository.Find(ctx, &out, Where(repository.Or(
+		repository.In("Name", []string{"Test1", "Test2"}),
+		repository.Not(repository.Lt("value", 10)),
+	))))
+	s.Assert().Equal([]int{1, 2, 10}, s.getValues(out))
+
+	s.Require().NoError(s.repository.Find(ctx, &out, Where(repository.Contains("tags", "tag-0"))))
+	s.Assert().Equal([]int{3, 6, 9}, s.getValues(out))
+
+	s.Require().NoError(s.repository.Find(ctx, &out, Where(repository.Contains("name", "t1"))))
+	s.Assert().Equal([]int{1, 10}, s.getValues(out))
+
+	s.Require().NoError(s.repository.Find(ctx, &out, Where(repository.IsNull("score"))))
+	s.Assert().Len(out, 10)
+
+	// Ordering
+	s.Require().NoError(s.repository.Find(ctx, &out, OrderBy(Descending("even"), Descending("value"))))
+	s.Assert().Equal([]int{10, 8, 6, 4, 2, 9, 7, 5, 3, 1}, s.getValues(out))
+
+	// Pagination
+	s.Require().NoError(s.repository.Find(ctx, &out, MaxResults(3), Offset(2)))
+	s.Assert().Equal([]int{3, 4, 5}, s.getValues(out))
+
+	s.Require().NoError(s.repository.Find(ctx, &out, Offset(20)))
+	s.Assert().Empty(out)
+
+	// Pointer slices
+	var pointers []*Test
+	s.Require().NoError(s.repository.Find(ctx, &pointers, MaxResults(2)))
+	s.Require().Len(pointers, 2)
+	s.Assert().Equal("Test2", pointers[1].Name)
+
+	// Invalid input
+	s.Assert().Error(s.repository.Find(ctx, &out, sql.MaxResults(1)))
+	s.Assert().ErrorIs(s.repository.Find(ctx, &out, Where(repository.Eq("invalid", 1))), repository.ErrUnsupportedExpression)
+	s.Assert().ErrorIs(s.repository.Find(ctx, &[]int{}), repository.ErrInvalidModelType)
+	s.Assert().Error(s.repository.Find(ctx, out))
+}
+
+func (s *RepositoryTestSuite) TestIterate() {
+	ctx := context.Background()
+
+	var values []int
+	err := s.repository.(repository.Iterator).Iterate(ctx, func(entry repository.Model) error {
+		test, ok := entry.(*Test)
+		s.Require().True(ok)
+		values = append(values, test.Value)
+		// Entries can be modified while iterating
+		return s.repository.Update(ctx, map[string]interface{}{"value": test.Value * 10}, repository.NewFilter(repository.Eq("id", test.ID)))
+	}, Where(repository.Eq("even", true)), OrderBy(Descending("value")))
+	s.Require().NoError(err)
+	s.Assert().Equal([]int{10, 8, 6, 4, 2}, values)
+
+	count, err := s.repository.Count(ctx, repository.NewFilter(repository.Gt("value", 10)))
+	s.Require().NoError(err)
+	s.Assert().Equal(uint64(5), count)
+
+	// Iteration stops when fn returns an error
+	errStop := errors.New("stop")
+	var calls int
+	err = s.repository.(repository.Iterator).Iterate(ctx, func(entry repository.Model) error {
+		calls++
+		return errStop
+	})
+	s.Assert().ErrorIs(err, errStop)
+	s.Assert().Equal(1, calls)
+}
+
+func (s *RepositoryTestSuite) TestFindOne() {
+	ctx := context.Background()
+
+	var out Test
+	s.Require().NoError(s.repository.FindOne(ctx, &out, repository.NewFilter(repository.Gt("value", 5))))
+	s.Assert().Equal(6, out.Value)
+
+	s.Assert().ErrorIs(s.repository.FindOne(ctx, &out, repository.NewFilter(repository.Gt("value", 50))), repository.ErrNotFound)
+	s.Assert().ErrorIs(s.repository.FindOne(ctx, &out, repository.Filter{Template: "value > ?", Values: []interface{}{5}}), repository.ErrUnsupportedExpression)
+}
+
+func (s *RepositoryTestSuite) TestLast() {
+	var out Test
+	s.Require().NoError(s.repository.Last(context.Background(), &out, repository.NewFilter(repository.Lt("value", 5))))
+	s.Assert().Equal(4, out.Value)
+}
+
+func (s *RepositoryTestSuite) TestUpdate() {
+	ctx := context.Background()
+
+	var before Test
+	s.Require().NoError(s.repository.FindOne(ctx, &before, repository.NewFilter(repository.Eq("name", "Test1"))))
+
+	// Maps
+	s.Require().NoError(s.repository.Update(ctx, map[string]interface{}{"name": "updated", "value": int64(100)},
+		repository.NewFilter(repository.Lt("value", 3))))
+	count, err := s.repository.Count(ctx, repository.NewFilter(repository.Eq("name", "updated")))
+	s.Require().NoError(err)
+	s.Assert().Equal(uint64(2), count)
+
+	var after Test
+	s.Require().NoError(s.repository.FindOne(ctx, &after, repository.NewFilter(repository.Eq("id", before.ID))))
+	s.Assert().Equal(100, after.Value)
+	s.Assert().True(after.UpdatedAt.After(before.UpdatedAt) || after.UpdatedAt.Equal(before.UpdatedAt))
+
+	// Structs only update non-zero fields
+	s.Require().NoError(s.repository.Update(ctx, Test{Name: "struct"}, repository.NewFilter(repository.Eq("id", before.ID))))
+	s.Require().NoError(s.repository.FindOne(ctx, &after, repository.NewFilter(repository.Eq("id", before.ID))))
+	s.Assert().Equal("struct", after.Name)
+	s.Assert().Equal(100, after.Value)
+
+	// Pointer fields
+	s.Require().NoError(s.repository.Update(ctx, map[string]interface{}{"score": 5}, repository.NewFilter(repository.Eq("id", before.ID))))
+	s.Require().NoError(s.repository.FindOne(ctx, &after, repository.NewFilter(repository.Eq("score", 5))))
+	s.Assert().Equal(before.ID, after.ID)
+
+	// Invalid input
+	s.Assert().ErrorIs(s.repository.Update(ctx, map[string]interface{}{"name": "updated"}), repository.ErrNoFilter)
+	s.Assert().ErrorIs(s.repository.Update(ctx, map[string]interface{}{"invalid": 1}, repository.NewFilter(repository.Eq("id", 1))), ErrInvalidUpdateData)
+	s.Assert().ErrorIs(s.repository.Update(ctx, map[string]interface{}{"name": 1}, repository.NewFilter(repository.Eq("id", 1))), ErrInvalidUpdateData)
+}
+
+func (s *RepositoryTestSuite) TestUpdateIf() {
+	ctx := context.Background()
+	updater := s.repository.(repository.ConditionalUpdater)
+
+	var read Test
+	s.Require().NoError(s.repository.FindOne(ctx, &read, repository.NewFilter(repository.Eq("id", 1))))
+
+	s.Require().NoError(updater.UpdateIf(ctx, map[string]interface{}{"value": 10}, repository.IfUpdatedAt(read.UpdatedAt),
+		repository.NewFilter(repository.Eq("id", 1))))
+
+	// Preconditions must be met by every entry
+	err := updater.UpdateIf(ctx, map[string]interface{}{"value": 20}, repository.IfUpdatedAt(read.UpdatedAt),
+		repository.NewFilter(repository.In("id", []uint{1, 2})))
+	s.Assert().ErrorIs(err, repository.ErrConflict)
+	count, err := s.repository.Count(ctx, repository.NewFilter(repository.Eq("value", 20)))
+	s.Require().NoError(err)
+	s.Assert().Zero(count)
+
+	// The model doesn't have a version field
+	err = updater.UpdateIf(ctx, map[string]interface{}{"value": 20}, repository.IfVersion(1), repository.NewFilter(repository.Eq("id", 1)))
+	s.Assert().ErrorIs(err, ErrInvalidUpdateData)
+}
+
+func (s *RepositoryTestSuite) TestDelete() {
+	ctx := context.Background()
+
+	s.Require().NoError(s.repository.Delete(ctx, Where(repository.Eq("even", true))))
+	var out []Test
+	s.Require().NoError(s.repository.Find(ctx, &out))
+	s.Assert().Equal([]int{1, 3, 5, 7, 9}, s.getValues(out))
+
+	// Deleting without filters removes all the entries
+	s.Require().NoError(s.repository.Delete(ctx))
+	count, err := s.repository.Count(ctx)
+	s.Require().NoError(err)
+	s.Assert().Zero(count)
+}
+
+func (s *RepositoryTestSuite) TestEntriesAreCopied() {
+	ctx := context.Background()
+	filter := repository.NewFilter(repository.Eq("name", "Test1"))
+
+	score := 10
+	entity := &Test{Name: "Test11", Value: 11, Tags: []string{"a"}, Score: &score}
+	_, err := s.repository.Create(ctx, entity)
+	s.Require().NoError(err)
+
+	// Changing the created entity doesn't change the stored entry
+	entity.Tags[0] = "b"
+	*entity.Score = 20
+	var stored Test
+	s.Require().NoError(s.repository.FindOne(ctx, &stored, repository.NewFilter(repository.Eq("name", "Test11"))))
+	s.Assert().Equal([]string{"a"}, stored.Tags)
+	s.Assert().Equal(10, *stored.Score)
+
+	// Changing a returned entry doesn't change the stored entry
+	var out Test
+	s.Require().NoError(s.repository.FindOne(ctx, &out, filter))
+	out.Tags[0] = "changed"
+	s.Require().NoError(s.repository.FindOne(ctx, &out, filter))
+	s.Assert().Equal([]string{"tag-1"}, out.Tags)
+}
+
+func (s *RepositoryTestSuite) TestCount() {
+	count, err := s.repository.Count(context.Background(), repository.NewFilter(repository.Gte("value", 5)))
+	s.Require().NoError(err)
+	s.Assert().Equal(uint64(6), count)
+}
+
+func (s *RepositoryTestSuite) TestModel() {
+	s.Assert().Equal(&Test{}, s.repository.Model())
+}
+
+func (s *RepositoryTestSuite) TestConcurrentAccess() {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := s.repository.Create(ctx, &Test{Name: "concurrent"})
+			s.Assert().NoError(err)
+		}()
+		go func() {
+			defer wg.Done()
+			var out []Test
+			s.Assert().NoError(s.repository.Find(ctx, &out))
+		}()
+	}
+	wg.Wait()
+
+	count, err := s.repository.Count(ctx, repository.NewFilter(repository.Eq("name", "concurrent")))
+	s.Require().NoError(err)
+	s.Assert().Equal(uint64(50), count)
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// FieldVersion is the default name of the field used by IfVersion to keep track of entry versions.
+const FieldVersion = "version"
+
+// Precondition is a condition that entries must meet in order to be updated by a conditional update.
+// Preconditions are used to implement optimistic concurrency control: the caller provides the state of the entry it
+// read, and the update is rejected with ErrConflict if the entry has been modified since.
+type Precondition interface {
+	isPrecondition()
+}
+
+// VersionPrecondition requires entries to have a specific version. Entries updated with this precondition get their
+// version incremented by one.
+//
+// Models opt in to version checks by defining an unsigned integer version field.
+type VersionPrecondition struct {
+	// Field contains the name of the version field.
+	Field string
+	// Version contains the version the entry is expected to have.
+	Version uint64
+}
+
+func (VersionPrecondition) isPrecondition() {}
+
+// UpdatedAtPrecondition requires entries to have been last updated at a specific time.
+// It uses the update timestamp that Repository implementations already maintain.
+type UpdatedAtPrecondition struct {
+	// UpdatedAt contains the last update time the entry is expected to have.
+	UpdatedAt time.Time
+}
+
+func (UpdatedAtPrecondition) isPrecondition() {}
+
+// IfVersion returns a Precondition that requires entries to have the given version in their FieldVersion field.
+func IfVersion(version uint64) Precondition {
+	return VersionPrecondition{
+		Field:   FieldVersion,
+		Version: version,
+	}
+}
+
+// IfUpdatedAt returns a Precondition that requires entries to have been last updated at t.
+// t should be the update timestamp read from the entry without any modifications.
+func IfUpdatedAt(t time.Time) Precondition {
+	return UpdatedAtPrecondition{
+		UpdatedAt: t,
+	}
+}
+
+// ConditionalUpdater is implemented by Repository implementations that support optimistic concurrency control.
+type ConditionalUpdater interface {
+	// UpdateIf updates all the entries that match filters with data, only if every one of them meets precondition.
+	// If any of the entries does not meet the precondition, no entries are updated and ErrConflict is returned.
+	// Data can be either a map or a struct, depending on the implementation.
+	UpdateIf(ctx context.Context, data interface{}, precondition Precondition, filters ...Filter) error
+}
@@ -14,6 +14,20 @@ var (
 	// ErrNoEntriesDeleted represent an error when no entries were deleted in the database
 	// after a Delete operation.
 	ErrNoEntriesDeleted = errors.New("no entries were deleted")
+	// ErrNotFound represents an error when no entries match the filters of an operation that expects a single entry.
+	ErrNotFound = errors.New("no entries found")
+	// ErrInvalidModelType represents an error when a Model does not match the type expected by a Repository or
+	// a TypedRepository.
+	ErrInvalidModelType = errors.New("invalid model type")
+	// ErrUnsupportedExpression represents an error when a Repository implementation cannot translate a filter
+	// Expression into a query.
+	ErrUnsupportedExpression = errors.New("unsupported filter expression")
+	// ErrConflict represents an error when an entry was modified by another operation after it was read, and the
+	// Precondition of a conditional update is no longer met.
+	ErrConflict = errors.New("entry was modified by another operation")
+	// ErrUnsupportedOperation represents an error when a Repository decorator cannot forward an operation because the
+	// wrapped Repository does not support it.
+	ErrUnsupportedOperation = errors.New("operation not supported by repository")
 )
 
 // Option is used to define repository operation options for the generic Repository interface.
@@ -35,6 +49,10 @@ type Repository interface {
 	// CreateBulk creates multiple entries with a single operation.
 	// entities: should be a slice of a Model implementation.
 	CreateBulk(ctx context.Context, entities []Model) ([]Model, error)
+	// Upsert inserts a single entry, or updates the existing entry that conflicts with it.
+	// entity: must be a pointer to a Model implementation. The stored entry will be saved in this argument.
+	// conflictFields: fields that identify the existing entry. If empty, the entry identifier is used.
+	Upsert(ctx context.Context, entity Model, conflictFields ...string) error
 	// Find filters entries and stores filtered entries in output.
 	// output: will contain the result of the query. It must be a pointer to a slice.
 	// options: configuration options for the search. Refer to the implementation's set of options to get a lit of options.
@@ -58,3 +76,20 @@ type Repository interface {
 	// Model returns this repository's model.
 	Model() Model
 }
+
+// AffectedCounter is implemented by Repository implementations that report the number of entries affected by update
+// and delete operations.
+type AffectedCounter interface {
+	// UpdateAndCount updates all model entries that match the provided filters with the given data, and returns the
+	// number of updated entries.
+	UpdateAndCount(ctx context.Context, data interface{}, filters ...Filter) (uint64, error)
+	// DeleteAndCount removes all the model entries that match filters, and returns the number of deleted entries.
+	DeleteAndCount(ctx context.Context, opts ...Option) (uint64, error)
+}
+
+// FirstOrCreator is implemented by Repository implementations that report whether FirstOrCreate created a new entry.
+type FirstOrCreator interface {
+	// FirstOrCreateAndReport inserts a new entry if the given filters don't find any existing record, and returns true
+	// if the entry was created, or false if an existing record was found.
+	FirstOrCreateAndReport(ctx context.Context, entity Model, filters ...Filter) (bool, error)
+}
@@ -0,0 +1,139 @@
+package sql
+
+import (
+	"context"
+	stdsql "database/sql"
+	"fmt"
+	stdreflect "reflect"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"gorm.io/gorm/clause"
+)
+
+// Aggregator extends repository.Aggregator with the aggregation queries supported by SQL databases.
+type Aggregator interface {
+	repository.Aggregator
+	// Min stores the minimum value of field in the entries that match filters in output.
+	// output must be a pointer to a value of the field type. It returns repository.ErrNotFound if no entries match
+	// filters.
+	Min(ctx context.Context, output interface{}, field string, filters ...repository.Filter) error
+	// Max stores the maximum value of field in the entries that match filters in output.
+	// output must be a pointer to a value of the field type. It returns repository.ErrNotFound if no entries match
+	// filters.
+	Max(ctx context.Context, output interface{}, field string, filters ...repository.Filter) error
+	// CountBy groups the entries that match filters by the value of field, and stores the number of entries in each
+	// group in output.
+	// output must be a pointer to a map[K]uint64, where K is the field type. Entries with a null field value are not
+	// counted.
+	//
+	//	var counts map[string]uint64
+	//	err := repo.CountBy(ctx, &counts, "status")
+	CountBy(ctx context.Context, output interface{}, field string, filters ...repository.Filter) error
+}
+
+// Ensure that repositoryGorm implements the Aggregator interface.
+var _ Aggregator = (*repositoryGorm)(nil)
+
+// Sum returns the sum of the values of field in the entries that match filters.
+func (r *repositoryGorm) Sum(ctx context.Context, field string, filters ...repository.Filter) (float64, error) {
+	var sum stdsql.NullFloat64
+	if err := r.aggregate(ctx, &sum, "SUM", field, filters); err != nil {
+		return 0, err
+	}
+	return sum.Float64, nil
+}
+
+// Avg returns the average of the values of field in the entries that match filters.
+func (r *repositoryGorm) Avg(ctx context.Context, field string, filters ...repository.Filter) (float64, error) {
+	var avg stdsql.NullFloat64
+	if err := r.aggregate(ctx, &avg, "AVG", field, filters); err != nil {
+		return 0, err
+	}
+	if !avg.Valid {
+		return 0, repository.ErrNotFound
+	}
+	return avg.Float64, nil
+}
+
+// Min stores the minimum value of field in the entries that match filters in output.
+func (r *repositoryGorm) Min(ctx context.Context, output interface{}, field string, filters ...repository.Filter) error {
+	return r.aggregateValue(ctx, output, "MIN", field, filters)
+}
+
+// Max stores the maximum value of field in the entries that match filters in output.
+func (r *repositoryGorm) Max(ctx context.Context, output interface{}, field string, filters ...repository.Filter) error {
+	return r.aggregateValue(ctx, output, "MAX", field, filters)
+}
+
+// CountBy groups the entries that match filters by the value of field, and stores the number of entries in each
+// group in output.
+func (r *repositoryGorm) CountBy(ctx context.Context, output interface{}, field string, filters ...repository.Filter) error {
+	out := stdreflect.ValueOf(output)
+	if out.Kind() != stdreflect.Ptr || out.Elem().Kind() != stdreflect.Map ||
+		out.Elem().Type().Elem().Kind() != stdreflect.Uint64 {
+		return fmt.Errorf("output must be a pointer to a map[K]uint64, got %T", output)
+	}
+	column := clause.Column{Name: field}
+
+	q := r.startQuery(ctx)
+	q = r.setQueryFilters(q, filters)
+	rows, err := q.Select("?, COUNT(*)", column).Where(clause.Neq{Column: column, Value: nil}).Clauses(clause.GroupBy{Columns: []clause.Column{column}}).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	counts := stdreflect.MakeMap(out.Elem().Type())
+	keyType := out.Elem().Type().Key()
+	for rows.Next() {
+		key := stdreflect.New(keyType)
+		var count uint64
+		if err := rows.Scan(key.Interface(), &count); err != nil {
+			return err
+		}
+		counts.SetMapIndex(key.Elem(), stdreflect.ValueOf(count).Convert(out.Elem().Type().Elem()))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	out.Elem().Set(counts)
+	return nil
+}
+
+// aggregateValue stores the result of the aggregate function fn applied to field in output. It returns
+// repository.ErrNotFound if the result is null.
+func (r *repositoryGorm) aggregateValue(ctx context.Context, output interface{}, fn string, field string, filters []repository.Filter) error {
+	out := stdreflect.ValueOf(output)
+	if out.Kind() != stdreflect.Ptr || out.IsNil() {
+		return fmt.Errorf("output must be a pointer, got %T", output)
+	}
+	// The result is scanned into a pointer to detect null values.
+	result := stdreflect.New(stdreflect.PointerTo(out.Elem().Type()))
+	if err := r.aggregate(ctx, result.Interface(), fn, field, filters); err != nil {
+		return err
+	}
+	if result.Elem().IsNil() {
+		return repository.ErrNotFound
+	}
+	out.Elem().Set(result.Elem().Elem())
+	return nil
+}
+
+// aggregate scans the result of the aggregate function fn applied to field in the entries that match filters into
+// dest.
+func (r *repositoryGorm) aggregate(ctx context.Context, dest interface{}, fn string, field string, filters []repository.Filter) error {
+	q := r.startQuery(ctx)
+	q = r.setQueryFilters(q, filters)
+	rows, err := q.Select(fn+"(?)", clause.Column{Name: field}).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return stdsql.ErrNoRows
+	}
+	return rows.Scan(dest)
+}
@@ -0,0 +1,119 @@
+package sql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"gorm.io/gorm/clause"
+)
+
+// likeEscapeCharacter is the character used to escape wildcards in LIKE patterns.
+// A character other than backslash is used because backslashes are handled differently across SQL dialects.
+const likeEscapeCharacter = "!"
+
+// newClauseExpression translates a repository.Expression into a Gorm clause.Expression.
+func newClauseExpression(expr repository.Expression) (clause.Expression, error) {
+	switch e := expr.(type) {
+	case repository.Comparison:
+		return newClauseComparison(e)
+	case repository.Conjunction:
+		exprs, err := newClauseExpressions(e)
+		if err != nil {
+			return nil, err
+		}
+		return clause.And(exprs...), nil
+	case repository.Disjunction:
+		exprs, err := newClauseExpressions(e)
+		if err != nil {
+			return nil, err
+		}
+		return clause.Or(exprs...), nil
+	case repository.Negation:
+		inner, err := newClauseExpression(e.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return clause.Not(inner), nil
+	default:
+		return nil, fmt.Errorf("%w: %T", repository.ErrUnsupportedExpression, expr)
+	}
+}
+
+// newClauseExpressions translates a list of repository.Expression into a list of Gorm clause.Expression.
+func newClauseExpressions(list []repository.Expression) ([]clause.Expression, error) {
+	if len(list) == 0 {
+		return nil, fmt.Errorf("%w: empty logical expression", repository.ErrUnsupportedExpression)
+	}
+	exprs := make([]clause.Expression, len(list))
+	for i, e := range list {
+		var err error
+		if exprs[i], err = newClauseExpression(e); err != nil {
+			return nil, err
+		}
+	}
+	return exprs, nil
+}
+
+// newClauseComparison translates a repository.Comparison into a Gorm clause.Expression.
+func newClauseComparison(c repository.Comparison) (clause.Expression, error) {
+	column := clause.Column{Name: c.Field}
+	switch c.Operator {
+	case repository.OperatorEqual:
+		return clause.Eq{Column: column, Value: c.Value}, nil
+	case repository.OperatorNotEqual:
+		return clause.Neq{Column: column, Value: c.Value}, nil
+	case repository.OperatorLessThan:
+		return clause.Lt{Column: column, Value: c.Value}, nil
+	case repository.OperatorLessThanOrEqual:
+		return clause.Lte{Column: column, Value: c.Value}, nil
+	case repository.OperatorGreaterThan:
+		return clause.Gt{Column: column, Value: c.Value}, nil
+	case repository.OperatorGreaterThanOrEqual:
+		return clause.Gte{Column: column, Value: c.Value}, nil
+	case repository.OperatorIn, repository.OperatorNotIn:
+		values, err := toInterfaceSlice(c.Value)
+		if err != nil {
+			return nil, err
+		}
+		in := clause.IN{Column: column, Values: values}
+		if c.Operator == repository.OperatorNotIn {
+			return clause.Not(in), nil
+		}
+		return in, nil
+	case repository.OperatorContains:
+		return clause.Expr{
+			SQL:  fmt.Sprintf("? LIKE ? ESCAPE '%s'", likeEscapeCharacter),
+			Vars: []interface{}{column, "%" + escapeLike(fmt.Sprint(c.Value)) + "%"},
+		}, nil
+	case repository.OperatorIsNull:
+		return clause.Eq{Column: column, Value: nil}, nil
+	case repository.OperatorIsNotNull:
+		return clause.Neq{Column: column, Value: nil}, nil
+	default:
+		return nil, fmt.Errorf("%w: invalid operator %q", repository.ErrUnsupportedExpression, c.Operator)
+	}
+}
+
+// toInterfaceSlice converts the given slice into a slice of interface{} values.
+func toInterfaceSlice(value any) ([]interface{}, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%w: expected a slice of values, got %T", repository.ErrUnsupportedExpression, value)
+	}
+	out := make([]interface{}, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		out[i] = v.Index(i).Interface()
+	}
+	return out, nil
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern using likeEscapeCharacter.
+func escapeLike(value string) string {
+	return strings.NewReplacer(
+		likeEscapeCharacter, likeEscapeCharacter+likeEscapeCharacter,
+		"%", likeEscapeCharacter+"%",
+		"_", likeEscapeCharacter+"_",
+	).Replace(value)
+}
@@ -0,0 +1,122 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// newDryRunDB initializes a Gorm database connection that generates SQL statements without executing them.
+func newDryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "gazebo:gazebo@tcp(localhost:3306)/gazebo",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	return db
+}
+
+func TestNewClauseExpression(t *testing.T) {
+	db := newDryRunDB(t)
+
+	cases := []struct {
+		name     string
+		expr     repository.Expression
+		expected string
+		vars     []interface{}
+	}{
+		{
+			name:     "eq",
+			expr:     repository.Eq("name", "test"),
+			expected: "WHERE `name` = ?",
+			vars:     []interface{}{"test"},
+		},
+		{
+			name:     "neq",
+			expr:     repository.Neq("value", 1),
+			expected: "WHERE `value` <> ?",
+			vars:     []interface{}{1},
+		},
+		{
+			name:     "lt",
+			expr:     repository.Lt("value", 1),
+			expected: "WHERE `value` < ?",
+			vars:     []interface{}{1},
+		},
+		{
+			name:     "in",
+			expr:     repository.In("value", []int{1, 2}),
+			expected: "WHERE `value` IN (?,?)",
+			vars:     []interface{}{1, 2},
+		},
+		{
+			name:     "is null",
+			expr:     repository.IsNull("deleted_at"),
+			expected: "WHERE `deleted_at` IS NULL",
+			vars:     []interface{}{},
+		},
+		{
+			name:     "is not null",
+			expr:     repository.IsNotNull("deleted_at"),
+			expected: "WHERE `deleted_at` IS NOT NULL",
+			vars:     []interface{}{},
+		},
+		{
+			name:     "contains escapes wildcards",
+			expr:     repository.Contains("name", "50%_!"),
+			expected: "WHERE `name` LIKE ? ESCAPE '!'",
+			vars:     []interface{}{"%50!%!_!!%"},
+		},
+		{
+			name:     "and/or",
+			expr:     repository.And(repository.Eq("a", 1), repository.Or(repository.Eq("b", 2), repository.Eq("c", 3))),
+			expected: "WHERE (`a` = ? AND (`b` = ? OR `c` = ?))",
+			vars:     []interface{}{1, 2, 3},
+		},
+		{
+			name:     "not in",
+			expr:     repository.Not(repository.In("value", []int{1, 2})),
+			expected: "WHERE `value` NOT IN (?,?)",
+			vars:     []interface{}{1, 2},
+		},
+		{
+			name:     "not and",
+			expr:     repository.Not(repository.And(repository.Eq("a", 1), repository.Eq("b", 2))),
+			expected: "WHERE NOT (`a` = ? AND `b` = ?)",
+			vars:     []interface{}{1, 2},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expr, err := newClauseExpression(c.expr)
+			require.NoError(t, err)
+
+			var out []Test
+			stmt := db.Model(&Test{}).Where(expr).Find(&out).Statement
+			assert.Contains(t, stmt.SQL.String(), c.expected)
+			assert.Equal(t, c.vars, stmt.Vars)
+		})
+	}
+}
+
+func TestNewClauseExpression_Invalid(t *testing.T) {
+	_, err := newClauseExpression(repository.Comparison{Field: "name", Operator: "~", Value: "test"})
+	assert.ErrorIs(t, err, repository.ErrUnsupportedExpression)
+
+	_, err = newClauseExpression(repository.Comparison{Field: "name", Operator: repository.OperatorIn, Value: "test"})
+	assert.Error(t, err)
+
+	_, err = newClauseExpression(repository.And())
+	assert.ErrorIs(t, err, repository.ErrUnsupportedExpression)
+
+	_, err = newClauseExpression(nil)
+	assert.ErrorIs(t, err, repository.ErrUnsupportedExpression)
+}
@@ -1,6 +1,10 @@
 package sql
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // Model implements the repository.Model interface for SQL backends.
 // It provides a set of common generic fields and operations that partially implement the repository.Model interface.
@@ -21,3 +25,18 @@ type Model struct {
 func (m Model) GetID() uint {
 	return m.ID
 }
+
+// SoftDeleteModel is a Model that supports soft deletion.
+// Repository deletes set the DeletedAt field of entries instead of removing them, and soft-deleted entries are ignored
+// by Repository operations unless the WithDeleted or OnlyDeleted options are used. Use SoftDeleteRepository to restore
+// or permanently remove them.
+// To use it, embed it in your application-specific repository.Model implementation instead of Model.
+//
+// Models embedding Model are permanently removed by Repository deletes, and the WithDeleted and OnlyDeleted options
+// and SoftDeleteRepository.Restore return repository.ErrUnsupportedOperation when used with them.
+type SoftDeleteModel struct {
+	Model
+	// DeletedAt contains the date and time when this model has been soft deleted. If set, the record will be
+	// considered as deleted. It replaces the DeletedAt field of Model.
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
@@ -2,12 +2,17 @@ package sql
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/gazebo-web/gz-go/v10/repository"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// columnDeletedAt is the name of the column used by SoftDeleteModel to implement soft deletes.
+const columnDeletedAt = "deleted_at"
+
 // Option is a SQL-specific repository.Option implementation.
 // It is used to configure SQL repository operations.
 type Option func(r *gorm.DB)
@@ -30,6 +35,19 @@ func Where(template string, values ...interface{}) repository.Option {
 	})
 }
 
+// WhereExpression filters results based on a backend-agnostic repository.Expression.
+// Multiple WhereExpression options can be passed to a single Repository operation. They are logically ANDed together.
+func WhereExpression(expr repository.Expression) repository.Option {
+	return Option(func(q *gorm.DB) {
+		c, err := newClauseExpression(expr)
+		if err != nil {
+			_ = q.AddError(err)
+			return
+		}
+		*q = *q.Where(c)
+	})
+}
+
 // MaxResults defines the maximum number of results for an operation that can return multiple results.
 // Passing this Option to a Repository operation overwrites any previous MaxResults options passed.
 func MaxResults(n int) repository.Option {
@@ -89,3 +107,52 @@ func Preload(field string, filter ...interface{}) repository.Option {
 		*q = *q.Preload(field, filter...)
 	})
 }
+
+// WithDeleted includes soft-deleted entries in the results of an operation.
+// Only models embedding SoftDeleteModel support soft deletion, operations on other models return
+// repository.ErrUnsupportedOperation.
+func WithDeleted() repository.Option {
+	return Option(func(q *gorm.DB) {
+		if !requireSoftDelete(q) {
+			return
+		}
+		*q = *q.Unscoped()
+	})
+}
+
+// OnlyDeleted limits the results of an operation to soft-deleted entries.
+// Only models embedding SoftDeleteModel support soft deletion, operations on other models return
+// repository.ErrUnsupportedOperation.
+func OnlyDeleted() repository.Option {
+	return Option(func(q *gorm.DB) {
+		if !requireSoftDelete(q) {
+			return
+		}
+		*q = *q.Unscoped().Where(onlyDeletedClause())
+	})
+}
+
+// requireSoftDelete returns true if the model of q supports soft deletion, that is, if it has a gorm.DeletedAt field
+// stored in the deleted_at column such as the one defined by SoftDeleteModel. Otherwise, it adds an error to q.
+func requireSoftDelete(q *gorm.DB) bool {
+	if q.Statement.Schema == nil {
+		if err := q.Statement.Parse(q.Statement.Model); err != nil {
+			_ = q.AddError(err)
+			return false
+		}
+	}
+	f := q.Statement.Schema.LookUpField(columnDeletedAt)
+	if f == nil || f.FieldType != reflect.TypeOf(gorm.DeletedAt{}) {
+		_ = q.AddError(fmt.Errorf("%w: %s does not support soft deletion", repository.ErrUnsupportedOperation, q.Statement.Schema.Name))
+		return false
+	}
+	return true
+}
+
+// onlyDeletedClause returns a condition that matches soft-deleted entries.
+func onlyDeletedClause() clause.Expression {
+	return clause.Neq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: columnDeletedAt},
+		Value:  nil,
+	}
+}
@@ -3,10 +3,12 @@ package sql
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"testing"
 
 	utilsgorm "github.com/gazebo-web/gz-go/v10/database/gorm"
 	"github.com/gazebo-web/gz-go/v10/repository"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/gorm"
 )
@@ -136,6 +138,58 @@ func (s *SQLOptionsTestSuite) TestFindWhereOption() {
 	s.Assert().EqualValues([]int{2, 4, 8, 10}, s.getValues(out))
 }
 
+func (s *SQLOptionsTestSuite) TestFindWhereExpressionOption() {
+	var out []SQLOptionsTestModel
+
+	// Single expression
+	s.Require().NoError(s.repository.Find(context.Background(), &out, WhereExpression(repository.Eq("even", true))))
+	s.Assert().EqualValues([]int{2, 4, 6, 8, 10}, s.getValues(out))
+
+	// Composed expression
+	s.Require().NoError(s.repository.Find(context.Background(), &out, WhereExpression(repository.And(
+		repository.Eq("even", true),
+		repository.Not(repository.In("value", []int{4, 6})),
+	))))
+	s.Assert().EqualValues([]int{2, 8, 10}, s.getValues(out))
+
+	// Multiple expressions are ANDed together
+	s.Require().NoError(s.repository.Find(context.Background(), &out, WhereExpression(repository.Eq("even", true)), WhereExpression(repository.Lt("value", 5))))
+	s.Assert().EqualValues([]int{2, 4}, s.getValues(out))
+
+	// Invalid expressions return an error
+	s.Require().ErrorIs(s.repository.Find(context.Background(), &out, WhereExpression(repository.And())), repository.ErrUnsupportedExpression)
+}
+
+func (s *SQLOptionsTestSuite) TestFindSearchOption() {
+	var out []SQLOptionsTestModel
+
+	// Without a FULLTEXT index, fields are matched with LIKE
+	s.Require().NoError(s.repository.Find(context.Background(), &out, Search([]string{"name"}, "Test 1")))
+	s.Assert().EqualValues([]int{1, 10}, s.getValues(out))
+
+	// Wildcards are escaped
+	s.Require().NoError(s.repository.Find(context.Background(), &out, Search([]string{"name"}, "Test_1")))
+	s.Assert().Empty(out)
+
+	// With a FULLTEXT index, fields are matched with MATCH ... AGAINST
+	s.Require().NoError(s.db.Exec("CREATE FULLTEXT INDEX idx_search_name ON sql_options_test_model (name)").Error)
+	s.Require().NoError(s.repository.Find(context.Background(), &out, Search([]string{"name"}, "test")))
+	s.Assert().EqualValues([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, s.getValues(out))
+
+	// Searching without fields returns an error
+	s.Require().ErrorIs(s.repository.Find(context.Background(), &out, Search(nil, "test")), ErrNoSearchFields)
+}
+
+func (s *SQLOptionsTestSuite) TestFindPrefixMatchOption() {
+	var out []SQLOptionsTestModel
+
+	s.Require().NoError(s.repository.Find(context.Background(), &out, PrefixMatch("name", "Test 1")))
+	s.Assert().EqualValues([]int{1, 10}, s.getValues(out))
+
+	s.Require().NoError(s.repository.Find(context.Background(), &out, PrefixMatch("name", "%1")))
+	s.Assert().Empty(out)
+}
+
 func (s *SQLOptionsTestSuite) TestFindMaxResultsOption() {
 	var out []SQLOptionsTestModel
 
@@ -242,3 +296,42 @@ func (s *SQLOptionsTestSuite) TestFindOrderByOption() {
 	s.Require().NoError(s.repository.Find(context.Background(), &out, OrderBy(Descending("even")), OrderBy(Descending("value"))))
 	s.Assert().EqualValues([]int{10, 8, 6, 4, 2, 9, 7, 5, 3, 1}, s.getValues(out))
 }
+
+func TestSoftDeleteOptions(t *testing.T) {
+	db := newDryRunDB(t)
+
+	var out []SoftDeleteTest
+	stmt := db.Model(&SoftDeleteTest{}).Find(&out).Statement
+	assert.Contains(t, stmt.SQL.String(), "`soft_delete_test`.`deleted_at` IS NULL")
+
+	q := db.Model(&SoftDeleteTest{})
+	WithDeleted().(Option)(q)
+	stmt = q.Find(&out).Statement
+	assert.NotContains(t, stmt.SQL.String(), "deleted_at")
+
+	q = db.Model(&SoftDeleteTest{})
+	OnlyDeleted().(Option)(q)
+	stmt = q.Find(&out).Statement
+	assert.Contains(t, stmt.SQL.String(), "`soft_delete_test`.`deleted_at` IS NOT NULL")
+	assert.NotContains(t, stmt.SQL.String(), "`soft_delete_test`.`deleted_at` IS NULL")
+
+	// Soft deletion is only enabled for models embedding SoftDeleteModel
+	var tests []Test
+	stmt = db.Model(&Test{}).Find(&tests).Statement
+	assert.NotContains(t, stmt.SQL.String(), "deleted_at")
+
+	q = db.Model(&Test{})
+	WithDeleted().(Option)(q)
+	assert.ErrorIs(t, q.Error, repository.ErrUnsupportedOperation)
+
+	q = db.Model(&Test{})
+	OnlyDeleted().(Option)(q)
+	assert.ErrorIs(t, q.Error, repository.ErrUnsupportedOperation)
+
+	// SoftDeleteModel replaces the deleted_at column of Model
+	q = db.Model(&SoftDeleteTest{})
+	assert.NoError(t, q.Statement.Parse(&SoftDeleteTest{}))
+	assert.Len(t, q.Statement.Schema.DBNames, 6)
+	assert.NotNil(t, q.Statement.Schema.LookUpField("ID"))
+	assert.Equal(t, reflect.TypeOf(gorm.DeletedAt{}), q.Statement.Schema.LookUpField(columnDeletedAt).FieldType)
+}
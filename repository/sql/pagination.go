@@ -0,0 +1,218 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gazebo-web/gz-go/v10/pagination"
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// columnID is the name of the primary key column defined by Model. It is used to break ties between entries when
+// paginating results.
+const columnID = "id"
+
+var (
+	// ErrInvalidPageToken is returned when a page token cannot be used to retrieve a page of results.
+	ErrInvalidPageToken = errors.New("invalid page token")
+	// ErrInvalidCursor is returned when a page token cannot be generated from a cursor.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+func init() {
+	// Cursor values are encoded with gob. time.Time is the only driver.Value type that is not registered by default.
+	gob.Register(time.Time{})
+}
+
+// pageCursor contains the values of the sorting fields of the first entry of a page.
+// It implements encoding.TextMarshaler and encoding.TextUnmarshaler to be used as a page token.
+type pageCursor []driver.Value
+
+// MarshalText encodes the cursor.
+func (c pageCursor) MarshalText() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode([]driver.Value(c)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalText decodes the cursor.
+func (c *pageCursor) UnmarshalText(text []byte) error {
+	var values []driver.Value
+	if err := gob.NewDecoder(bytes.NewReader(text)).Decode(&values); err != nil {
+		return err
+	}
+	*c = values
+	return nil
+}
+
+// keysetOrder contains the sorting information of a single field used for keyset pagination.
+type keysetOrder struct {
+	field      string
+	descending bool
+}
+
+// newKeysetOrders parses the given orders and appends an ascending order on the primary key to break ties,
+// unless the last order already sorts by the primary key.
+func newKeysetOrders(orders []OrderByField) []keysetOrder {
+	out := make([]keysetOrder, 0, len(orders)+1)
+	for _, order := range orders {
+		parts := strings.Fields(string(order))
+		if len(parts) == 0 {
+			continue
+		}
+		out = append(out, keysetOrder{
+			field:      parts[0],
+			descending: len(parts) > 1 && strings.EqualFold(parts[1], "DESC"),
+		})
+	}
+	if len(out) == 0 || out[len(out)-1].field != columnID {
+		out = append(out, keysetOrder{field: columnID})
+	}
+	return out
+}
+
+// newKeysetClause returns a condition that matches the entry identified by the cursor values and every entry that
+// follows it in the given order.
+//
+// For orders (a ASC, b DESC, id ASC) and cursor values (x, y, z) it generates:
+//
+//	(a > x) OR (a = x AND b < y) OR (a = x AND b = y AND id > z) OR (a = x AND b = y AND id = z)
+func newKeysetClause(orders []keysetOrder, values []driver.Value) clause.Expression {
+	conditions := make([]clause.Expression, 0, len(orders)+1)
+	equals := make([]clause.Expression, 0, len(orders))
+	for i, order := range orders {
+		column := clause.Column{Name: order.field}
+		var next clause.Expression = clause.Gt{Column: column, Value: values[i]}
+		if order.descending {
+			next = clause.Lt{Column: column, Value: values[i]}
+		}
+		condition := append(append(make([]clause.Expression, 0, len(equals)+1), equals...), next)
+		conditions = append(conditions, clause.And(condition...))
+		equals = append(equals, clause.Eq{Column: column, Value: values[i]})
+	}
+	conditions = append(conditions, clause.And(equals...))
+	return clause.Or(conditions...)
+}
+
+// setMaxResults establishes the max number of items that should be returned from a query
+// based on pagination configuration.
+//
+// In order to determine whether there are additional pages of results available, this function requests one
+// extra element than the maximum page size. If this element exists, then there is an additional page available, if not,
+// then this is the last page.
+//
+// The last element should be discarded before the list is returned to the user.
+// See pagination.GetListAndCursor for more information.
+func setMaxResults(opts []repository.Option, sg pagination.PageSizeGetter) ([]repository.Option, error) {
+	p := pagination.PageSize(sg)
+	if p == pagination.InvalidValue {
+		return nil, errors.New("invalid page size")
+	}
+	return append(opts, MaxResults(int(p)+1)), nil
+}
+
+// SetCurrentPage generates a set of repository.Option to retrieve results for a specific page using keyset
+// pagination.
+//
+// Results are sorted by the given orders. An ascending order on the "id" column is appended to break ties, unless the
+// last order already sorts by "id". The same orders must be used to request every page of a query, and to generate
+// page tokens with GetNextPageToken.
+//
+// Fields used for sorting must not contain null values.
+func SetCurrentPage(p pagination.Pagination, orders ...OrderByField) ([]repository.Option, error) {
+	keyset := newKeysetOrders(orders)
+	sorting := make([]OrderByField, 0, len(keyset))
+	for _, order := range keyset {
+		if order.descending {
+			sorting = append(sorting, Descending(order.field))
+		} else {
+			sorting = append(sorting, Ascending(order.field))
+		}
+	}
+
+	var opts []repository.Option
+	opts = append(opts, OrderBy(sorting...))
+	opts, err := setMaxResults(opts, p)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil || len(p.GetPageToken()) == 0 {
+		return opts, nil
+	}
+
+	var cursor pageCursor
+	if err := pagination.ParsePageToken(p.GetPageToken(), &cursor); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPageToken, err)
+	}
+	if len(cursor) != len(keyset) {
+		return nil, fmt.Errorf("%w: the token does not match the requested order", ErrInvalidPageToken)
+	}
+	opts = append(opts, Option(func(q *gorm.DB) {
+		*q = *q.Where(newKeysetClause(keyset, cursor))
+	}))
+	return opts, nil
+}
+
+// GetNextPageToken generates a page token to request the page of results that starts with cursor. cursor is usually
+// the value returned by pagination.GetListAndCursor. The orders must match the orders passed to SetCurrentPage.
+//
+// Fields are looked up by column or struct field name, using the default Gorm naming strategy.
+// It returns an empty string if cursor is a zero value.
+func GetNextPageToken(cursor interface{}, orders ...OrderByField) (string, error) {
+	value := reflect.ValueOf(cursor)
+	if !value.IsValid() || value.IsZero() {
+		return "", nil
+	}
+	s, err := schema.Parse(cursor, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidCursor, err)
+	}
+
+	keyset := newKeysetOrders(orders)
+	values := make(pageCursor, len(keyset))
+	for i, order := range keyset {
+		field := s.LookUpField(order.field)
+		if field == nil {
+			return "", fmt.Errorf("%w: field %s not found", ErrInvalidCursor, order.field)
+		}
+		v, _ := field.ValueOf(context.Background(), reflect.Indirect(value))
+		values[i], err = driver.DefaultParameterConverter.ConvertValue(v)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrInvalidCursor, err)
+		}
+		if values[i] == nil {
+			return "", fmt.Errorf("%w: field %s is null", ErrInvalidCursor, order.field)
+		}
+	}
+
+	token := pagination.NewPageToken(values)
+	if len(token) == 0 {
+		return "", ErrInvalidCursor
+	}
+	return token, nil
+}
+
+// GetListAndPageToken takes the results of a query paginated with SetCurrentPage and returns the page of results and
+// the token to request the next page. The token is empty if the returned page is the last page.
+// The orders must match the orders passed to SetCurrentPage.
+func GetListAndPageToken[T any](raw []T, sg pagination.PageSizeGetter, orders ...OrderByField) ([]T, string, error) {
+	list, cursor := pagination.GetListAndCursor(raw, sg)
+	token, err := GetNextPageToken(cursor, orders...)
+	if err != nil {
+		return nil, "", err
+	}
+	return list, token, nil
+}
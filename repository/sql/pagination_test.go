@@ -0,0 +1,108 @@
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gazebo-web/gz-go/v10/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPagination struct {
+	size  int32
+	token string
+}
+
+func (p testPagination) GetPageSize() int32 {
+	return p.size
+}
+
+func (p testPagination) GetPageToken() string {
+	return p.token
+}
+
+func TestSetCurrentPage_Query(t *testing.T) {
+	db := newDryRunDB(t)
+
+	entry := Test{Model: Model{ID: 5, UpdatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}, Value: 3}
+	token, err := GetNextPageToken(entry, Descending("value"))
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	opts, err := SetCurrentPage(testPagination{size: 2, token: token}, Descending("value"))
+	require.NoError(t, err)
+
+	q := db.Model(&Test{})
+	for _, opt := range opts {
+		opt.(Option)(q)
+	}
+	var out []Test
+	stmt := q.Find(&out).Statement
+	assert.Equal(t, "SELECT * FROM `test` WHERE (`value` < ? OR (`value` = ? AND `id` > ?) OR (`value` = ? AND `id` = ?)) "+
+		"ORDER BY value DESC,id ASC LIMIT 3", stmt.SQL.String())
+	assert.Equal(t, []interface{}{int64(3), int64(3), int64(5), int64(3), int64(5)}, stmt.Vars)
+}
+
+func TestSetCurrentPage_Errors(t *testing.T) {
+	_, err := SetCurrentPage(testPagination{size: -1})
+	assert.Error(t, err)
+
+	_, err = SetCurrentPage(testPagination{size: 2, token: "not a token"})
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+
+	// The token was generated for a different order
+	token, err := GetNextPageToken(Test{Model: Model{ID: 1}}, Ascending("value"))
+	require.NoError(t, err)
+	_, err = SetCurrentPage(testPagination{size: 2, token: token})
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+}
+
+func TestGetNextPageToken(t *testing.T) {
+	// Zero values return an empty token
+	token, err := GetNextPageToken(Test{})
+	assert.NoError(t, err)
+	assert.Empty(t, token)
+
+	token, err = GetNextPageToken((*Test)(nil))
+	assert.NoError(t, err)
+	assert.Empty(t, token)
+
+	// Unknown fields
+	_, err = GetNextPageToken(Test{Model: Model{ID: 1}}, Ascending("invalid"))
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	// Tokens contain the values of the sorting fields
+	updatedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	token, err = GetNextPageToken(&Test{Model: Model{ID: 5, UpdatedAt: updatedAt}, Name: "test"}, Ascending("updated_at"), Descending("Name"))
+	require.NoError(t, err)
+
+	var cursor pageCursor
+	require.NoError(t, pagination.ParsePageToken(token, &cursor))
+	assert.Len(t, cursor, 3)
+	assert.True(t, updatedAt.Equal(cursor[0].(time.Time)))
+	assert.Equal(t, "test", cursor[1])
+	assert.Equal(t, int64(5), cursor[2])
+}
+
+func (s *SQLOptionsTestSuite) TestSetCurrentPage() {
+	p := testPagination{size: 3}
+	var values []int
+	for i := 0; i < 10; i++ {
+		opts, err := SetCurrentPage(p, Descending("even"), Ascending("value"))
+		s.Require().NoError(err)
+
+		var out []SQLOptionsTestModel
+		s.Require().NoError(s.repository.Find(context.Background(), &out, opts...))
+
+		list, token, err := GetListAndPageToken(out, p, Descending("even"), Ascending("value"))
+		s.Require().NoError(err)
+		values = append(values, s.getValues(list)...)
+		if token == "" {
+			break
+		}
+		p.token = token
+	}
+	s.Assert().EqualValues([]int{2, 4, 6, 8, 10, 1, 3, 5, 7, 9}, values)
+}
@@ -0,0 +1,64 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// columnUpdatedAt is the name of the column used by Model to store the last update time.
+const columnUpdatedAt = "updated_at"
+
+// ErrInvalidUpdateData is returned when the data passed to a conditional update cannot be used to update entries.
+var ErrInvalidUpdateData = errors.New("invalid update data")
+
+// newPreconditionClause translates a repository.Precondition into a SQL condition.
+func newPreconditionClause(precondition repository.Precondition) (clause.Expression, error) {
+	switch p := precondition.(type) {
+	case repository.VersionPrecondition:
+		return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: p.Field}, Value: p.Version}, nil
+	case repository.UpdatedAtPrecondition:
+		return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: columnUpdatedAt}, Value: p.UpdatedAt}, nil
+	default:
+		return nil, fmt.Errorf("unsupported precondition %T", precondition)
+	}
+}
+
+// setVersion returns a copy of data with its version field set to version, data is never modified.
+// Maps are returned as maps, and structs are returned as pointers to a copy of the struct.
+func (r *repositoryGorm) setVersion(data interface{}, field string, version uint64) (interface{}, error) {
+	if values, ok := data.(map[string]interface{}); ok {
+		out := make(map[string]interface{}, len(values)+1)
+		for k, v := range values {
+			out[k] = v
+		}
+		out[field] = version
+		return out, nil
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(data))
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: expected a map or a struct, got %T", ErrInvalidUpdateData, data)
+	}
+	ptr := reflect.New(value.Type())
+	ptr.Elem().Set(value)
+	value = ptr
+
+	stmt := &gorm.Statement{DB: r.DB}
+	if err := stmt.Parse(value.Interface()); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidUpdateData, err)
+	}
+	f := stmt.Schema.LookUpField(field)
+	if f == nil {
+		return nil, fmt.Errorf("%w: %s does not contain a %s field", ErrInvalidUpdateData, stmt.Schema.Name, field)
+	}
+	if err := f.Set(context.Background(), value.Elem(), version); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidUpdateData, err)
+	}
+	return value.Interface(), nil
+}
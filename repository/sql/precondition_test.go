@@ -0,0 +1,56 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetVersion(t *testing.T) {
+	r := &repositoryGorm{DB: newDryRunDB(t)}
+
+	// Maps are copied
+	data := map[string]interface{}{"name": "test"}
+	out, err := r.setVersion(data, repository.FieldVersion, 2)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "test", "version": uint64(2)}, out)
+	assert.NotContains(t, data, "version")
+
+	// Pointers to structs are copied
+	entity := &VersionedTest{Version: 1}
+	out, err = r.setVersion(entity, repository.FieldVersion, 2)
+	require.NoError(t, err)
+	assert.NotSame(t, entity, out)
+	assert.Equal(t, uint(2), out.(*VersionedTest).Version)
+	assert.Equal(t, uint(1), entity.Version)
+
+	// Structs are copied
+	value := VersionedTest{Version: 1}
+	out, err = r.setVersion(value, repository.FieldVersion, 2)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), out.(*VersionedTest).Version)
+	assert.Equal(t, uint(1), value.Version)
+
+	// Models without a version field cannot be used
+	_, err = r.setVersion(&Test{}, repository.FieldVersion, 2)
+	assert.ErrorIs(t, err, ErrInvalidUpdateData)
+
+	_, err = r.setVersion(1, repository.FieldVersion, 2)
+	assert.ErrorIs(t, err, ErrInvalidUpdateData)
+}
+
+func TestNewPreconditionClause(t *testing.T) {
+	db := newDryRunDB(t)
+
+	c, err := newPreconditionClause(repository.IfVersion(3))
+	require.NoError(t, err)
+	var out []Test
+	stmt := db.Model(&Test{}).Where(c).Find(&out).Statement
+	assert.Contains(t, stmt.SQL.String(), "`test`.`version` = ?")
+	assert.Equal(t, []interface{}{uint64(3)}, stmt.Vars)
+
+	_, err = newPreconditionClause(nil)
+	assert.Error(t, err)
+}
@@ -2,29 +2,94 @@ package sql
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	stdreflect "reflect"
+	"strings"
 
 	"github.com/gazebo-web/gz-go/v10/reflect"
 	"github.com/gazebo-web/gz-go/v10/repository"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// defaultCreateBatchSize is the number of entries inserted by each statement of CreateBulk when the gorm.DB used by
+// the repository does not set a CreateBatchSize.
+const defaultCreateBatchSize = 100
+
 // NewRepository initializes a new repository.Repository implementation for SQL databases.
-func NewRepository(db *gorm.DB, entity repository.Model) repository.Repository {
-	return &repositoryGorm{
+// The returned repository also implements SoftDeleteRepository.
+// The number of entries inserted by each statement of CreateBulk can be configured with the CreateBatchSize field of
+// gorm.Config or gorm.Session.
+func NewRepository(db *gorm.DB, entity repository.Model, opts ...RepositoryOption) repository.Repository {
+	return newRepositoryGorm(db, entity, opts)
+}
+
+// RepositoryOption contains logic that can be passed to a repository initializer to configure it.
+type RepositoryOption func(r *repositoryGorm)
+
+// Strict makes Update, UpdateIf and Restore return repository.ErrNoEntriesUpdated when no entries are updated, and
+// Delete and HardDelete return repository.ErrNoEntriesDeleted when no entries are deleted.
+//
+// MySQL reports the number of rows whose values actually changed, so an update that sets the values entries already
+// have counts as no entries updated. Updates to models with an UpdatedAt field always change the entries.
+func Strict() RepositoryOption {
+	return func(r *repositoryGorm) {
+		r.strict = true
+	}
+}
+
+// SoftDeleteRepository is a repository.Repository that provides operations to manage soft-deleted entries.
+// Only models with a gorm.DeletedAt field (such as models embedding SoftDeleteModel) support soft deletion.
+// The repository.Repository returned by NewRepository implements this interface.
+type SoftDeleteRepository interface {
+	repository.Repository
+	// Restore restores all the soft-deleted model entries that match the given options.
+	// At least one option must be provided.
+	Restore(ctx context.Context, opts ...repository.Option) error
+	// HardDelete permanently removes all the model entries that match the given options, including soft-deleted
+	// entries.
+	HardDelete(ctx context.Context, opts ...repository.Option) error
+}
+
+// newRepositoryGorm initializes a new repositoryGorm and applies the given options to it.
+func newRepositoryGorm(db *gorm.DB, entity repository.Model, opts []RepositoryOption) *repositoryGorm {
+	r := &repositoryGorm{
 		DB:     db,
 		entity: entity,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 // repositoryGorm implements a SQL repository.Repository implementation using Gorm.
 type repositoryGorm struct {
 	DB     *gorm.DB
 	entity repository.Model
+	// strict is true if operations that don't affect any entries should return an error.
+	strict bool
 }
 
 // Ensure that repositoryGorm implements the repository.Repository interface.
 var _ repository.Repository = (*repositoryGorm)(nil)
 
+// Ensure that repositoryGorm implements the repository.ConditionalUpdater interface.
+var _ repository.ConditionalUpdater = (*repositoryGorm)(nil)
+
+// Ensure that repositoryGorm implements the repository.Iterator interface.
+var _ repository.Iterator = (*repositoryGorm)(nil)
+
+// Ensure that repositoryGorm implements the repository.AffectedCounter interface.
+var _ repository.AffectedCounter = (*repositoryGorm)(nil)
+
+// Ensure that repositoryGorm implements the repository.FirstOrCreator interface.
+var _ repository.FirstOrCreator = (*repositoryGorm)(nil)
+
+// Ensure that repositoryGorm implements the SoftDeleteRepository interface.
+var _ SoftDeleteRepository = (*repositoryGorm)(nil)
+
 // applyOptions applies operation options to a database query.
 func (r *repositoryGorm) applyOptions(q *gorm.DB, opts ...repository.Option) {
 	for _, opt := range opts {
@@ -43,19 +108,114 @@ func (r *repositoryGorm) Create(ctx context.Context, entity repository.Model) (r
 	return result[0], nil
 }
 
-// CreateBulk creates multiple entries with a single operation.
+// CreateBulk creates multiple entries using batched multi-row inserts.
+// All the entries are inserted inside a transaction, if any of the inserts fails, no entries are created.
+// The size of each batch is taken from the CreateBatchSize setting of the gorm.DB used by this repository, and
+// defaults to defaultCreateBatchSize. Cancelling ctx stops the operation between batches.
 //
-//	entities: should be a slice of the same data structure implementing repository.Model.
+//	entities: should be a slice of pointers to the same data structure implementing repository.Model.
 func (r *repositoryGorm) CreateBulk(ctx context.Context, entities []repository.Model) ([]repository.Model, error) {
-	for _, entity := range entities {
-		err := r.DB.Model(r.Model()).Create(entity).Error
-		if err != nil {
-			return nil, err
+	if len(entities) == 0 {
+		return entities, nil
+	}
+	values, err := newModelSlice(entities)
+	if err != nil {
+		return nil, err
+	}
+	err = NewTransactioner(r.DB).WithTransaction(ctx, func(ctx context.Context) error {
+		size := r.conn(ctx).CreateBatchSize
+		if size <= 0 {
+			size = defaultCreateBatchSize
+		}
+		for i := 0; i < values.Len(); i += size {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			end := i + size
+			if end > values.Len() {
+				end = values.Len()
+			}
+			if err := r.startQuery(ctx).Create(values.Slice(i, end).Interface()).Error; err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return entities, nil
 }
 
+// Upsert inserts a single entry, or updates the existing entry that conflicts with it.
+// MySQL detects conflicts on any unique index, while other databases only use the conflictFields unique index.
+// If conflictFields are provided, the stored entry is read back into entity after the operation.
+// The soft delete column is never updated: soft-deleted entries that conflict with entity are updated, but they are not
+// restored.
+//
+//	entity: must be a pointer to a repository.Model implementation.
+//	conflictFields: columns of the unique index used to detect conflicts. If empty, the primary key is used.
+func (r *repositoryGorm) Upsert(ctx context.Context, entity repository.Model, conflictFields ...string) error {
+	fields := make([]interface{}, len(conflictFields))
+	for i, field := range conflictFields {
+		fields[i] = field
+	}
+	onConflict, err := r.newOnConflictClause(conflictFields)
+	if err != nil {
+		return err
+	}
+	return NewTransactioner(r.DB).WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.startQuery(ctx).Clauses(onConflict).Create(entity)
+		if q.Error != nil {
+			return q.Error
+		}
+		if len(conflictFields) == 0 {
+			return nil
+		}
+		// The primary key set by the insert is not reliable when an existing entry is updated
+		stored := r.Model()
+		if err := r.startQuery(ctx).Unscoped().Where(entity, fields...).First(stored).Error; err != nil {
+			return err
+		}
+		return reflect.SetValue(entity, stdreflect.ValueOf(stored).Elem().Interface())
+	})
+}
+
+// newOnConflictClause returns the clause used by Upsert to update the existing entry that conflicts with the inserted
+// one. It updates the same columns as Gorm's clause.OnConflict UpdateAll, excluding the soft delete column.
+func (r *repositoryGorm) newOnConflictClause(conflictFields []string) (clause.OnConflict, error) {
+	stmt := &gorm.Statement{DB: r.DB}
+	if err := stmt.Parse(r.Model()); err != nil {
+		return clause.OnConflict{}, err
+	}
+
+	var onConflict clause.OnConflict
+	for _, field := range conflictFields {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: field})
+	}
+	if len(onConflict.Columns) == 0 {
+		for _, f := range stmt.Schema.PrimaryFields {
+			onConflict.Columns = append(onConflict.Columns, clause.Column{Name: f.DBName})
+		}
+	}
+
+	columns := make([]string, 0, len(stmt.Schema.DBNames))
+	for _, name := range stmt.Schema.DBNames {
+		f := stmt.Schema.FieldsByDBName[name]
+		if f.PrimaryKey || f.AutoCreateTime > 0 || !f.Creatable || name == columnDeletedAt {
+			continue
+		}
+		// Fields with a database default value are not inserted when they're empty
+		if f.HasDefaultValue && f.DefaultValueInterface == nil && !strings.EqualFold(f.DefaultValue, "NULL") {
+			continue
+		}
+		columns = append(columns, name)
+	}
+	onConflict.DoUpdates = clause.AssignmentColumns(columns)
+	onConflict.DoNothing = len(columns) == 0
+	return onConflict, nil
+}
+
 // Find filters entries and stores filtered entries in output.
 //
 //	output: will contain the result of the query. It must be a pointer to a slice.
@@ -71,8 +231,35 @@ func (r *repositoryGorm) Find(ctx context.Context, output interface{}, options .
 	return nil
 }
 
+// Iterate calls fn with every entry found using the given options. Entries are streamed from a single query, and
+// each entry passed to fn is a pointer to a new instance of the repository model.
+//
+// The database connection used by the query is held until the iteration finishes. If ctx carries a transaction, fn
+// must not run operations in that same transaction. The Preload option is not supported.
+func (r *repositoryGorm) Iterate(ctx context.Context, fn repository.IterateFunc, options ...repository.Option) error {
+	q := r.startQuery(ctx)
+	r.applyOptions(q, options...)
+	rows, err := q.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		entry := r.Model()
+		if err := q.ScanRows(rows, entry); err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 // FindOne filters entries and stores the first filtered entry in output, it must be a pointer to
 // a data structure implementing repository.Model.
+// It returns repository.ErrNotFound if no entries match the given filters. The returned error also matches
+// gorm.ErrRecordNotFound.
 func (r *repositoryGorm) FindOne(ctx context.Context, output repository.Model, filters ...repository.Filter) error {
 	if len(filters) == 0 {
 		return repository.ErrNoFilter
@@ -80,10 +267,12 @@ func (r *repositoryGorm) FindOne(ctx context.Context, output repository.Model, f
 	q := r.startQuery(ctx)
 	q = r.setQueryFilters(q, filters)
 	q = q.First(output)
-	return q.Error
+	return wrapNotFound(q.Error)
 }
 
 // Last gets the last record ordered by primary key desc.
+// It returns repository.ErrNotFound if no entries match the given filters. The returned error also matches
+// gorm.ErrRecordNotFound.
 //
 //	output: must be a pointer to a repository.Model implementation.
 func (r *repositoryGorm) Last(ctx context.Context, output repository.Model, filters ...repository.Filter) error {
@@ -93,7 +282,16 @@ func (r *repositoryGorm) Last(ctx context.Context, output repository.Model, filt
 	q := r.startQuery(ctx)
 	q = r.setQueryFilters(q, filters)
 	q = q.Last(output)
-	return q.Error
+	return wrapNotFound(q.Error)
+}
+
+// wrapNotFound wraps gorm.ErrRecordNotFound errors with repository.ErrNotFound, allowing callers to check for missing
+// entries using either error. Other errors are returned as they are.
+func wrapNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %w", repository.ErrNotFound, err)
+	}
+	return err
 }
 
 // Update updates all model entries that match the provided filters with the given data.
@@ -101,33 +299,172 @@ func (r *repositoryGorm) Last(ctx context.Context, output repository.Model, filt
 //		data: must be a map[string]interface{}
 //	 filters: filter entries that should be updated.
 func (r *repositoryGorm) Update(ctx context.Context, data interface{}, filters ...repository.Filter) error {
+	_, err := r.UpdateAndCount(ctx, data, filters...)
+	return err
+}
+
+// UpdateAndCount updates all model entries that match the provided filters with the given data, and returns the number
+// of updated entries.
+//
+//		data: must be a map[string]interface{}
+//	 filters: filter entries that should be updated.
+func (r *repositoryGorm) UpdateAndCount(ctx context.Context, data interface{}, filters ...repository.Filter) (uint64, error) {
 	q := r.startQuery(ctx)
 	q = r.setQueryFilters(q, filters)
 	q = q.Updates(data)
-	return q.Error
+	if q.Error != nil {
+		return 0, q.Error
+	}
+	return r.affected(q.RowsAffected, repository.ErrNoEntriesUpdated)
+}
+
+// UpdateIf updates all the model entries that match filters with data, only if every one of them meets precondition.
+// If any of the entries does not meet the precondition, no entries are updated and repository.ErrConflict is returned.
+// Entries are locked for the duration of the update.
+//
+//	data: a map or a struct containing the fields to update. Entries updated with a repository.VersionPrecondition
+//	  get their version incremented, and the new version is set in data if it is a pointer to a struct and the
+//	  update succeeds.
+//	precondition: the condition that entries must meet in order to be updated.
+//	filters: selection criteria for entries that should be updated. At least one filter must be provided.
+func (r *repositoryGorm) UpdateIf(ctx context.Context, data interface{}, precondition repository.Precondition, filters ...repository.Filter) error {
+	if len(filters) == 0 {
+		return repository.ErrNoFilter
+	}
+	cond, err := newPreconditionClause(precondition)
+	if err != nil {
+		return err
+	}
+	p, versioned := precondition.(repository.VersionPrecondition)
+	if !versioned {
+		return r.updateIf(ctx, data, cond, filters)
+	}
+	values, err := r.setVersion(data, p.Field, p.Version+1)
+	if err != nil {
+		return err
+	}
+	if err := r.updateIf(ctx, values, cond, filters); err != nil {
+		return err
+	}
+	// The new version is only set in data once the update succeeds.
+	if ptr := stdreflect.ValueOf(data); ptr.Kind() == stdreflect.Ptr {
+		ptr.Elem().Set(stdreflect.ValueOf(values).Elem())
+	}
+	return nil
+}
+
+// updateIf updates the entries that match filters with data inside a transaction, only if every one of them meets
+// the given condition.
+func (r *repositoryGorm) updateIf(ctx context.Context, data interface{}, cond clause.Expression, filters []repository.Filter) error {
+	return NewTransactioner(r.DB).WithTransaction(ctx, func(ctx context.Context) error {
+		var ids []interface{}
+		q := r.startQuery(ctx)
+		q = r.setQueryFilters(q, filters)
+		if err := q.Clauses(clause.Locking{Strength: "UPDATE"}).Pluck(columnID, &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			_, err := r.affected(0, repository.ErrNoEntriesUpdated)
+			return err
+		}
+		q = r.startQuery(ctx).Where(clause.IN{Column: clause.PrimaryColumn, Values: ids}).Where(cond)
+		if err := q.Updates(data).Error; err != nil {
+			return err
+		}
+		if q.RowsAffected != int64(len(ids)) {
+			return repository.ErrConflict
+		}
+		return nil
+	})
 }
 
 // Delete removes all the model entries that match filters.
+// Models that support soft deletion are soft deleted, use HardDelete to remove them permanently.
 //
 //	options: configuration options for the removal.
 func (r *repositoryGorm) Delete(ctx context.Context, opts ...repository.Option) error {
+	_, err := r.DeleteAndCount(ctx, opts...)
+	return err
+}
+
+// DeleteAndCount removes all the model entries that match filters, and returns the number of deleted entries.
+// Models that support soft deletion are soft deleted, use HardDelete to remove them permanently.
+//
+//	options: configuration options for the removal.
+func (r *repositoryGorm) DeleteAndCount(ctx context.Context, opts ...repository.Option) (uint64, error) {
 	q := r.startQuery(ctx)
 	r.applyOptions(q, opts...)
 	q = q.Delete(r.Model())
-	err := q.Error
-	if err != nil {
-		return err
+	if q.Error != nil {
+		return 0, q.Error
 	}
-	return nil
+	return r.affected(q.RowsAffected, repository.ErrNoEntriesDeleted)
+}
+
+// Restore restores all the soft-deleted model entries that match the given options.
+//
+//	options: configuration options to select the entries to restore. At least one option must be provided.
+func (r *repositoryGorm) Restore(ctx context.Context, opts ...repository.Option) error {
+	if len(opts) == 0 {
+		return repository.ErrNoFilter
+	}
+	q := r.startQuery(ctx)
+	r.applyOptions(q, opts...)
+	if !requireSoftDelete(q) {
+		return q.Error
+	}
+	q = q.Unscoped().Where(onlyDeletedClause()).Update(columnDeletedAt, nil)
+	if q.Error != nil {
+		return q.Error
+	}
+	_, err := r.affected(q.RowsAffected, repository.ErrNoEntriesUpdated)
+	return err
+}
+
+// HardDelete permanently removes all the model entries that match the given options, including soft-deleted entries.
+//
+//	options: configuration options for the removal.
+func (r *repositoryGorm) HardDelete(ctx context.Context, opts ...repository.Option) error {
+	q := r.startQuery(ctx)
+	r.applyOptions(q, opts...)
+	q = q.Unscoped().Delete(r.Model())
+	if q.Error != nil {
+		return q.Error
+	}
+	_, err := r.affected(q.RowsAffected, repository.ErrNoEntriesDeleted)
+	return err
+}
+
+// affected returns the number of rows affected by an operation. If the repository is strict, it returns errNone when
+// no rows were affected.
+func (r *repositoryGorm) affected(rows int64, errNone error) (uint64, error) {
+	if rows == 0 && r.strict {
+		return 0, errNone
+	}
+	return uint64(rows), nil
 }
 
 // FirstOrCreate inserts a new entry if the given filters don't find any existing record.
 //
 //	entity: must be a pointer to a repository.Model implementation. Results will be saved in this argument if the record exists.
 func (r *repositoryGorm) FirstOrCreate(ctx context.Context, entity repository.Model, filters ...repository.Filter) error {
+	_, err := r.FirstOrCreateAndReport(ctx, entity, filters...)
+	return err
+}
+
+// FirstOrCreateAndReport inserts a new entry if the given filters don't find any existing record, and returns true if
+// the entry was created.
+//
+//	entity: must be a pointer to a repository.Model implementation. Results will be saved in this argument if the record exists.
+func (r *repositoryGorm) FirstOrCreateAndReport(ctx context.Context, entity repository.Model, filters ...repository.Filter) (bool, error) {
 	q := r.startQuery(ctx)
 	q = r.setQueryFilters(q, filters)
-	return q.FirstOrCreate(entity).Error
+	q = q.FirstOrCreate(entity)
+	if q.Error != nil {
+		return false, q.Error
+	}
+	// Gorm only reports affected rows if the entry is created
+	return q.RowsAffected > 0, nil
 }
 
 // Count counts all the model entries that match filters.
@@ -145,14 +482,32 @@ func (r *repositoryGorm) Count(ctx context.Context, filters ...repository.Filter
 
 // startQuery inits a sql query for this repository's model. Multiple filters are ANDd together.
 func (r *repositoryGorm) startQuery(ctx context.Context) *gorm.DB {
+	return r.conn(ctx).Model(r.Model()).WithContext(ctx)
+}
 
-	return r.DB.Model(r.Model()).WithContext(ctx)
+// conn returns the database connection used to run operations. If ctx carries a transaction started by a
+// repository.Transactioner created with NewTransactioner, the transaction is returned.
+func (r *repositoryGorm) conn(ctx context.Context) *gorm.DB {
+	if tx := transactionFromContext(ctx); tx != nil {
+		return tx
+	}
+	return r.DB
 }
 
 // setQueryFilters applies the given filters to a sql query.
+// Filters containing an Expression are translated into SQL conditions, any translation error is added to the query.
 func (r *repositoryGorm) setQueryFilters(q *gorm.DB, filters []repository.Filter) *gorm.DB {
 	for _, f := range filters {
-		q = q.Where(f.Template, f.Values...)
+		if f.Expression == nil {
+			q = q.Where(f.Template, f.Values...)
+			continue
+		}
+		c, err := newClauseExpression(f.Expression)
+		if err != nil {
+			_ = q.AddError(err)
+			return q
+		}
+		q = q.Where(c)
 	}
 	return q
 }
@@ -161,3 +516,23 @@ func (r *repositoryGorm) setQueryFilters(q *gorm.DB, filters []repository.Filter
 func (r *repositoryGorm) Model() repository.Model {
 	return reflect.NewInstance(r.entity).(repository.Model)
 }
+
+// NewTypedRepository initializes a new repository.TypedRepository implementation for SQL databases.
+func NewTypedRepository[T repository.Model](db *gorm.DB, opts ...RepositoryOption) repository.TypedRepository[T] {
+	var entity T
+	return repository.NewTypedRepository[T](NewRepository(db, repository.AsModel(&entity), opts...))
+}
+
+// newModelSlice copies entities into a slice of their concrete type, so they can be inserted by Gorm with a single
+// statement. Entities are expected to be pointers, the values created by Gorm are written back into them.
+func newModelSlice(entities []repository.Model) (stdreflect.Value, error) {
+	t := stdreflect.TypeOf(entities[0])
+	values := stdreflect.MakeSlice(stdreflect.SliceOf(t), 0, len(entities))
+	for _, entity := range entities {
+		if stdreflect.TypeOf(entity) != t {
+			return stdreflect.Value{}, fmt.Errorf("%w: expected %s, got %T", repository.ErrInvalidModelType, t, entity)
+		}
+		values = stdreflect.Append(values, stdreflect.ValueOf(entity))
+	}
+	return values, nil
+}
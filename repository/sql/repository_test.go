@@ -2,12 +2,17 @@ package sql
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	utilsgorm "github.com/gazebo-web/gz-go/v10/database/gorm"
 	"github.com/gazebo-web/gz-go/v10/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func TestRepository(t *testing.T) {
@@ -118,6 +123,64 @@ func (suite *RepositoryTestSuite) TestCreateMultiple() {
 	suite.Assert().Equal(int64(6), count)
 }
 
+func (suite *RepositoryTestSuite) TestCreateBulk_Batches() {
+	var statements int
+	db := suite.db.Session(&gorm.Session{CreateBatchSize: 10})
+	suite.Require().NoError(db.Callback().Create().After("gorm:create").Register("test:count_inserts", func(*gorm.DB) {
+		statements++
+	}))
+	defer func() {
+		suite.Require().NoError(db.Callback().Create().Remove("test:count_inserts"))
+	}()
+
+	entities := make([]repository.Model, 25)
+	for i := range entities {
+		entities[i] = &Test{Name: "batch", Value: i}
+	}
+	res, err := NewRepository(db, &Test{}).CreateBulk(context.Background(), entities)
+	suite.Require().NoError(err)
+	suite.Require().Len(res, 25)
+	suite.Assert().Equal(3, statements)
+
+	// Generated primary keys are written back into the entities
+	for _, e := range res {
+		suite.Assert().NotZero(e.GetID())
+	}
+
+	count, err := suite.Repository.Count(context.Background(), repository.NewFilter(repository.Eq("name", "batch")))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(25), count)
+}
+
+func (suite *RepositoryTestSuite) TestCreateBulk_RollsBackOnError() {
+	// The second entry uses a primary key that already exists
+	_, err := suite.Repository.CreateBulk(context.Background(), []repository.Model{
+		&Test{Name: "rollback"},
+		&Test{Model: Model{ID: 1}, Name: "rollback"},
+	})
+	suite.Require().Error(err)
+
+	count, err := suite.Repository.Count(context.Background(), repository.NewFilter(repository.Eq("name", "rollback")))
+	suite.Require().NoError(err)
+	suite.Assert().Zero(count)
+}
+
+func (suite *RepositoryTestSuite) TestCreateBulk_Errors() {
+	// Cancelled contexts don't insert entries
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := suite.Repository.CreateBulk(ctx, []repository.Model{&Test{Name: "cancelled"}})
+	suite.Assert().Error(err)
+
+	count, err := suite.Repository.Count(context.Background(), repository.NewFilter(repository.Eq("name", "cancelled")))
+	suite.Require().NoError(err)
+	suite.Assert().Zero(count)
+
+	// Entities must have the same type
+	_, err = suite.Repository.CreateBulk(context.Background(), []repository.Model{&Test{}, &VersionedTest{}})
+	suite.Assert().ErrorIs(err, repository.ErrInvalidModelType)
+}
+
 func (suite *RepositoryTestSuite) TestFind() {
 	var t []Test
 
@@ -144,6 +207,19 @@ func (suite *RepositoryTestSuite) TestFindOne() {
 	suite.Assert().Equal(1, t.Value)
 }
 
+func (suite *RepositoryTestSuite) TestFindOne_Expression() {
+	var t Test
+
+	suite.Assert().NoError(suite.Repository.FindOne(context.Background(), &t, repository.NewFilter(
+		repository.And(repository.Eq("name", "Test2"), repository.Gte("value", 2)),
+	)))
+	suite.Assert().Equal("Test2", t.Name)
+
+	count, err := suite.Repository.Count(context.Background(), repository.NewFilter(repository.Contains("name", "Test")))
+	suite.Assert().NoError(err)
+	suite.Assert().Equal(uint64(3), count)
+}
+
 func (suite *RepositoryTestSuite) TestUpdate() {
 	filter := repository.Filter{
 		Template: "name = ?",
@@ -180,7 +256,10 @@ func (suite *RepositoryTestSuite) TestDelete() {
 
 	// Finding one should fail, the record no longer exists.
 	var t Test
-	suite.Assert().Error(suite.Repository.FindOne(context.Background(), &t, filter))
+	err := suite.Repository.FindOne(context.Background(), &t, filter)
+	suite.Assert().ErrorIs(err, repository.ErrNotFound)
+	suite.Assert().ErrorIs(err, gorm.ErrRecordNotFound)
+	suite.Assert().ErrorIs(suite.Repository.Last(context.Background(), &t, filter), repository.ErrNotFound)
 
 	// Deleting is idempotent, deleting twice should not return an error.
 	suite.Assert().NoError(suite.Repository.Delete(context.Background(), Where("name = ?", "Test1")))
@@ -208,6 +287,15 @@ func (suite *RepositoryTestSuite) TestFirstOrCreate() {
 
 	suite.Assert().Equal(uint(4), test.ID)
 	suite.Assert().Equal("Test4", test.Name)
+
+	creator := suite.Repository.(repository.FirstOrCreator)
+	created, err := creator.FirstOrCreateAndReport(context.Background(), &Test{Name: "Test4", Value: 4}, repository.NewFilter(repository.Eq("value", 4)))
+	suite.Require().NoError(err)
+	suite.Assert().False(created)
+
+	created, err = creator.FirstOrCreateAndReport(context.Background(), &Test{Name: "Test5", Value: 5}, repository.NewFilter(repository.Eq("value", 5)))
+	suite.Require().NoError(err)
+	suite.Assert().True(created)
 }
 
 func (suite *RepositoryTestSuite) TestLast() {
@@ -244,3 +332,500 @@ func (suite *RepositoryTestSuite) TestCount() {
 	suite.Assert().NoError(err)
 	suite.Assert().Equal(uint64(3), count)
 }
+
+func (suite *RepositoryTestSuite) TestTypedRepository() {
+	typed := NewTypedRepository[Test](suite.db)
+
+	created, err := typed.Create(context.Background(), Test{Name: "Test4", Value: 4})
+	suite.Require().NoError(err)
+	suite.Assert().NotZero(created.ID)
+
+	found, err := typed.Find(context.Background(), Where("value > ?", 2))
+	suite.Require().NoError(err)
+	suite.Assert().Len(found, 2)
+
+	one, err := typed.FindOne(context.Background(), repository.Filter{
+		Template: "name = ?",
+		Values:   []interface{}{"Test4"},
+	})
+	suite.Require().NoError(err)
+	suite.Assert().Equal(created.ID, one.ID)
+}
+
+func (suite *RepositoryTestSuite) TestWithTransaction_Commit() {
+	tr := NewTransactioner(suite.db)
+
+	err := tr.WithTransaction(context.Background(), func(ctx context.Context) error {
+		if _, err := suite.Repository.Create(ctx, &Test{Name: "Test4", Value: 4}); err != nil {
+			return err
+		}
+		return suite.Repository.Update(ctx, map[string]interface{}{"value": 10}, repository.Filter{
+			Template: "name = ?",
+			Values:   []interface{}{"Test1"},
+		})
+	})
+	suite.Require().NoError(err)
+
+	count, err := suite.Repository.Count(context.Background(), repository.NewFilter(repository.In("value", []int{4, 10})))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(2), count)
+}
+
+func (suite *RepositoryTestSuite) TestWithTransaction_RollbackOnError() {
+	tr := NewTransactioner(suite.db)
+
+	expected := errors.New("test error")
+	err := tr.WithTransaction(context.Background(), func(ctx context.Context) error {
+		if _, err := suite.Repository.Create(ctx, &Test{Name: "Test4", Value: 4}); err != nil {
+			return err
+		}
+		return expected
+	})
+	suite.Require().ErrorIs(err, expected)
+
+	count, err := suite.Repository.Count(context.Background())
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(3), count)
+}
+
+func (suite *RepositoryTestSuite) TestWithTransaction_RollbackOnPanic() {
+	tr := NewTransactioner(suite.db)
+
+	suite.Require().Panics(func() {
+		_ = tr.WithTransaction(context.Background(), func(ctx context.Context) error {
+			if err := suite.Repository.Delete(ctx, Where("value > ?", 0)); err != nil {
+				return err
+			}
+			panic("test panic")
+		})
+	})
+
+	count, err := suite.Repository.Count(context.Background())
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(3), count)
+}
+
+func (suite *RepositoryTestSuite) TestWithTransaction_Nested() {
+	tr := NewTransactioner(suite.db)
+
+	err := tr.WithTransaction(context.Background(), func(ctx context.Context) error {
+		if _, err := suite.Repository.Create(ctx, &Test{Name: "Test4", Value: 4}); err != nil {
+			return err
+		}
+		// Rolling back the inner transaction should keep the changes of the outer transaction.
+		_ = tr.WithTransaction(ctx, func(ctx context.Context) error {
+			if _, err := suite.Repository.Create(ctx, &Test{Name: "Test5", Value: 5}); err != nil {
+				return err
+			}
+			return errors.New("test error")
+		})
+		return nil
+	})
+	suite.Require().NoError(err)
+
+	count, err := suite.Repository.Count(context.Background())
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(4), count)
+}
+
+type SoftDeleteTest struct {
+	SoftDeleteModel
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+func (t SoftDeleteTest) TableName() string {
+	return "soft_delete_test"
+}
+
+// setupSoftDelete creates the soft delete test table with three entries, and returns a SoftDeleteRepository for it.
+func (suite *RepositoryTestSuite) setupSoftDelete(opts ...RepositoryOption) SoftDeleteRepository {
+	suite.Require().NoError(suite.db.Migrator().DropTable(&SoftDeleteTest{}))
+	suite.Require().NoError(suite.db.AutoMigrate(&SoftDeleteTest{}))
+	suite.Require().NoError(suite.db.Create([]*SoftDeleteTest{
+		{Name: "Test1", Value: 1},
+		{Name: "Test2", Value: 2},
+		{Name: "Test3", Value: 3},
+	}).Error)
+
+	repo, ok := NewRepository(suite.db, &SoftDeleteTest{}, opts...).(SoftDeleteRepository)
+	suite.Require().True(ok)
+	return repo
+}
+
+func (suite *RepositoryTestSuite) TestDelete_NotSoftDelete() {
+	ctx := context.Background()
+
+	// Models embedding Model are permanently removed
+	suite.Require().NoError(suite.Repository.Delete(ctx, Where("name = ?", "Test1")))
+
+	var count int64
+	suite.Require().NoError(suite.db.Unscoped().Model(&Test{}).Count(&count).Error)
+	suite.Assert().Equal(int64(2), count)
+}
+
+func (suite *RepositoryTestSuite) TestSoftDelete() {
+	repo := suite.setupSoftDelete()
+	ctx := context.Background()
+
+	suite.Require().NoError(repo.Delete(ctx, Where("name = ?", "Test1")))
+
+	// Soft-deleted entries are not returned by default
+	var out []SoftDeleteTest
+	suite.Require().NoError(repo.Find(ctx, &out))
+	suite.Assert().Len(out, 2)
+
+	// WithDeleted includes soft-deleted entries
+	out = nil
+	suite.Require().NoError(repo.Find(ctx, &out, WithDeleted()))
+	suite.Assert().Len(out, 3)
+
+	// OnlyDeleted only returns soft-deleted entries
+	out = nil
+	suite.Require().NoError(repo.Find(ctx, &out, OnlyDeleted()))
+	suite.Require().Len(out, 1)
+	suite.Assert().Equal("Test1", out[0].Name)
+	suite.Assert().True(out[0].DeletedAt.Valid)
+}
+
+func (suite *RepositoryTestSuite) TestRestore() {
+	repo := suite.setupSoftDelete()
+	ctx := context.Background()
+
+	suite.Require().NoError(repo.Delete(ctx, Where("value < ?", 3)))
+
+	suite.Assert().ErrorIs(repo.Restore(ctx), repository.ErrNoFilter)
+	suite.Require().NoError(repo.Restore(ctx, Where("name = ?", "Test1")))
+
+	var out []SoftDeleteTest
+	suite.Require().NoError(repo.Find(ctx, &out))
+	suite.Assert().Len(out, 2)
+
+	out = nil
+	suite.Require().NoError(repo.Find(ctx, &out, OnlyDeleted()))
+	suite.Require().Len(out, 1)
+	suite.Assert().Equal("Test2", out[0].Name)
+}
+
+func (suite *RepositoryTestSuite) TestHardDelete() {
+	repo := suite.setupSoftDelete()
+	ctx := context.Background()
+
+	// Soft-deleted entries can be hard deleted
+	suite.Require().NoError(repo.Delete(ctx, Where("name = ?", "Test1")))
+	suite.Require().NoError(repo.HardDelete(ctx, Where("name IN (?)", []string{"Test1", "Test2"})))
+
+	var out []SoftDeleteTest
+	suite.Require().NoError(repo.Find(ctx, &out, WithDeleted()))
+	suite.Require().Len(out, 1)
+	suite.Assert().Equal("Test3", out[0].Name)
+
+	// Hard deleted entries cannot be restored
+	suite.Require().NoError(repo.Restore(ctx, Where("name = ?", "Test1")))
+	count, err := repo.Count(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(1), count)
+}
+
+type VersionedTest struct {
+	Model
+	Name    string `json:"name"`
+	Version uint   `json:"version" gorm:"not null;default:1"`
+}
+
+func (t VersionedTest) TableName() string {
+	return "versioned_test"
+}
+
+func (suite *RepositoryTestSuite) TestUpdateIf_Version() {
+	suite.Require().NoError(suite.db.Migrator().DropTable(&VersionedTest{}))
+	suite.Require().NoError(suite.db.AutoMigrate(&VersionedTest{}))
+	defer func() {
+		suite.Require().NoError(suite.db.Migrator().DropTable(&VersionedTest{}))
+	}()
+
+	repo := NewRepository(suite.db, &VersionedTest{})
+	ctx := context.Background()
+	_, err := repo.Create(ctx, &VersionedTest{Name: "Test1"})
+	suite.Require().NoError(err)
+	filter := repository.NewFilter(repository.Eq("name", "Test1"))
+
+	var read VersionedTest
+	suite.Require().NoError(repo.FindOne(ctx, &read, filter))
+	suite.Require().Equal(uint(1), read.Version)
+
+	// Updating with the current version increments the version
+	data := read
+	data.Name = "Test2"
+	suite.Require().NoError(repo.(repository.ConditionalUpdater).UpdateIf(ctx, &data, repository.IfVersion(1), filter))
+	suite.Assert().Equal(uint(2), data.Version)
+
+	var updated VersionedTest
+	suite.Require().NoError(repo.FindOne(ctx, &updated, repository.NewFilter(repository.Eq("name", "Test2"))))
+	suite.Assert().Equal(uint(2), updated.Version)
+
+	// Updating with a stale version returns a conflict
+	err = repo.(repository.ConditionalUpdater).UpdateIf(ctx, map[string]interface{}{"name": "Test3"},
+		repository.IfVersion(1), repository.NewFilter(repository.Eq("name", "Test2")))
+	suite.Assert().ErrorIs(err, repository.ErrConflict)
+
+	// The version of data is not changed when the update fails
+	stale := read
+	stale.Name = "Test3"
+	err = repo.(repository.ConditionalUpdater).UpdateIf(ctx, &stale, repository.IfVersion(1),
+		repository.NewFilter(repository.Eq("name", "Test2")))
+	suite.Assert().ErrorIs(err, repository.ErrConflict)
+	suite.Assert().Equal(uint(1), stale.Version)
+
+	// Filters are required
+	suite.Assert().ErrorIs(repo.(repository.ConditionalUpdater).UpdateIf(ctx, map[string]interface{}{"name": "Test3"},
+		repository.IfVersion(2)), repository.ErrNoFilter)
+
+	count, err := repo.Count(ctx, repository.NewFilter(repository.Eq("name", "Test3")))
+	suite.Require().NoError(err)
+	suite.Assert().Zero(count)
+
+	// Updating entries that don't exist does nothing
+	suite.Assert().NoError(repo.(repository.ConditionalUpdater).UpdateIf(ctx, map[string]interface{}{"name": "Test3"},
+		repository.IfVersion(2), filter))
+}
+
+func (suite *RepositoryTestSuite) TestUpdateIf_UpdatedAt() {
+	updater := suite.Repository.(repository.ConditionalUpdater)
+	ctx := context.Background()
+
+	var read Test
+	suite.Require().NoError(suite.Repository.FindOne(ctx, &read, repository.NewFilter(repository.Eq("name", "Test1"))))
+
+	// Updating with the last update time succeeds
+	suite.Require().NoError(updater.UpdateIf(ctx, map[string]interface{}{"value": 10},
+		repository.IfUpdatedAt(read.UpdatedAt), repository.NewFilter(repository.Eq("name", "Test1"))))
+
+	// The entry was updated after it was read, updating it again with the same precondition returns a conflict
+	err := updater.UpdateIf(ctx, map[string]interface{}{"value": 20},
+		repository.IfUpdatedAt(read.UpdatedAt), repository.NewFilter(repository.Eq("name", "Test1")))
+	suite.Assert().ErrorIs(err, repository.ErrConflict)
+
+	var updated Test
+	suite.Require().NoError(suite.Repository.FindOne(ctx, &updated, repository.NewFilter(repository.Eq("name", "Test1"))))
+	suite.Assert().Equal(10, updated.Value)
+
+	// Preconditions must be met by every matching entry
+	err = updater.UpdateIf(ctx, map[string]interface{}{"value": 30},
+		repository.IfUpdatedAt(updated.UpdatedAt), repository.NewFilter(repository.In("name", []string{"Test1", "Test2"})))
+	suite.Assert().ErrorIs(err, repository.ErrConflict)
+
+	count, err := suite.Repository.Count(ctx, repository.NewFilter(repository.Eq("value", 30)))
+	suite.Require().NoError(err)
+	suite.Assert().Zero(count)
+}
+
+type UniqueTest struct {
+	Model
+	Code  string `json:"code" gorm:"uniqueIndex;size:64"`
+	Value int    `json:"value"`
+}
+
+func (t UniqueTest) TableName() string {
+	return "unique_test"
+}
+
+func (suite *RepositoryTestSuite) TestUpsert_PrimaryKey() {
+	ctx := context.Background()
+
+	var existing Test
+	suite.Require().NoError(suite.Repository.FindOne(ctx, &existing, repository.NewFilter(repository.Eq("name", "Test1"))))
+
+	// Conflicting entries are updated
+	entity := &Test{Model: Model{ID: existing.ID}, Name: "Test1", Value: 100}
+	suite.Require().NoError(suite.Repository.Upsert(ctx, entity))
+
+	var updated Test
+	suite.Require().NoError(suite.Repository.FindOne(ctx, &updated, repository.NewFilter(repository.Eq("name", "Test1"))))
+	suite.Assert().Equal(existing.ID, updated.ID)
+	suite.Assert().Equal(100, updated.Value)
+
+	// New entries are inserted
+	suite.Require().NoError(suite.Repository.Upsert(ctx, &Test{Name: "Test4", Value: 4}))
+	count, err := suite.Repository.Count(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(4), count)
+}
+
+func (suite *RepositoryTestSuite) TestUpsert_ConflictFields() {
+	suite.Require().NoError(suite.db.Migrator().DropTable(&UniqueTest{}))
+	suite.Require().NoError(suite.db.AutoMigrate(&UniqueTest{}))
+	defer func() {
+		suite.Require().NoError(suite.db.Migrator().DropTable(&UniqueTest{}))
+	}()
+
+	repo := NewRepository(suite.db, &UniqueTest{})
+	ctx := context.Background()
+
+	first := &UniqueTest{Code: "a", Value: 1}
+	suite.Require().NoError(repo.Upsert(ctx, first, "code"))
+	suite.Require().NotZero(first.ID)
+
+	// Upserting an entry with the same code updates the existing entry
+	second := &UniqueTest{Code: "a", Value: 2}
+	suite.Require().NoError(repo.Upsert(ctx, second, "code"))
+	suite.Assert().Equal(first.ID, second.ID)
+	suite.Assert().Equal(2, second.Value)
+
+	count, err := repo.Count(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(1), count)
+
+	var stored UniqueTest
+	suite.Require().NoError(repo.FindOne(ctx, &stored, repository.NewFilter(repository.Eq("code", "a"))))
+	suite.Assert().Equal(2, stored.Value)
+}
+
+func (suite *RepositoryTestSuite) TestUpsert_SoftDeleted() {
+	repo := suite.setupSoftDelete()
+	ctx := context.Background()
+
+	var existing SoftDeleteTest
+	suite.Require().NoError(repo.FindOne(ctx, &existing, repository.NewFilter(repository.Eq("name", "Test1"))))
+	suite.Require().NoError(repo.Delete(ctx, Where("name = ?", "Test1")))
+
+	// Soft-deleted entries are updated but not restored
+	entity := &SoftDeleteTest{SoftDeleteModel: SoftDeleteModel{Model: Model{ID: existing.ID}}, Name: "Test1", Value: 100}
+	suite.Require().NoError(repo.Upsert(ctx, entity))
+
+	var out []SoftDeleteTest
+	suite.Require().NoError(repo.Find(ctx, &out, OnlyDeleted()))
+	suite.Require().Len(out, 1)
+	suite.Assert().Equal(existing.ID, out[0].ID)
+	suite.Assert().Equal(100, out[0].Value)
+}
+
+func (suite *RepositoryTestSuite) TestAggregations() {
+	ctx := context.Background()
+	_, err := suite.Repository.Create(ctx, &Test{Name: "Test4", Value: 1})
+	suite.Require().NoError(err)
+
+	aggregator, ok := suite.Repository.(Aggregator)
+	suite.Require().True(ok)
+
+	sum, err := aggregator.Sum(ctx, "value")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(float64(7), sum)
+
+	avg, err := aggregator.Avg(ctx, "value", repository.NewFilter(repository.Gt("value", 1)))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(2.5, avg)
+
+	var minValue, maxValue int
+	suite.Require().NoError(aggregator.Min(ctx, &minValue, "value"))
+	suite.Assert().Equal(1, minValue)
+	suite.Require().NoError(aggregator.Max(ctx, &maxValue, "value", repository.NewFilter(repository.Lt("value", 3))))
+	suite.Assert().Equal(2, maxValue)
+
+	var counts map[int]uint64
+	suite.Require().NoError(aggregator.CountBy(ctx, &counts, "value"))
+	suite.Assert().Equal(map[int]uint64{1: 2, 2: 1, 3: 1}, counts)
+
+	var names map[string]uint64
+	suite.Require().NoError(aggregator.CountBy(ctx, &names, "name", repository.NewFilter(repository.Eq("value", 1))))
+	suite.Assert().Equal(map[string]uint64{"Test1": 1, "Test4": 1}, names)
+
+	// No entries match
+	filter := repository.NewFilter(repository.Gt("value", 10))
+	sum, err = aggregator.Sum(ctx, "value", filter)
+	suite.Require().NoError(err)
+	suite.Assert().Zero(sum)
+	_, err = aggregator.Avg(ctx, "value", filter)
+	suite.Assert().ErrorIs(err, repository.ErrNotFound)
+	suite.Assert().ErrorIs(aggregator.Min(ctx, &minValue, "value", filter), repository.ErrNotFound)
+	suite.Require().NoError(aggregator.CountBy(ctx, &counts, "value", filter))
+	suite.Assert().Empty(counts)
+
+	suite.Assert().Error(aggregator.CountBy(ctx, counts, "value"))
+}
+
+func (suite *RepositoryTestSuite) TestIterate() {
+	ctx := context.Background()
+	iterator, ok := suite.Repository.(repository.Iterator)
+	suite.Require().True(ok)
+
+	var names []string
+	err := iterator.Iterate(ctx, func(entry repository.Model) error {
+		test, ok := entry.(*Test)
+		suite.Require().True(ok)
+		names = append(names, test.Name)
+		return nil
+	}, OrderBy(Descending("value")), Where("value > ?", 1))
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{"Test3", "Test2"}, names)
+
+	// Iteration stops when fn returns an error
+	errStop := errors.New("stop")
+	var count int
+	err = iterator.Iterate(ctx, func(entry repository.Model) error {
+		count++
+		return errStop
+	})
+	suite.Assert().ErrorIs(err, errStop)
+	suite.Assert().Equal(1, count)
+}
+
+func (suite *RepositoryTestSuite) TestAffectedCount() {
+	ctx := context.Background()
+	counter, ok := suite.Repository.(repository.AffectedCounter)
+	suite.Require().True(ok)
+
+	updated, err := counter.UpdateAndCount(ctx, map[string]interface{}{"value": 10}, repository.NewFilter(repository.Lt("value", 3)))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(2), updated)
+
+	updated, err = counter.UpdateAndCount(ctx, map[string]interface{}{"value": 10}, repository.NewFilter(repository.Eq("name", "Invalid")))
+	suite.Require().NoError(err)
+	suite.Assert().Zero(updated)
+
+	deleted, err := counter.DeleteAndCount(ctx, WhereExpression(repository.Eq("value", 10)))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(2), deleted)
+
+	// Deleting is idempotent, deleting twice doesn't delete any entries.
+	deleted, err = counter.DeleteAndCount(ctx, WhereExpression(repository.Eq("value", 10)))
+	suite.Require().NoError(err)
+	suite.Assert().Zero(deleted)
+}
+
+func (suite *RepositoryTestSuite) TestStrict() {
+	ctx := context.Background()
+	repo := suite.setupSoftDelete(Strict())
+	missing := repository.NewFilter(repository.Eq("name", "Invalid"))
+
+	suite.Assert().ErrorIs(repo.Update(ctx, map[string]interface{}{"value": 10}, missing), repository.ErrNoEntriesUpdated)
+	suite.Assert().ErrorIs(repo.(repository.ConditionalUpdater).UpdateIf(ctx, map[string]interface{}{"value": 10}, repository.IfUpdatedAt(time.Now()), missing), repository.ErrNoEntriesUpdated)
+	suite.Assert().ErrorIs(repo.Delete(ctx, WhereExpression(missing.Expression)), repository.ErrNoEntriesDeleted)
+	suite.Assert().ErrorIs(repo.HardDelete(ctx, WhereExpression(missing.Expression)), repository.ErrNoEntriesDeleted)
+	suite.Assert().ErrorIs(repo.Restore(ctx, WhereExpression(missing.Expression)), repository.ErrNoEntriesUpdated)
+
+	existing := repository.NewFilter(repository.Eq("name", "Test1"))
+	suite.Assert().NoError(repo.Update(ctx, map[string]interface{}{"value": 10}, existing))
+	suite.Assert().NoError(repo.Delete(ctx, WhereExpression(existing.Expression)))
+	suite.Assert().ErrorIs(repo.Delete(ctx, WhereExpression(existing.Expression)), repository.ErrNoEntriesDeleted)
+	suite.Assert().NoError(repo.Restore(ctx, WhereExpression(existing.Expression)))
+	suite.Assert().ErrorIs(repo.Restore(ctx, WhereExpression(existing.Expression)), repository.ErrNoEntriesUpdated)
+}
+
+func TestUpsert_Query(t *testing.T) {
+	db := newDryRunDB(t)
+	r := &repositoryGorm{DB: db, entity: &SoftDeleteTest{}}
+
+	onConflict, err := r.newOnConflictClause(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []clause.Column{{Name: "id"}}, onConflict.Columns)
+
+	q := db.Session(&gorm.Session{SkipDefaultTransaction: true}).Clauses(onConflict).Create(&SoftDeleteTest{Name: "Test1", Value: 1})
+	require.NoError(t, q.Error)
+	assert.Contains(t, q.Statement.SQL.String(), "ON DUPLICATE KEY UPDATE `updated_at`=VALUES(`updated_at`),`name`=VALUES(`name`),`value`=VALUES(`value`)")
+	assert.NotContains(t, q.Statement.SQL.String(), "`deleted_at`=")
+
+	onConflict, err = r.newOnConflictClause([]string{"name"})
+	require.NoError(t, err)
+	assert.Equal(t, []clause.Column{{Name: "name"}}, onConflict.Columns)
+}
@@ -0,0 +1,165 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gazebo-web/gz-go/v10"
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNoSearchFields is returned when a search is performed without any fields to search in.
+var ErrNoSearchFields = errors.New("no search fields provided")
+
+// Search filters results to entries where any of the given fields contain term.
+//
+// On MySQL, if the model table has a FULLTEXT index that covers exactly the given fields, the search is performed
+// with MATCH ... AGAINST in natural language mode, and it's subject to the full-text search rules of the database,
+// such as the minimum word length and stopwords. The FULLTEXT indexes of each table are read from the information
+// schema the first time the table is searched, and they are cached for the lifetime of the process: indexes created
+// or dropped afterwards are not detected until the process is restarted.
+//
+// Otherwise, including on other databases such as PostgreSQL and SQLite, fields are matched using LIKE, and wildcard
+// characters in term are escaped.
+//
+// An empty term matches all entries. Multiple Search options can be passed to a single Repository operation. They are
+// logically ANDed together.
+func Search(fields []string, term string) repository.Option {
+	return Option(func(q *gorm.DB) {
+		if len(fields) == 0 {
+			_ = q.AddError(ErrNoSearchFields)
+			return
+		}
+		if len(term) == 0 {
+			return
+		}
+		fulltext, err := hasFullTextIndex(q, fields)
+		if err != nil {
+			_ = q.AddError(err)
+			return
+		}
+		*q = *q.Where(newSearchClause(fields, term, fulltext))
+	})
+}
+
+// PrefixMatch filters results to entries where field starts with term. Wildcard characters in term are escaped, so
+// the match can make use of regular indexes on field.
+// Multiple PrefixMatch options can be passed to a single Repository operation. They are logically ANDed together.
+func PrefixMatch(field string, term string) repository.Option {
+	return Option(func(q *gorm.DB) {
+		*q = *q.Where(newLikeClause(field, escapeLike(term)+"%"))
+	})
+}
+
+// newSearchClause returns a condition that matches entries where any of the given fields contain term.
+// If fulltext is true, the condition is a MySQL full-text search, and it requires a FULLTEXT index on fields.
+func newSearchClause(fields []string, term string, fulltext bool) clause.Expression {
+	if fulltext {
+		vars := make([]interface{}, 0, len(fields)+1)
+		for _, f := range fields {
+			vars = append(vars, clause.Column{Name: f})
+		}
+		return clause.Expr{
+			SQL:  fmt.Sprintf("MATCH (%s) AGAINST (? IN NATURAL LANGUAGE MODE)", strings.TrimSuffix(strings.Repeat("?,", len(fields)), ",")),
+			Vars: append(vars, term),
+		}
+	}
+	pattern := "%" + escapeLike(term) + "%"
+	exprs := make([]clause.Expression, len(fields))
+	for i, f := range fields {
+		exprs[i] = newLikeClause(f, pattern)
+	}
+	if len(exprs) == 1 {
+		return exprs[0]
+	}
+	return clause.Or(exprs...)
+}
+
+// newLikeClause returns a condition that matches entries where field matches the given LIKE pattern. Wildcards in
+// pattern must be escaped using likeEscapeCharacter.
+func newLikeClause(field string, pattern string) clause.Expression {
+	return clause.Expr{
+		SQL:  fmt.Sprintf("? LIKE ? ESCAPE '%s'", likeEscapeCharacter),
+		Vars: []interface{}{clause.Column{Name: field}, pattern},
+	}
+}
+
+// fullTextIndexColumn is a column of a FULLTEXT index, as listed by the MySQL information schema.
+type fullTextIndexColumn struct {
+	IndexName  string
+	ColumnName string
+}
+
+// fullTextIndexKey identifies the table of a database in fullTextIndexes.
+type fullTextIndexKey struct {
+	config *gorm.Config
+	table  string
+}
+
+// fullTextIndexes caches the FULLTEXT indexes of each table, keyed by fullTextIndexKey. Each index is stored as the
+// sorted, comma-separated list of its lowercase column names.
+var fullTextIndexes sync.Map
+
+// hasFullTextIndex returns true if q is a MySQL query and its model table has a FULLTEXT index that covers exactly the
+// given fields.
+func hasFullTextIndex(q *gorm.DB, fields []string) (bool, error) {
+	if q.Dialector.Name() != gz.DriverMySQL {
+		return false, nil
+	}
+	if q.Statement.Table == "" {
+		if err := q.Statement.Parse(q.Statement.Model); err != nil {
+			return false, err
+		}
+	}
+
+	indexes, err := getFullTextIndexes(q, q.Statement.Table)
+	if err != nil {
+		return false, err
+	}
+	expected := make([]string, len(fields))
+	for i, f := range fields {
+		expected[i] = strings.ToLower(f)
+	}
+	sort.Strings(expected)
+	for _, index := range indexes {
+		if index == strings.Join(expected, ",") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getFullTextIndexes returns the FULLTEXT indexes of the given table. Indexes are read from the information schema
+// the first time a table is requested, and cached afterwards. Errors are not cached.
+func getFullTextIndexes(q *gorm.DB, table string) ([]string, error) {
+	key := fullTextIndexKey{config: q.Config, table: table}
+	if indexes, ok := fullTextIndexes.Load(key); ok {
+		return indexes.([]string), nil
+	}
+
+	var columns []fullTextIndexColumn
+	err := q.Session(&gorm.Session{NewDB: true}).
+		Raw("SELECT index_name AS index_name, column_name AS column_name FROM information_schema.statistics "+
+			"WHERE table_schema = DATABASE() AND table_name = ? AND index_type = 'FULLTEXT'", table).
+		Find(&columns).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string][]string)
+	for _, c := range columns {
+		byName[c.IndexName] = append(byName[c.IndexName], strings.ToLower(c.ColumnName))
+	}
+	indexes := make([]string, 0, len(byName))
+	for _, index := range byName {
+		sort.Strings(index)
+		indexes = append(indexes, strings.Join(index, ","))
+	}
+	fullTextIndexes.Store(key, indexes)
+	return indexes, nil
+}
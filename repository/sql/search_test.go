@@ -0,0 +1,144 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// otherDialector is a MySQL dialector that reports a different database name and quotes identifiers with double
+// quotes, as done by PostgreSQL and SQLite. It's used to build queries for other databases without their drivers.
+type otherDialector struct {
+	gorm.Dialector
+	name string
+}
+
+func (d otherDialector) Name() string {
+	return d.name
+}
+
+func (d otherDialector) QuoteTo(writer clause.Writer, str string) {
+	_ = writer.WriteByte('"')
+	_, _ = writer.WriteString(str)
+	_ = writer.WriteByte('"')
+}
+
+func TestNewSearchClause(t *testing.T) {
+	db := newDryRunDB(t)
+
+	cases := []struct {
+		name     string
+		expr     clause.Expression
+		expected string
+		vars     []interface{}
+	}{
+		{
+			name:     "like single field",
+			expr:     newSearchClause([]string{"name"}, "test", false),
+			expected: "WHERE `name` LIKE ? ESCAPE '!'",
+			vars:     []interface{}{"%test%"},
+		},
+		{
+			name:     "like multiple fields",
+			expr:     newSearchClause([]string{"name", "description"}, "test", false),
+			expected: "WHERE (`name` LIKE ? ESCAPE '!' OR `description` LIKE ? ESCAPE '!')",
+			vars:     []interface{}{"%test%", "%test%"},
+		},
+		{
+			name:     "like escapes wildcards",
+			expr:     newSearchClause([]string{"name"}, "50%_off!", false),
+			expected: "WHERE `name` LIKE ? ESCAPE '!'",
+			vars:     []interface{}{"%50!%!_off!!%"},
+		},
+		{
+			name:     "fulltext",
+			expr:     newSearchClause([]string{"name", "description"}, "50% off", true),
+			expected: "WHERE MATCH (`name`,`description`) AGAINST (? IN NATURAL LANGUAGE MODE)",
+			vars:     []interface{}{"50% off"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var out []Test
+			stmt := db.Model(&Test{}).Where(c.expr).Find(&out).Statement
+			assert.Contains(t, stmt.SQL.String(), c.expected)
+			assert.Equal(t, c.vars, stmt.Vars)
+		})
+	}
+}
+
+func TestSearch_Query(t *testing.T) {
+	db := newDryRunDB(t)
+
+	// Dry runs don't find any FULLTEXT index, so searches fall back to LIKE.
+	q := db.Model(&Test{})
+	Search([]string{"name"}, "te_st").(Option)(q)
+	PrefixMatch("name", "10%").(Option)(q)
+	var out []Test
+	stmt := q.Find(&out).Statement
+	assert.NoError(t, q.Error)
+	assert.Contains(t, stmt.SQL.String(), "WHERE `name` LIKE ? ESCAPE '!' AND `name` LIKE ? ESCAPE '!'")
+	assert.Equal(t, []interface{}{"%te!_st%", "10!%%"}, stmt.Vars)
+
+	// Empty terms match all entries
+	q = db.Model(&Test{})
+	Search([]string{"name"}, "").(Option)(q)
+	stmt = q.Find(&out).Statement
+	assert.NotContains(t, stmt.SQL.String(), "LIKE")
+
+	q = db.Model(&Test{})
+	Search(nil, "test").(Option)(q)
+	assert.ErrorIs(t, q.Error, ErrNoSearchFields)
+}
+
+func TestSearch_OtherDialects(t *testing.T) {
+	for _, name := range []string{"postgres", "sqlite"} {
+		t.Run(name, func(t *testing.T) {
+			db, err := gorm.Open(otherDialector{
+				Dialector: mysql.New(mysql.Config{SkipInitializeWithVersion: true}),
+				name:      name,
+			}, &gorm.Config{
+				DryRun:               true,
+				DisableAutomaticPing: true,
+			})
+			require.NoError(t, err)
+
+			q := db.Model(&Test{})
+			Search([]string{"name", "description"}, "50%_off!").(Option)(q)
+			var out []Test
+			stmt := q.Find(&out).Statement
+			assert.NoError(t, q.Error)
+			assert.Contains(t, stmt.SQL.String(), `WHERE ("name" LIKE ? ESCAPE '!' OR "description" LIKE ? ESCAPE '!')`)
+			assert.Equal(t, []interface{}{"%50!%!_off!!%", "%50!%!_off!!%"}, stmt.Vars)
+
+			// The information schema is only read on MySQL
+			_, ok := fullTextIndexes.Load(fullTextIndexKey{config: db.Config, table: "test"})
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestSearch_CachedFullTextIndexes(t *testing.T) {
+	db := newDryRunDB(t)
+
+	// Searching a table caches its indexes, dry runs don't find any.
+	q := db.Model(&Test{})
+	Search([]string{"name"}, "test").(Option)(q)
+	indexes, ok := fullTextIndexes.Load(fullTextIndexKey{config: db.Config, table: "test"})
+	assert.True(t, ok)
+	assert.Empty(t, indexes)
+
+	// Cached indexes are used instead of querying the information schema.
+	fullTextIndexes.Store(fullTextIndexKey{config: db.Config, table: "test"}, []string{"description,name"})
+	q = db.Model(&Test{})
+	Search([]string{"Name", "description"}, "test").(Option)(q)
+	var out []Test
+	stmt := q.Find(&out).Statement
+	assert.NoError(t, q.Error)
+	assert.Contains(t, stmt.SQL.String(), "WHERE MATCH (`Name`,`description`) AGAINST (? IN NATURAL LANGUAGE MODE)")
+}
@@ -0,0 +1,48 @@
+package sql
+
+import (
+	"context"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"gorm.io/gorm"
+)
+
+// transactionKey is the context key used to store a Gorm transaction.
+type transactionKey struct{}
+
+// NewTransactioner initializes a new repository.Transactioner implementation for SQL databases.
+// Repositories created with NewRepository use the transaction carried by the context passed to their operations.
+// Nested transactions are supported using save points.
+func NewTransactioner(db *gorm.DB) repository.Transactioner {
+	return &transactionerGorm{
+		DB: db,
+	}
+}
+
+// transactionerGorm implements repository.Transactioner using Gorm transactions.
+type transactionerGorm struct {
+	DB *gorm.DB
+}
+
+// WithTransaction runs fn inside a transaction.
+func (t *transactionerGorm) WithTransaction(ctx context.Context, fn repository.TransactionFunc) error {
+	db := t.DB
+	if tx := transactionFromContext(ctx); tx != nil {
+		db = tx
+	}
+	txCtx := repository.ContextWithTransaction(ctx)
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(txCtx, transactionKey{}, tx))
+	})
+	if err != nil {
+		return err
+	}
+	repository.Committed(txCtx)
+	return nil
+}
+
+// transactionFromContext returns the transaction stored in ctx. It returns nil if ctx doesn't contain a transaction.
+func transactionFromContext(ctx context.Context) *gorm.DB {
+	tx, _ := ctx.Value(transactionKey{}).(*gorm.DB)
+	return tx
+}
@@ -0,0 +1,529 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// ErrNoTenant is returned when an operation is performed without a tenant in the context.
+	ErrNoTenant = errors.New("no tenant found in context")
+	// ErrUnscopedOperation is returned when an operation could access or modify entries of other tenants.
+	ErrUnscopedOperation = errors.New("operation cannot be scoped to a tenant")
+)
+
+// Model is a repository.Model that belongs to a tenant.
+type Model interface {
+	repository.Model
+	// SetTenant sets the tenant the model belongs to.
+	SetTenant(tenant string)
+}
+
+// tenantKey is the context key used to store the current tenant.
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx that carries the given tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// FromContext returns the tenant carried by ctx. It returns ErrNoTenant if ctx doesn't carry a tenant.
+func FromContext(ctx context.Context) (string, error) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	if !ok || len(tenant) == 0 {
+		return "", ErrNoTenant
+	}
+	return tenant, nil
+}
+
+// Func returns the tenant of an operation from the operation context.
+type Func func(ctx context.Context) (string, error)
+
+// Option contains logic that can be passed to NewRepository to modify the scoped Repository.
+type Option func(r *tenantRepository)
+
+// WithTenantFunc sets the function used to get the tenant of an operation.
+// By default, the tenant is read with FromContext.
+func WithTenantFunc(fn Func) Option {
+	return func(r *tenantRepository) {
+		r.tenant = fn
+	}
+}
+
+// NewRepository initializes a new repository.Repository that scopes every operation of the given Repository to the
+// tenant found in the operation context. Operations return ErrNoTenant if the context doesn't carry a tenant.
+//
+// field is the name of the field that contains the tenant of each entry. Read, update and delete operations only
+// match entries where field is equal to the tenant, and created entries have their tenant set with Model.SetTenant.
+// Entries that don't implement Model cannot be created.
+//
+// where converts a repository.Expression into an Option of the given Repository, and it's used to scope operations
+// that receive options instead of filters. Pass the WhereExpression Option of the Repository implementation, for
+// example sql.WhereExpression.
+//
+// Update data cannot modify field, otherwise ErrUnscopedOperation is returned. Map keys are matched against the Go
+// name, column name and json and firestore tag names of field without considering case, and struct data cannot
+// contain a non-zero value for field.
+//
+// Upsert requires field to be one of its conflict fields, otherwise ErrUnscopedOperation is returned. The primary key
+// of the upserted entity is reset before the operation, so that conflicts are only resolved using conflict fields.
+// Some databases, such as MySQL, ignore conflict fields and resolve conflicts on any unique index, so Upsert also
+// returns ErrUnscopedOperation if the model has a unique index or unique field defined with Gorm tags that doesn't
+// include field.
+//
+// The returned Repository also implements repository.ConditionalUpdater, repository.Iterator,
+// repository.AffectedCounter, repository.Aggregator and repository.FirstOrCreator. These operations return
+// repository.ErrUnsupportedOperation if the given Repository does not implement them.
+func NewRepository(repo repository.Repository, field string, where func(expr repository.Expression) repository.Option, opts ...Option) repository.Repository {
+	r := &tenantRepository{
+		Repository: repo,
+		field:      field,
+		where:      where,
+		tenant:     FromContext,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// tenantRepository implements repository.Repository by wrapping a Repository.
+type tenantRepository struct {
+	repository.Repository
+	field  string
+	where  func(expr repository.Expression) repository.Option
+	tenant Func
+	// schemas caches the parsed schemas of models and update data.
+	schemas sync.Map
+}
+
+// Ensure that tenantRepository implements the repository.ConditionalUpdater interface.
+var _ repository.ConditionalUpdater = (*tenantRepository)(nil)
+
+// Ensure that tenantRepository implements the repository.Iterator interface.
+var _ repository.Iterator = (*tenantRepository)(nil)
+
+// Ensure that tenantRepository implements the repository.AffectedCounter interface.
+var _ repository.AffectedCounter = (*tenantRepository)(nil)
+
+// Ensure that tenantRepository implements the repository.Aggregator interface.
+var _ repository.Aggregator = (*tenantRepository)(nil)
+
+// Ensure that tenantRepository implements the repository.FirstOrCreator interface.
+var _ repository.FirstOrCreator = (*tenantRepository)(nil)
+
+// FirstOrCreate inserts a new entry if the given filters don't find any existing record of the current tenant.
+func (r *tenantRepository) FirstOrCreate(ctx context.Context, entity repository.Model, filters ...repository.Filter) error {
+	filters, err := r.filters(ctx, filters)
+	if err != nil {
+		return err
+	}
+	if err := r.stamp(ctx, entity); err != nil {
+		return err
+	}
+	return r.Repository.FirstOrCreate(ctx, entity, filters...)
+}
+
+// FirstOrCreateAndReport inserts a new entry if the given filters don't find any existing record of the current tenant,
+// and returns true if the entry was created.
+func (r *tenantRepository) FirstOrCreateAndReport(ctx context.Context, entity repository.Model, filters ...repository.Filter) (bool, error) {
+	creator, ok := r.Repository.(repository.FirstOrCreator)
+	if !ok {
+		return false, repository.ErrUnsupportedOperation
+	}
+	filters, err := r.filters(ctx, filters)
+	if err != nil {
+		return false, err
+	}
+	if err := r.stamp(ctx, entity); err != nil {
+		return false, err
+	}
+	return creator.FirstOrCreateAndReport(ctx, entity, filters...)
+}
+
+// Create inserts a single entry that belongs to the current tenant.
+func (r *tenantRepository) Create(ctx context.Context, entity repository.Model) (repository.Model, error) {
+	if err := r.stamp(ctx, entity); err != nil {
+		return nil, err
+	}
+	return r.Repository.Create(ctx, entity)
+}
+
+// CreateBulk creates multiple entries that belong to the current tenant with a single operation.
+func (r *tenantRepository) CreateBulk(ctx context.Context, entities []repository.Model) ([]repository.Model, error) {
+	for _, entity := range entities {
+		if err := r.stamp(ctx, entity); err != nil {
+			return nil, err
+		}
+	}
+	return r.Repository.CreateBulk(ctx, entities)
+}
+
+// Upsert inserts a single entry that belongs to the current tenant, or updates the existing entry of the current
+// tenant that conflicts with it. conflictFields must contain the tenant field.
+func (r *tenantRepository) Upsert(ctx context.Context, entity repository.Model, conflictFields ...string) error {
+	if !r.hasTenantField(conflictFields) {
+		return fmt.Errorf("%w: upsert conflict fields must contain %s", ErrUnscopedOperation, r.field)
+	}
+	if err := r.checkUniqueIndexes(entity); err != nil {
+		return err
+	}
+	if err := r.stamp(ctx, entity); err != nil {
+		return err
+	}
+	if err := r.resetPrimaryKey(entity); err != nil {
+		return err
+	}
+	return r.Repository.Upsert(ctx, entity, conflictFields...)
+}
+
+// Find filters entries of the current tenant and stores filtered entries in output.
+func (r *tenantRepository) Find(ctx context.Context, output interface{}, options ...repository.Option) error {
+	options, err := r.options(ctx, options)
+	if err != nil {
+		return err
+	}
+	return r.Repository.Find(ctx, output, options...)
+}
+
+// FindOne filters entries of the current tenant and stores the first filtered entry in output.
+func (r *tenantRepository) FindOne(ctx context.Context, output repository.Model, filters ...repository.Filter) error {
+	filters, err := r.filters(ctx, filters)
+	if err != nil {
+		return err
+	}
+	return r.Repository.FindOne(ctx, output, filters...)
+}
+
+// Last gets the last record of the current tenant ordered by primary key desc.
+func (r *tenantRepository) Last(ctx context.Context, output repository.Model, filters ...repository.Filter) error {
+	filters, err := r.filters(ctx, filters)
+	if err != nil {
+		return err
+	}
+	return r.Repository.Last(ctx, output, filters...)
+}
+
+// Update updates all the entries of the current tenant that match the provided filters with the given data.
+func (r *tenantRepository) Update(ctx context.Context, data interface{}, filters ...repository.Filter) error {
+	filters, err := r.updateFilters(ctx, data, filters)
+	if err != nil {
+		return err
+	}
+	return r.Repository.Update(ctx, data, filters...)
+}
+
+// Delete removes all the entries of the current tenant that match filters.
+func (r *tenantRepository) Delete(ctx context.Context, opts ...repository.Option) error {
+	opts, err := r.options(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return r.Repository.Delete(ctx, opts...)
+}
+
+// Count counts all the entries of the current tenant that match filters.
+func (r *tenantRepository) Count(ctx context.Context, filters ...repository.Filter) (uint64, error) {
+	filters, err := r.filters(ctx, filters)
+	if err != nil {
+		return 0, err
+	}
+	return r.Repository.Count(ctx, filters...)
+}
+
+// UpdateIf updates all the entries of the current tenant that match filters with data, only if every one of them
+// meets precondition.
+func (r *tenantRepository) UpdateIf(ctx context.Context, data interface{}, precondition repository.Precondition, filters ...repository.Filter) error {
+	updater, ok := r.Repository.(repository.ConditionalUpdater)
+	if !ok {
+		return repository.ErrUnsupportedOperation
+	}
+	filters, err := r.updateFilters(ctx, data, filters)
+	if err != nil {
+		return err
+	}
+	return updater.UpdateIf(ctx, data, precondition, filters...)
+}
+
+// Iterate calls fn with every entry of the current tenant found using the given options.
+func (r *tenantRepository) Iterate(ctx context.Context, fn repository.IterateFunc, options ...repository.Option) error {
+	iterator, ok := r.Repository.(repository.Iterator)
+	if !ok {
+		return repository.ErrUnsupportedOperation
+	}
+	options, err := r.options(ctx, options)
+	if err != nil {
+		return err
+	}
+	return iterator.Iterate(ctx, fn, options...)
+}
+
+// UpdateAndCount updates all the entries of the current tenant that match the provided filters with the given data,
+// and returns the number of updated entries.
+func (r *tenantRepository) UpdateAndCount(ctx context.Context, data interface{}, filters ...repository.Filter) (uint64, error) {
+	counter, ok := r.Repository.(repository.AffectedCounter)
+	if !ok {
+		return 0, repository.ErrUnsupportedOperation
+	}
+	filters, err := r.updateFilters(ctx, data, filters)
+	if err != nil {
+		return 0, err
+	}
+	return counter.UpdateAndCount(ctx, data, filters...)
+}
+
+// DeleteAndCount removes all the entries of the current tenant that match filters, and returns the number of deleted
+// entries.
+func (r *tenantRepository) DeleteAndCount(ctx context.Context, opts ...repository.Option) (uint64, error) {
+	counter, ok := r.Repository.(repository.AffectedCounter)
+	if !ok {
+		return 0, repository.ErrUnsupportedOperation
+	}
+	opts, err := r.options(ctx, opts)
+	if err != nil {
+		return 0, err
+	}
+	return counter.DeleteAndCount(ctx, opts...)
+}
+
+// Sum returns the sum of the values of field in the entries of the current tenant that match filters.
+func (r *tenantRepository) Sum(ctx context.Context, field string, filters ...repository.Filter) (float64, error) {
+	aggregator, ok := r.Repository.(repository.Aggregator)
+	if !ok {
+		return 0, repository.ErrUnsupportedOperation
+	}
+	filters, err := r.filters(ctx, filters)
+	if err != nil {
+		return 0, err
+	}
+	return aggregator.Sum(ctx, field, filters...)
+}
+
+// Avg returns the average of the values of field in the entries of the current tenant that match filters.
+func (r *tenantRepository) Avg(ctx context.Context, field string, filters ...repository.Filter) (float64, error) {
+	aggregator, ok := r.Repository.(repository.Aggregator)
+	if !ok {
+		return 0, repository.ErrUnsupportedOperation
+	}
+	filters, err := r.filters(ctx, filters)
+	if err != nil {
+		return 0, err
+	}
+	return aggregator.Avg(ctx, field, filters...)
+}
+
+// predicate returns the expression that matches the entries of the current tenant.
+func (r *tenantRepository) predicate(ctx context.Context) (repository.Expression, error) {
+	tenant, err := r.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return repository.Eq(r.field, tenant), nil
+}
+
+// filters returns a copy of filters scoped to the current tenant.
+func (r *tenantRepository) filters(ctx context.Context, filters []repository.Filter) ([]repository.Filter, error) {
+	predicate, err := r.predicate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scoped := make([]repository.Filter, len(filters), len(filters)+1)
+	copy(scoped, filters)
+	return append(scoped, repository.NewFilter(predicate)), nil
+}
+
+// updateFilters returns a copy of filters scoped to the current tenant. It returns ErrUnscopedOperation if data
+// modifies the tenant field.
+func (r *tenantRepository) updateFilters(ctx context.Context, data interface{}, filters []repository.Filter) ([]repository.Filter, error) {
+	modified, err := r.modifiesTenant(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	if modified {
+		return nil, fmt.Errorf("%w: %s cannot be updated", ErrUnscopedOperation, r.field)
+	}
+	return r.filters(ctx, filters)
+}
+
+// modifiesTenant returns true if the given update data sets the tenant field.
+func (r *tenantRepository) modifiesTenant(ctx context.Context, data interface{}) (bool, error) {
+	if m, ok := data.(map[string]interface{}); ok {
+		names, err := r.tenantNames()
+		if err != nil {
+			return false, err
+		}
+		for key := range m {
+			for _, name := range names {
+				if strings.EqualFold(key, name) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if v.Kind() != reflect.Struct {
+		return false, nil
+	}
+	s, err := r.schema(v.Interface())
+	if err != nil {
+		return false, err
+	}
+	names, err := r.tenantNames()
+	if err != nil {
+		return false, err
+	}
+	for _, name := range names {
+		f := lookUpField(s, name)
+		if f == nil {
+			continue
+		}
+		if _, zero := f.ValueOf(ctx, v); !zero {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// tenantNames returns the names that identify the tenant field in the repository model: its Go name, column name and
+// json and firestore tag names.
+func (r *tenantRepository) tenantNames() ([]string, error) {
+	s, err := r.schema(r.Model())
+	if err != nil {
+		return nil, err
+	}
+	f := lookUpField(s, r.field)
+	if f == nil {
+		return []string{r.field}, nil
+	}
+	names := []string{r.field}
+	for _, name := range []string{f.Name, f.DBName, tagName(f.Tag.Get("json")), tagName(f.Tag.Get("firestore"))} {
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// checkUniqueIndexes returns ErrUnscopedOperation if entity has a unique index or unique field that doesn't include the
+// tenant field, as upserts could resolve conflicts with entries of other tenants on it. Primary keys are not checked,
+// they are reset by resetPrimaryKey.
+func (r *tenantRepository) checkUniqueIndexes(entity repository.Model) error {
+	s, err := r.schema(entity)
+	if err != nil {
+		return err
+	}
+	tenant := lookUpField(s, r.field)
+	for _, f := range s.Fields {
+		if f.Unique && !f.PrimaryKey && f != tenant {
+			return fmt.Errorf("%w: unique field %s does not include %s", ErrUnscopedOperation, f.Name, r.field)
+		}
+	}
+	for _, idx := range s.ParseIndexes() {
+		if idx.Class != "UNIQUE" {
+			continue
+		}
+		scoped := false
+		for _, opt := range idx.Fields {
+			scoped = scoped || opt.Field == tenant
+		}
+		if !scoped {
+			return fmt.Errorf("%w: unique index %s does not include %s", ErrUnscopedOperation, idx.Name, r.field)
+		}
+	}
+	return nil
+}
+
+// resetPrimaryKey sets the primary key of entity to its zero value.
+func (r *tenantRepository) resetPrimaryKey(entity repository.Model) error {
+	v := reflect.ValueOf(entity)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T must be a pointer to a struct", repository.ErrInvalidModelType, entity)
+	}
+	s, err := r.schema(entity)
+	if err != nil {
+		return err
+	}
+	for _, f := range s.PrimaryFields {
+		v.Elem().FieldByIndex(f.StructField.Index).SetZero()
+	}
+	return nil
+}
+
+// schema returns the parsed schema of the given struct value.
+func (r *tenantRepository) schema(v interface{}) (*schema.Schema, error) {
+	s, err := schema.Parse(v, &r.schemas, schema.NamingStrategy{})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", repository.ErrInvalidModelType, err)
+	}
+	return s, nil
+}
+
+// lookUpField returns the field of s identified by name, comparing Go names, column names and json and firestore tag
+// names without considering case. It returns nil if s doesn't contain such field.
+func lookUpField(s *schema.Schema, name string) *schema.Field {
+	if name == "" || name == "-" {
+		return nil
+	}
+	if f := s.LookUpField(name); f != nil {
+		return f
+	}
+	for _, f := range s.Fields {
+		names := []string{f.Name, f.DBName, tagName(f.Tag.Get("json")), tagName(f.Tag.Get("firestore"))}
+		for _, n := range names {
+			if strings.EqualFold(n, name) {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// tagName returns the name defined in a json or firestore struct tag.
+func tagName(tag string) string {
+	name, _, _ := strings.Cut(tag, ",")
+	return name
+}
+
+// options returns a copy of opts scoped to the current tenant.
+func (r *tenantRepository) options(ctx context.Context, opts []repository.Option) ([]repository.Option, error) {
+	predicate, err := r.predicate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scoped := make([]repository.Option, len(opts), len(opts)+1)
+	copy(scoped, opts)
+	return append(scoped, r.where(predicate)), nil
+}
+
+// stamp sets the current tenant in entity.
+func (r *tenantRepository) stamp(ctx context.Context, entity repository.Model) error {
+	tenant, err := r.tenant(ctx)
+	if err != nil {
+		return err
+	}
+	m, ok := entity.(Model)
+	if !ok {
+		return fmt.Errorf("%w: %T does not implement tenant.Model", repository.ErrInvalidModelType, entity)
+	}
+	m.SetTenant(tenant)
+	return nil
+}
+
+// hasTenantField returns true if fields contains the tenant field.
+func (r *tenantRepository) hasTenantField(fields []string) bool {
+	for _, f := range fields {
+		if f == r.field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,344 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gazebo-web/gz-go/v10/repository"
+	"github.com/gazebo-web/gz-go/v10/repository/memory"
+	"github.com/stretchr/testify/suite"
+)
+
+type Test struct {
+	ID      uint   `json:"id"`
+	Owner   string `json:"owner"`
+	Name    string `json:"name"`
+	Value   int    `json:"value"`
+	Version uint64 `json:"version"`
+}
+
+func (t Test) TableName() string {
+	return "test"
+}
+
+func (t Test) GetID() uint {
+	return t.ID
+}
+
+func (t *Test) SetTenant(tenant string) {
+	t.Owner = tenant
+}
+
+type uniqueName struct {
+	ID    uint   `json:"id"`
+	Owner string `json:"owner" gorm:"uniqueIndex:idx_owner_name"`
+	Name  string `json:"name" gorm:"uniqueIndex:idx_owner_name"`
+	Code  string `json:"code"`
+}
+
+func (u uniqueName) TableName() string {
+	return "unique_name"
+}
+
+func (u uniqueName) GetID() uint {
+	return u.ID
+}
+
+func (u *uniqueName) SetTenant(tenant string) {
+	u.Owner = tenant
+}
+
+type uniqueCode struct {
+	uniqueName
+	Code string `json:"code" gorm:"unique"`
+}
+
+type uniqueCodeIndex struct {
+	uniqueName
+	Code string `json:"code" gorm:"uniqueIndex"`
+}
+
+type untenanted struct {
+	ID uint
+}
+
+func (u untenanted) TableName() string {
+	return "untenanted"
+}
+
+func (u untenanted) GetID() uint {
+	return u.ID
+}
+
+func TestRepository(t *testing.T) {
+	suite.Run(t, new(RepositoryTestSuite))
+}
+
+type RepositoryTestSuite struct {
+	suite.Suite
+	ctx        context.Context
+	wrapped    repository.Repository
+	repository repository.Repository
+}
+
+func (s *RepositoryTestSuite) SetupTest() {
+	s.ctx = WithTenant(context.Background(), "org1")
+	s.wrapped = memory.NewRepository(&Test{})
+	s.repository = NewRepository(s.wrapped, "owner", memory.Where)
+
+	_, err := s.wrapped.CreateBulk(context.Background(), []repository.Model{
+		&Test{Owner: "org1", Name: "Test1", Value: 1},
+		&Test{Owner: "org1", Name: "Test2", Value: 2},
+		&Test{Owner: "org2", Name: "Test3", Value: 3, Version: 1},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *RepositoryTestSuite) TestFromContext() {
+	tenant, err := FromContext(s.ctx)
+	s.Require().NoError(err)
+	s.Assert().Equal("org1", tenant)
+
+	_, err = FromContext(context.Background())
+	s.Assert().ErrorIs(err, ErrNoTenant)
+
+	_, err = FromContext(WithTenant(context.Background(), ""))
+	s.Assert().ErrorIs(err, ErrNoTenant)
+}
+
+func (s *RepositoryTestSuite) TestNoTenant() {
+	ctx := context.Background()
+
+	_, err := s.repository.Create(ctx, &Test{Name: "Test4"})
+	s.Assert().ErrorIs(err, ErrNoTenant)
+
+	var out []Test
+	s.Assert().ErrorIs(s.repository.Find(ctx, &out), ErrNoTenant)
+	s.Assert().ErrorIs(s.repository.FindOne(ctx, &Test{}), ErrNoTenant)
+	s.Assert().ErrorIs(s.repository.Update(ctx, map[string]interface{}{"value": 10}), ErrNoTenant)
+	s.Assert().ErrorIs(s.repository.Delete(ctx), ErrNoTenant)
+	_, err = s.repository.Count(ctx)
+	s.Assert().ErrorIs(err, ErrNoTenant)
+
+	count, err := s.wrapped.Count(ctx)
+	s.Require().NoError(err)
+	s.Assert().Equal(uint64(3), count)
+}
+
+func (s *RepositoryTestSuite) TestCreate() {
+	entity, err := s.repository.Create(s.ctx, &Test{Owner: "org2", Name: "Test4"})
+	s.Require().NoError(err)
+	s.Assert().Equal("org1", entity.(*Test).Owner)
+
+	var out Test
+	s.Require().NoError(s.wrapped.FindOne(s.ctx, &out, repository.NewFilter(repository.Eq("name", "Test4"))))
+	s.Assert().Equal("org1", out.Owner)
+}
+
+func (s *RepositoryTestSuite) TestCreate_InvalidModel() {
+	r := NewRepository(memory.NewRepository(&untenanted{}), "owner", memory.Where)
+	_, err := r.Create(s.ctx, &untenanted{})
+	s.Assert().ErrorIs(err, repository.ErrInvalidModelType)
+}
+
+func (s *RepositoryTestSuite) TestCreateBulk() {
+	entities, err := s.repository.CreateBulk(s.ctx, []repository.Model{&Test{Name: "Test4"}, &Test{Name: "Test5"}})
+	s.Require().NoError(err)
+	for _, entity := range entities {
+		s.Assert().Equal("org1", entity.(*Test).Owner)
+	}
+}
+
+func (s *RepositoryTestSuite) TestFirstOrCreate() {
+	// Test3 belongs to another tenant, so a new entry is created.
+	entity := &Test{Name: "Test3"}
+	s.Require().NoError(s.repository.FirstOrCreate(s.ctx, entity, repository.NewFilter(repository.Eq("name", "Test3"))))
+	s.Assert().Equal("org1", entity.Owner)
+	s.Assert().Equal(uint(4), entity.ID)
+
+	// The entry now exists for the current tenant
+	created, err := s.repository.(repository.FirstOrCreator).FirstOrCreateAndReport(s.ctx, &Test{Name: "Test3"}, repository.NewFilter(repository.Eq("name", "Test3")))
+	s.Require().NoError(err)
+	s.Assert().False(created)
+}
+
+func (s *RepositoryTestSuite) TestUpsert() {
+	err := s.repository.Upsert(s.ctx, &Test{Name: "Test3", Value: 10}, "name")
+	s.Assert().ErrorIs(err, ErrUnscopedOperation)
+
+	s.Require().NoError(s.repository.Upsert(s.ctx, &Test{Name: "Test3", Value: 10}, "owner", "name"))
+
+	var out []Test
+	s.Require().NoError(s.wrapped.Find(s.ctx, &out, memory.Where(repository.Eq("name", "Test3"))))
+	s.Require().Len(out, 2)
+	s.Assert().Equal("org2", out[0].Owner)
+	s.Assert().Equal(3, out[0].Value)
+	s.Assert().Equal("org1", out[1].Owner)
+	s.Assert().Equal(10, out[1].Value)
+}
+
+func (s *RepositoryTestSuite) TestUpsert_UniqueIndexes() {
+	// Unique indexes that include the tenant field are supported
+	repo := NewRepository(memory.NewRepository(&uniqueName{}), "owner", memory.Where)
+	s.Require().NoError(repo.Upsert(s.ctx, &uniqueName{Name: "Test1"}, "owner", "name"))
+
+	// Unique indexes that don't include the tenant field could match entries of other tenants
+	repo = NewRepository(memory.NewRepository(&uniqueCode{}), "owner", memory.Where)
+	s.Assert().ErrorIs(repo.Upsert(s.ctx, &uniqueCode{Code: "code"}, "owner", "name"), ErrUnscopedOperation)
+
+	repo = NewRepository(memory.NewRepository(&uniqueCodeIndex{}), "owner", memory.Where)
+	s.Assert().ErrorIs(repo.Upsert(s.ctx, &uniqueCodeIndex{Code: "code"}, "owner", "name"), ErrUnscopedOperation)
+}
+
+func (s *RepositoryTestSuite) TestUpsert_PrimaryKey() {
+	// The primary key of an entry of another tenant is ignored
+	entity := &Test{ID: 3, Name: "Test4", Value: 10}
+	s.Require().NoError(s.repository.Upsert(s.ctx, entity, "owner", "name"))
+	s.Assert().NotEqual(uint(3), entity.ID)
+
+	var out Test
+	s.Require().NoError(s.wrapped.FindOne(s.ctx, &out, repository.NewFilter(repository.Eq("id", 3))))
+	s.Assert().Equal("org2", out.Owner)
+	s.Assert().Equal("Test3", out.Name)
+	s.Assert().Equal(3, out.Value)
+}
+
+func (s *RepositoryTestSuite) TestFind() {
+	var out []Test
+	s.Require().NoError(s.repository.Find(s.ctx, &out))
+	s.Require().Len(out, 2)
+	s.Assert().Equal("Test1", out[0].Name)
+	s.Assert().Equal("Test2", out[1].Name)
+
+	s.Require().NoError(s.repository.Find(s.ctx, &out, memory.Where(repository.Gt("value", 1))))
+	s.Require().Len(out, 1)
+	s.Assert().Equal("Test2", out[0].Name)
+}
+
+func (s *RepositoryTestSuite) TestFindOne() {
+	var out Test
+	s.Require().NoError(s.repository.FindOne(s.ctx, &out, repository.NewFilter(repository.Eq("name", "Test1"))))
+	s.Assert().Equal(uint(1), out.ID)
+
+	err := s.repository.FindOne(s.ctx, &out, repository.NewFilter(repository.Eq("name", "Test3")))
+	s.Assert().ErrorIs(err, repository.ErrNotFound)
+}
+
+func (s *RepositoryTestSuite) TestLast() {
+	var out Test
+	s.Require().NoError(s.repository.Last(s.ctx, &out))
+	s.Assert().Equal("Test2", out.Name)
+}
+
+func (s *RepositoryTestSuite) TestCount() {
+	count, err := s.repository.Count(s.ctx)
+	s.Require().NoError(err)
+	s.Assert().Equal(uint64(2), count)
+
+	count, err = s.repository.Count(WithTenant(context.Background(), "org2"))
+	s.Require().NoError(err)
+	s.Assert().Equal(uint64(1), count)
+}
+
+func (s *RepositoryTestSuite) TestUpdate() {
+	s.Require().NoError(s.repository.Update(s.ctx, map[string]interface{}{"value": 10}, repository.NewFilter(repository.Gt("value", 1))))
+
+	var out []Test
+	s.Require().NoError(s.wrapped.Find(s.ctx, &out))
+	s.Assert().Equal(1, out[0].Value)
+	s.Assert().Equal(10, out[1].Value)
+	s.Assert().Equal(3, out[2].Value)
+}
+
+func (s *RepositoryTestSuite) TestUpdate_TenantField() {
+	err := s.repository.Update(s.ctx, map[string]interface{}{"owner": "org2"}, repository.NewFilter(repository.Eq("name", "Test1")))
+	s.Assert().ErrorIs(err, ErrUnscopedOperation)
+}
+
+func (s *RepositoryTestSuite) TestUpdate_TenantFieldNames() {
+	filter := repository.NewFilter(repository.Eq("name", "Test1"))
+	for _, key := range []string{"Owner", "OWNER", "owner"} {
+		err := s.repository.Update(s.ctx, map[string]interface{}{key: "org2"}, filter)
+		s.Assert().ErrorIs(err, ErrUnscopedOperation, key)
+	}
+}
+
+func (s *RepositoryTestSuite) TestUpdate_TenantFieldStruct() {
+	filter := repository.NewFilter(repository.Eq("name", "Test1"))
+	err := s.repository.Update(s.ctx, &Test{Owner: "org2"}, filter)
+	s.Assert().ErrorIs(err, ErrUnscopedOperation)
+
+	err = s.repository.Update(s.ctx, struct{ Owner string }{Owner: "org2"}, filter)
+	s.Assert().ErrorIs(err, ErrUnscopedOperation)
+
+	// Structs that don't set the tenant field can be used
+	s.Require().NoError(s.repository.Update(s.ctx, &Test{Value: 10}, filter))
+	var out Test
+	s.Require().NoError(s.wrapped.FindOne(s.ctx, &out, filter))
+	s.Assert().Equal("org1", out.Owner)
+	s.Assert().Equal(10, out.Value)
+}
+
+func (s *RepositoryTestSuite) TestUpdateIf() {
+	updater, ok := s.repository.(repository.ConditionalUpdater)
+	s.Require().True(ok)
+
+	// Test3 belongs to another tenant, so the precondition is not evaluated against it.
+	err := updater.UpdateIf(s.ctx, map[string]interface{}{"value": 10}, repository.IfVersion(0))
+	s.Require().NoError(err)
+
+	count, err := s.wrapped.Count(s.ctx, repository.NewFilter(repository.Eq("value", 10)))
+	s.Require().NoError(err)
+	s.Assert().Equal(uint64(2), count)
+}
+
+func (s *RepositoryTestSuite) TestDelete() {
+	s.Require().NoError(s.repository.Delete(s.ctx, memory.Where(repository.Neq("name", "Test2"))))
+
+	var out []Test
+	s.Require().NoError(s.wrapped.Find(s.ctx, &out))
+	s.Require().Len(out, 2)
+	s.Assert().Equal("Test2", out[0].Name)
+	s.Assert().Equal("Test3", out[1].Name)
+}
+
+func (s *RepositoryTestSuite) TestIterate() {
+	iterator, ok := s.repository.(repository.Iterator)
+	s.Require().True(ok)
+
+	var names []string
+	s.Require().NoError(iterator.Iterate(s.ctx, func(entry repository.Model) error {
+		names = append(names, entry.(*Test).Name)
+		return nil
+	}))
+	s.Assert().Equal([]string{"Test1", "Test2"}, names)
+}
+
+func (s *RepositoryTestSuite) TestUnsupportedOperation() {
+	counter, ok := s.repository.(repository.AffectedCounter)
+	s.Require().True(ok)
+	_, err := counter.DeleteAndCount(s.ctx)
+	s.Assert().ErrorIs(err, repository.ErrUnsupportedOperation)
+
+	aggregator, ok := s.repository.(repository.Aggregator)
+	s.Require().True(ok)
+	_, err = aggregator.Sum(s.ctx, "value")
+	s.Assert().ErrorIs(err, repository.ErrUnsupportedOperation)
+}
+
+func (s *RepositoryTestSuite) TestTenantFunc() {
+	errTenant := errors.New("tenant error")
+	s.repository = NewRepository(s.wrapped, "owner", memory.Where, WithTenantFunc(func(ctx context.Context) (string, error) {
+		return "", errTenant
+	}))
+	_, err := s.repository.Count(s.ctx)
+	s.Assert().ErrorIs(err, errTenant)
+
+	s.repository = NewRepository(s.wrapped, "owner", memory.Where, WithTenantFunc(func(ctx context.Context) (string, error) {
+		return "org2", nil
+	}))
+	count, err := s.repository.Count(context.Background())
+	s.Require().NoError(err)
+	s.Assert().Equal(uint64(1), count)
+}
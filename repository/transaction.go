@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"sync"
+)
+
+// TransactionFunc is a function that runs a set of repository operations inside a transaction.
+// The given context carries the transaction, and it must be passed to every Repository operation that should
+// participate in it.
+type TransactionFunc func(ctx context.Context) error
+
+// Transactioner runs repository operations atomically.
+//
+// Each Repository implementation provides its own Transactioner. Repositories created for the same persistence
+// layer automatically participate in transactions started by its Transactioner.
+type Transactioner interface {
+	// WithTransaction runs fn inside a transaction. The transaction is committed if fn returns no error, and it is
+	// rolled back if fn returns an error or panics. Panics are propagated after the transaction is rolled back.
+	//
+	// Calling WithTransaction with a context that already carries a transaction runs fn in that same transaction
+	// when the implementation does not support nested transactions.
+	WithTransaction(ctx context.Context, fn TransactionFunc) error
+}
+
+// transactionKey is the context key used to mark contexts that carry a transaction.
+type transactionKey struct{}
+
+// transactionHooks contains the functions to call after a transaction is committed.
+type transactionHooks struct {
+	lock sync.Mutex
+	// parent contains the hooks of the outer transaction of a nested transaction.
+	parent *transactionHooks
+	fns    []func()
+}
+
+// add registers fns to be called after the transaction is committed.
+func (h *transactionHooks) add(fns ...func()) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.fns = append(h.fns, fns...)
+}
+
+// ContextWithTransaction returns a copy of ctx marked as carrying a transaction. Transactioner implementations mark the
+// context passed to TransactionFunc, allowing Repository decorators to detect transactions regardless of the
+// persistence layer. Transactioner implementations must also call Committed with the returned context after the
+// transaction is committed.
+func ContextWithTransaction(ctx context.Context) context.Context {
+	parent, _ := ctx.Value(transactionKey{}).(*transactionHooks)
+	return context.WithValue(ctx, transactionKey{}, &transactionHooks{parent: parent})
+}
+
+// InTransaction returns true if ctx carries a transaction started by a Transactioner.
+func InTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(transactionKey{}).(*transactionHooks)
+	return ok
+}
+
+// AfterCommit registers fn to be called after the transaction carried by ctx is committed. fn is not called if the
+// transaction is rolled back. If ctx doesn't carry a transaction, fn is called immediately.
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(transactionKey{}).(*transactionHooks)
+	if !ok {
+		fn()
+		return
+	}
+	hooks.add(fn)
+}
+
+// Committed calls the functions registered with AfterCommit for the transaction carried by ctx, which must be a
+// context returned by ContextWithTransaction. The functions registered in a nested transaction are called when the
+// outermost transaction is committed.
+func Committed(ctx context.Context) {
+	hooks, ok := ctx.Value(transactionKey{}).(*transactionHooks)
+	if !ok {
+		return
+	}
+	hooks.lock.Lock()
+	fns := hooks.fns
+	hooks.fns = nil
+	hooks.lock.Unlock()
+
+	if hooks.parent != nil {
+		hooks.parent.add(fns...)
+		return
+	}
+	for _, fn := range fns {
+		fn()
+	}
+}
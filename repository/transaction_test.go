@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInTransaction(t *testing.T) {
+	ctx := context.Background()
+	assert.False(t, InTransaction(ctx))
+	assert.True(t, InTransaction(ContextWithTransaction(ctx)))
+}
+
+func TestAfterCommit(t *testing.T) {
+	var calls []string
+
+	// Functions are called immediately outside of transactions
+	AfterCommit(context.Background(), func() { calls = append(calls, "no transaction") })
+	assert.Equal(t, []string{"no transaction"}, calls)
+
+	calls = nil
+	tx := ContextWithTransaction(context.Background())
+	AfterCommit(tx, func() { calls = append(calls, "outer") })
+
+	// Functions of nested transactions are called when the outer transaction is committed
+	nested := ContextWithTransaction(tx)
+	AfterCommit(nested, func() { calls = append(calls, "nested") })
+	Committed(nested)
+	assert.Empty(t, calls)
+
+	// Functions of rolled back nested transactions are not called
+	rolledBack := ContextWithTransaction(tx)
+	AfterCommit(rolledBack, func() { calls = append(calls, "rolled back") })
+
+	Committed(tx)
+	assert.Equal(t, []string{"outer", "nested"}, calls)
+
+	// Functions are only called once
+	Committed(tx)
+	assert.Len(t, calls, 2)
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+)
+
+// TypedRepository is a type-safe version of Repository. It holds methods to CRUD an entity of type T on a certain
+// persistence layer.
+//
+// T can be either a Model value type (e.g. User) or a pointer to one (e.g. *User).
+//
+// Use NewTypedRepository to use an existing Repository implementation through this interface.
+type TypedRepository[T Model] interface {
+	// FirstOrCreate inserts a new entry if the given filters don't find any existing record.
+	// It returns the existing record if found, or the created entity otherwise.
+	FirstOrCreate(ctx context.Context, entity T, filters ...Filter) (T, error)
+	// Create inserts a single entry.
+	// entity: The entry to insert.
+	Create(ctx context.Context, entity T) (T, error)
+	// CreateBulk creates multiple entries with a single operation.
+	CreateBulk(ctx context.Context, entities []T) ([]T, error)
+	// Upsert inserts a single entry, or updates the existing entry that conflicts with it.
+	// It returns the stored entry.
+	// conflictFields: fields that identify the existing entry. If empty, the entry identifier is used.
+	Upsert(ctx context.Context, entity T, conflictFields ...string) (T, error)
+	// Find filters entries and returns them.
+	// options: configuration options for the search. Refer to the implementation's set of options to get a lit of options.
+	Find(ctx context.Context, options ...Option) ([]T, error)
+	// FindOne filters entries and returns the first filtered entry.
+	FindOne(ctx context.Context, filters ...Filter) (T, error)
+	// Last gets the last record ordered by primary key desc.
+	Last(ctx context.Context, filters ...Filter) (T, error)
+	// Update updates all model entries that match the provided filters with the given data.
+	// data: must be a map[string]interface{}
+	// filters: selection criteria for entries that should be updated.
+	Update(ctx context.Context, data interface{}, filters ...Filter) error
+	// Delete removes all the model entries that match filters.
+	// filters: selection criteria for entries that should be deleted.
+	Delete(ctx context.Context, opts ...Option) error
+	// Count counts all the model entries that match filters.
+	// filters: selection criteria for entries that should be considered when counting entries.
+	Count(ctx context.Context, filters ...Filter) (uint64, error)
+	// Model returns this repository's model.
+	Model() T
+	// Repository returns the underlying untyped Repository.
+	Repository() Repository
+}
+
+// NewTypedRepository initializes a new TypedRepository that performs all of its operations using the given Repository.
+func NewTypedRepository[T Model](repository Repository) TypedRepository[T] {
+	return &typedRepository[T]{
+		repository: repository,
+	}
+}
+
+// typedRepository implements TypedRepository by wrapping a Repository.
+type typedRepository[T Model] struct {
+	repository Repository
+}
+
+// FirstOrCreate inserts a new entry if the given filters don't find any existing record.
+func (r *typedRepository[T]) FirstOrCreate(ctx context.Context, entity T, filters ...Filter) (T, error) {
+	if err := r.repository.FirstOrCreate(ctx, AsModel(&entity), filters...); err != nil {
+		var zero T
+		return zero, err
+	}
+	return entity, nil
+}
+
+// Create inserts a single entry.
+func (r *typedRepository[T]) Create(ctx context.Context, entity T) (T, error) {
+	var zero T
+	created, err := r.repository.Create(ctx, AsModel(&entity))
+	if err != nil {
+		return zero, err
+	}
+	return fromModel[T](created)
+}
+
+// CreateBulk creates multiple entries with a single operation.
+func (r *typedRepository[T]) CreateBulk(ctx context.Context, entities []T) ([]T, error) {
+	models := make([]Model, len(entities))
+	for i := range entities {
+		models[i] = AsModel(&entities[i])
+	}
+	created, err := r.repository.CreateBulk(ctx, models)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]T, len(created))
+	for i, m := range created {
+		if out[i], err = fromModel[T](m); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Upsert inserts a single entry, or updates the existing entry that conflicts with it.
+func (r *typedRepository[T]) Upsert(ctx context.Context, entity T, conflictFields ...string) (T, error) {
+	if err := r.repository.Upsert(ctx, AsModel(&entity), conflictFields...); err != nil {
+		var zero T
+		return zero, err
+	}
+	return entity, nil
+}
+
+// Find filters entries and returns them.
+func (r *typedRepository[T]) Find(ctx context.Context, options ...Option) ([]T, error) {
+	var out []T
+	if err := r.repository.Find(ctx, &out, options...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FindOne filters entries and returns the first filtered entry.
+func (r *typedRepository[T]) FindOne(ctx context.Context, filters ...Filter) (T, error) {
+	var out T
+	if err := r.repository.FindOne(ctx, AsModel(&out), filters...); err != nil {
+		var zero T
+		return zero, err
+	}
+	return out, nil
+}
+
+// Last gets the last record ordered by primary key desc.
+func (r *typedRepository[T]) Last(ctx context.Context, filters ...Filter) (T, error) {
+	var out T
+	if err := r.repository.Last(ctx, AsModel(&out), filters...); err != nil {
+		var zero T
+		return zero, err
+	}
+	return out, nil
+}
+
+// Update updates all model entries that match the provided filters with the given data.
+func (r *typedRepository[T]) Update(ctx context.Context, data interface{}, filters ...Filter) error {
+	return r.repository.Update(ctx, data, filters...)
+}
+
+// Delete removes all the model entries that match filters.
+func (r *typedRepository[T]) Delete(ctx context.Context, opts ...Option) error {
+	return r.repository.Delete(ctx, opts...)
+}
+
+// Count counts all the model entries that match filters.
+func (r *typedRepository[T]) Count(ctx context.Context, filters ...Filter) (uint64, error) {
+	return r.repository.Count(ctx, filters...)
+}
+
+// Model returns this repository's model.
+func (r *typedRepository[T]) Model() T {
+	m, _ := fromModel[T](r.repository.Model())
+	return m
+}
+
+// Repository returns the underlying untyped Repository.
+func (r *typedRepository[T]) Repository() Repository {
+	return r.repository
+}
+
+// AsModel returns a Model that points to the value stored in v. Repository implementations can write to the returned
+// Model, and changes will be reflected in v.
+//
+// If T is a pointer type and v holds a nil pointer, a new zero value is allocated and assigned to v.
+func AsModel[T Model](v *T) Model {
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return rv.Interface().(Model)
+	}
+	return any(v).(Model)
+}
+
+// fromModel converts a Model returned by a Repository into T. It supports converting pointers to T into T.
+// It returns ErrInvalidModelType if m cannot be converted to T.
+func fromModel[T Model](m Model) (T, error) {
+	if v, ok := m.(T); ok {
+		return v, nil
+	}
+	if p, ok := any(m).(*T); ok && p != nil {
+		return *p, nil
+	}
+	var zero T
+	return zero, ErrInvalidModelType
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gazebo-web/gz-go/v10/reflect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type typedTestModel struct {
+	ID   uint
+	Name string
+}
+
+func (m typedTestModel) TableName() string {
+	return "typed_test_model"
+}
+
+func (m typedTestModel) GetID() uint {
+	return m.ID
+}
+
+// fakeRepository is a minimal Repository implementation that behaves like a SQL repository: it writes results
+// to pointer outputs and returns the entities it receives.
+type fakeRepository struct {
+	data   []typedTestModel
+	nextID uint
+}
+
+func (r *fakeRepository) FirstOrCreate(ctx context.Context, entity Model, filters ...Filter) error {
+	_, err := r.FirstOrCreateAndReport(ctx, entity, filters...)
+	return err
+}
+
+func (r *fakeRepository) FirstOrCreateAndReport(ctx context.Context, entity Model, filters ...Filter) (bool, error) {
+	if len(r.data) > 0 {
+		return false, reflect.SetValue(entity, r.data[0])
+	}
+	_, err := r.Create(ctx, entity)
+	return err == nil, err
+}
+
+func (r *fakeRepository) Create(ctx context.Context, entity Model) (Model, error) {
+	out, err := r.CreateBulk(ctx, []Model{entity})
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+func (r *fakeRepository) CreateBulk(ctx context.Context, entities []Model) ([]Model, error) {
+	for _, e := range entities {
+		m := e.(*typedTestModel)
+		r.nextID++
+		m.ID = r.nextID
+		r.data = append(r.data, *m)
+	}
+	return entities, nil
+}
+
+func (r *fakeRepository) Upsert(ctx context.Context, entity Model, conflictFields ...string) error {
+	m := entity.(*typedTestModel)
+	for i := range r.data {
+		if r.data[i].ID == m.ID {
+			r.data[i] = *m
+			return nil
+		}
+	}
+	_, err := r.Create(ctx, entity)
+	return err
+}
+
+func (r *fakeRepository) Find(ctx context.Context, output interface{}, options ...Option) error {
+	for _, m := range r.data {
+		if err := reflect.AppendToSlice(output, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) FindOne(ctx context.Context, output Model, filters ...Filter) error {
+	return reflect.SetValue(output, r.data[0])
+}
+
+func (r *fakeRepository) Last(ctx context.Context, output Model, filters ...Filter) error {
+	return reflect.SetValue(output, r.data[len(r.data)-1])
+}
+
+func (r *fakeRepository) Update(ctx context.Context, data interface{}, filters ...Filter) error {
+	return nil
+}
+
+func (r *fakeRepository) Delete(ctx context.Context, opts ...Option) error {
+	r.data = nil
+	return nil
+}
+
+func (r *fakeRepository) Count(ctx context.Context, filters ...Filter) (uint64, error) {
+	return uint64(len(r.data)), nil
+}
+
+func (r *fakeRepository) Model() Model {
+	return &typedTestModel{}
+}
+
+func TestTypedRepository_Value(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTypedRepository[typedTestModel](&fakeRepository{})
+
+	created, err := repo.Create(ctx, typedTestModel{Name: "test-1"})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), created.ID)
+
+	bulk, err := repo.CreateBulk(ctx, []typedTestModel{{Name: "test-2"}, {Name: "test-3"}})
+	require.NoError(t, err)
+	require.Len(t, bulk, 2)
+	assert.Equal(t, uint(2), bulk[0].ID)
+	assert.Equal(t, uint(3), bulk[1].ID)
+
+	list, err := repo.Find(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+
+	first, err := repo.FindOne(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "test-1", first.Name)
+
+	last, err := repo.Last(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "test-3", last.Name)
+
+	existing, err := repo.FirstOrCreate(ctx, typedTestModel{Name: "test-4"})
+	require.NoError(t, err)
+	assert.Equal(t, "test-1", existing.Name)
+
+	upserted, err := repo.Upsert(ctx, typedTestModel{ID: 1, Name: "test-5"})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), upserted.ID)
+	first, err = repo.FindOne(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "test-5", first.Name)
+
+	assert.Equal(t, typedTestModel{}, repo.Model())
+}
+
+func TestTypedRepository_Pointer(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTypedRepository[*typedTestModel](&fakeRepository{})
+
+	entity := &typedTestModel{Name: "test-1"}
+	created, err := repo.Create(ctx, entity)
+	require.NoError(t, err)
+	assert.Same(t, entity, created)
+	assert.Equal(t, uint(1), entity.ID)
+
+	first, err := repo.FindOne(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+	assert.Equal(t, "test-1", first.Name)
+
+	assert.NotNil(t, repo.Model())
+}
+
+func TestAsModel(t *testing.T) {
+	var value typedTestModel
+	m := AsModel(&value)
+	assert.Same(t, &value, m)
+
+	var ptr *typedTestModel
+	m = AsModel(&ptr)
+	require.NotNil(t, ptr)
+	assert.Same(t, ptr, m)
+}
+
+func TestFromModel(t *testing.T) {
+	v, err := fromModel[typedTestModel](&typedTestModel{Name: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, "test", v.Name)
+
+	p, err := fromModel[*typedTestModel](&typedTestModel{Name: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, "test", p.Name)
+
+	_, err = fromModel[*typedTestModel](typedTestModel{Name: "test"})
+	assert.ErrorIs(t, err, ErrInvalidModelType)
+}
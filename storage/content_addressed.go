@@ -0,0 +1,507 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gazebo-web/gz-go/v10"
+	"github.com/pkg/errors"
+)
+
+// GarbageCollector is implemented by Storage implementations that can remove the content that is no longer used by
+// any resource version.
+type GarbageCollector interface {
+	// CollectGarbage removes the content of the resource identified by owner and uuid that is not referenced by any
+	// of its versions. It returns the number of removed blobs.
+	//
+	//	Content is uploaded before the manifest that references it. Content modified within the grace period set with
+	//	GarbageCollectionGracePeriod is never removed. Uploads reuse content that is already stored, and upload it
+	//	again if it was modified more than half the grace period ago, so uploads in progress are not affected as long
+	//	as they take less than half the grace period.
+	CollectGarbage(ctx context.Context, owner string, uuid string) (int, error)
+}
+
+// modTimeFunc is used to provide integration with cloud providers when getting the last modification time of the
+// object found in a location. The location is used as-is. It returns ErrResourceNotFound if the object doesn't exist.
+type modTimeFunc func(ctx context.Context, location string) (time.Time, error)
+
+// openObjectFunc is used to provide integration with cloud providers when reading a range of bytes of the object found
+// in a location. The location is used as-is.
+type openObjectFunc func(ctx context.Context, location string, rng Range) (io.ReadCloser, error)
+
+// objectFuncs contains the functions used to access the objects of a cloud storage bucket. Locations passed to these
+// functions are used as-is.
+type objectFuncs struct {
+	put     WalkDirFunc
+	open    openObjectFunc
+	list    ListFunc
+	remove  WalkDirFunc
+	modTime modTimeFunc
+}
+
+// manifest contains the files of a resource version stored using the content-addressed layout.
+type manifest struct {
+	// Files contains the files of the version sorted by path.
+	Files []manifestEntry `json:"files"`
+}
+
+// manifestEntry maps the path of a file to its content.
+type manifestEntry struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	SHA256  string    `json:"sha256"`
+	MD5     string    `json:"md5"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// fileInfo returns the FileInfo of the file described by the current entry.
+func (e manifestEntry) fileInfo() FileInfo {
+	return FileInfo{
+		Path:     e.Path,
+		Size:     e.Size,
+		Checksum: e.MD5,
+		ModTime:  e.ModTime,
+	}
+}
+
+// find returns the entry of the file located in path.
+func (m *manifest) find(path string) (manifestEntry, bool) {
+	i := sort.Search(len(m.Files), func(i int) bool {
+		return m.Files[i].Path >= path
+	})
+	if i == len(m.Files) || m.Files[i].Path != path {
+		return manifestEntry{}, false
+	}
+	return m.Files[i], true
+}
+
+// set adds the given entries to the manifest, replacing the entries of files with the same path.
+func (m *manifest) set(entries ...manifestEntry) {
+	files := make(map[string]manifestEntry, len(m.Files)+len(entries))
+	for _, e := range m.Files {
+		files[e.Path] = e
+	}
+	for _, e := range entries {
+		files[e.Path] = e
+	}
+	m.Files = make([]manifestEntry, 0, len(files))
+	for _, e := range files {
+		m.Files = append(m.Files, e)
+	}
+	sort.Slice(m.Files, func(i, j int) bool {
+		return m.Files[i].Path < m.Files[j].Path
+	})
+}
+
+// contentAddressed implements Storage by storing the content of files by their SHA-256 digest, so files with the same
+// content are only stored once per resource. Each resource version has a manifest that maps the paths of its files
+// to their content.
+//
+//	Objects are stored in the following locations relative to the root location of each resource:
+//	  .blobs/<first two characters of the digest>/<digest>: content of the files.
+//	  .manifests/<version>.json: manifest of each version.
+//	  .zips/<version>.zip: zip file of each version, same as the default layout.
+//
+// Manifests are updated by reading, modifying and writing them back. Updates to the same manifest are serialized
+// within a single contentAddressed instance, but concurrent updates from different instances or processes may
+// overwrite each other, and must be coordinated by the caller. Zip files are removed when the manifest of their
+// version changes, and they are generated again when downloaded.
+//
+// Operations that don't depend on the layout, such as UploadZip and DeleteResource, are provided by the embedded
+// Storage.
+type contentAddressed struct {
+	Storage
+	objects objectFuncs
+	options options
+	// manifestLocks contains a *sync.Mutex for each manifest location, used to serialize manifest updates.
+	manifestLocks sync.Map
+}
+
+// Ensure that contentAddressed implements the GarbageCollector interface.
+var _ GarbageCollector = (*contentAddressed)(nil)
+
+// newContentAddressed initializes a new Storage implementation that uses the content-addressed layout. The given
+// storage is used for operations that don't depend on the layout, and objects provides access to the bucket objects.
+func newContentAddressed(storage Storage, objects objectFuncs, opts options) Storage {
+	return &contentAddressed{
+		Storage: storage,
+		objects: objects,
+		options: opts,
+	}
+}
+
+// GetFile returns the content of the file located in path from the given resource.
+func (c *contentAddressed) GetFile(ctx context.Context, resource Resource, path string) ([]byte, error) {
+	return ReadFile(ctx, resource, path, c.readFile)
+}
+
+// OpenFile returns a reader for the bytes in rng of the file located in path from the given resource.
+func (c *contentAddressed) OpenFile(ctx context.Context, resource Resource, path string, rng Range) (io.ReadCloser, error) {
+	return OpenFile(ctx, resource, path, rng, c.openFile)
+}
+
+// PutFile uploads the content read from body to the file located in path in the given resource, and adds it to the
+// manifest of the resource. Bodies that cannot be seeked are copied to a temporary file to calculate their digest.
+func (c *contentAddressed) PutFile(ctx context.Context, resource Resource, path string, body io.Reader) error {
+	return PutFile(ctx, resource, path, body, func(ctx context.Context, path string, body io.Reader) error {
+		entry, err := c.putBlob(ctx, resource, path, body)
+		if err != nil {
+			return err
+		}
+		_, err = c.updateManifest(ctx, resource, entry)
+		return err
+	})
+}
+
+// UploadDir uploads the content of the files found in src that is not already stored, and adds the files to the
+// manifest of the given resource.
+//
+//	If ZipOnUpload was provided when initializing the storage, the zip file of the resource is also generated and
+//	uploaded. If the resource version already had files, the zip file is generated from its updated manifest.
+func (c *contentAddressed) UploadDir(ctx context.Context, resource Resource, src string) error {
+	var entries []manifestEntry
+	err := UploadDir(ctx, resource, src, func(ctx context.Context, path string, body io.Reader) error {
+		entry, err := c.putBlob(ctx, resource, filepath.ToSlash(path), body)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	m, err := c.updateManifest(ctx, resource, entries...)
+	if err != nil {
+		return err
+	}
+	if !c.options.zipOnUpload {
+		return nil
+	}
+	if len(m.Files) > len(entries) {
+		return c.RebuildZip(ctx, resource)
+	}
+	return UploadDirZip(ctx, resource, src, c.objects.put)
+}
+
+// Download returns the URL to the zip file of the given resource. If the zip file doesn't exist, it's generated from
+// the files in the manifest of the resource.
+func (c *contentAddressed) Download(ctx context.Context, resource Resource) (string, error) {
+	if err := validateResource(resource); err != nil {
+		return "", err
+	}
+	found, err := c.exists(ctx, getZipLocation("", resource))
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		if err = c.RebuildZip(ctx, resource); err != nil {
+			return "", err
+		}
+	}
+	return c.Storage.Download(ctx, resource)
+}
+
+// ListFiles returns the information of the files in the manifest of the given resource whose path starts with prefix.
+func (c *contentAddressed) ListFiles(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error) {
+	return ListFiles(ctx, resource, prefix, c.listFiles)
+}
+
+// Stat returns the information of the file located in path from the manifest of the given resource.
+func (c *contentAddressed) Stat(ctx context.Context, resource Resource, path string) (FileInfo, error) {
+	return Stat(ctx, resource, path, func(ctx context.Context, resource Resource, path string) (FileInfo, error) {
+		entry, err := c.findEntry(ctx, resource, path)
+		if err != nil {
+			return FileInfo{}, err
+		}
+		return entry.fileInfo(), nil
+	})
+}
+
+// DeleteVersion removes the manifest and the zip file of the given resource version. The content of its files is
+// removed by CollectGarbage once no other version references it.
+func (c *contentAddressed) DeleteVersion(ctx context.Context, resource Resource) error {
+	if err := validateResource(resource); err != nil {
+		return err
+	}
+	locations := []string{getManifestLocation(resource)}
+	found, err := c.exists(ctx, locations[0])
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrResourceNotFound
+	}
+
+	zip := getZipLocation("", resource)
+	if found, err = c.exists(ctx, zip); err != nil {
+		return err
+	}
+	if found {
+		locations = append(locations, zip)
+	}
+	return deleteLocations(ctx, locations, c.objects.remove)
+}
+
+// RebuildZip generates the zip file of the given resource from the files in its manifest, and uploads it.
+func (c *contentAddressed) RebuildZip(ctx context.Context, resource Resource) error {
+	return RebuildZip(ctx, resource, c.listFiles, c.readFile, c.objects.put)
+}
+
+// CollectGarbage removes the blobs of the given resource that are not referenced by the manifest of any version, and
+// that were not modified within the garbage collection grace period.
+func (c *contentAddressed) CollectGarbage(ctx context.Context, owner string, uuid string) (int, error) {
+	if err := validateOwner(owner); err != nil {
+		return 0, err
+	}
+	if err := validateUUID(uuid); err != nil {
+		return 0, err
+	}
+
+	root := getRootLocation("", owner, uuid)
+	manifests, err := c.objects.list(ctx, root+"/.manifests/")
+	if err != nil {
+		return 0, err
+	}
+	referenced := make(map[string]bool)
+	for _, location := range manifests {
+		m, err := c.readManifest(ctx, location)
+		if err != nil {
+			return 0, err
+		}
+		for _, e := range m.Files {
+			referenced[getBlobLocation(owner, uuid, e.SHA256)] = true
+		}
+	}
+
+	blobs, err := c.objects.list(ctx, root+"/.blobs/")
+	if err != nil {
+		return 0, err
+	}
+	var removed int
+	for _, location := range blobs {
+		if referenced[location] {
+			continue
+		}
+		// Blobs of uploads in progress are not referenced by a manifest yet.
+		modTime, err := c.objects.modTime(ctx, location)
+		if errors.Is(err, ErrResourceNotFound) {
+			continue
+		}
+		if err != nil {
+			return removed, err
+		}
+		if time.Since(modTime) < c.options.gracePeriod {
+			continue
+		}
+		if err = c.objects.remove(ctx, location, nil); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// readFile returns a reader for the content of the file located in path from the given resource.
+func (c *contentAddressed) readFile(ctx context.Context, resource Resource, path string) (io.ReadCloser, error) {
+	path, err := cleanPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.openFile(ctx, resource, path, Range{})
+}
+
+// openFile returns a reader for the bytes in rng of the content of the file located in path from the given resource.
+func (c *contentAddressed) openFile(ctx context.Context, resource Resource, path string, rng Range) (io.ReadCloser, error) {
+	entry, err := c.findEntry(ctx, resource, path)
+	if err != nil {
+		return nil, err
+	}
+	if rng.Offset > 0 && rng.Offset >= entry.Size {
+		return nil, ErrInvalidRange
+	}
+	return c.objects.open(ctx, getBlobLocation(resource.GetOwner(), resource.GetUUID(), entry.SHA256), rng)
+}
+
+// listFiles returns the files in the manifest of the given resource whose path starts with prefix.
+func (c *contentAddressed) listFiles(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error) {
+	m, err := c.readManifest(ctx, getManifestLocation(resource))
+	if errors.Is(err, ErrResourceNotFound) {
+		return []FileInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := []FileInfo{}
+	for _, e := range m.Files {
+		if strings.HasPrefix(e.Path, prefix) {
+			files = append(files, e.fileInfo())
+		}
+	}
+	return files, nil
+}
+
+// findEntry returns the manifest entry of the file located in path from the given resource.
+func (c *contentAddressed) findEntry(ctx context.Context, resource Resource, path string) (manifestEntry, error) {
+	m, err := c.readManifest(ctx, getManifestLocation(resource))
+	if err != nil {
+		return manifestEntry{}, err
+	}
+	entry, ok := m.find(path)
+	if !ok {
+		return manifestEntry{}, errors.Wrap(ErrResourceNotFound, fmt.Sprintf("file %s not found in manifest", path))
+	}
+	return entry, nil
+}
+
+// putBlob uploads the content read from body to the location of its digest, unless the resource already has a blob
+// with the same content. It returns the manifest entry of the file located in path.
+func (c *contentAddressed) putBlob(ctx context.Context, resource Resource, path string, body io.Reader) (manifestEntry, error) {
+	// The digest must be known before uploading the content, bodies that cannot be read twice are copied to a
+	// temporary file.
+	rs, ok := body.(io.ReadSeeker)
+	if !ok {
+		tmp, err := os.CreateTemp("", "gz-storage-*")
+		if err != nil {
+			return manifestEntry{}, err
+		}
+		defer func() {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}()
+		if _, err = io.Copy(tmp, body); err != nil {
+			return manifestEntry{}, err
+		}
+		if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+			return manifestEntry{}, err
+		}
+		rs = tmp
+	}
+
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return manifestEntry{}, err
+	}
+	sha, md := sha256.New(), md5.New()
+	size, err := io.Copy(io.MultiWriter(sha, md), rs)
+	if err != nil {
+		return manifestEntry{}, err
+	}
+	entry := manifestEntry{
+		Path:    path,
+		Size:    size,
+		SHA256:  hex.EncodeToString(sha.Sum(nil)),
+		MD5:     hex.EncodeToString(md.Sum(nil)),
+		ModTime: time.Now().UTC(),
+	}
+
+	location := getBlobLocation(resource.GetOwner(), resource.GetUUID(), entry.SHA256)
+	modTime, err := c.objects.modTime(ctx, location)
+	if err != nil && !errors.Is(err, ErrResourceNotFound) {
+		return manifestEntry{}, err
+	}
+	// Stored content is reused unless CollectGarbage could remove it before the manifest that references it is
+	// updated. Uploading it again resets its modification time.
+	if err == nil && (c.options.gracePeriod <= 0 || time.Since(modTime) < c.options.gracePeriod/2) {
+		return entry, nil
+	}
+	if _, err = rs.Seek(start, io.SeekStart); err != nil {
+		return manifestEntry{}, err
+	}
+	if err = c.objects.put(ctx, location, rs); err != nil {
+		return manifestEntry{}, err
+	}
+	return entry, nil
+}
+
+// updateManifest adds the given entries to the manifest of the given resource, and returns the updated manifest. The
+// manifest is created if it doesn't exist. The zip file of the resource is removed, as it no longer matches the
+// manifest.
+func (c *contentAddressed) updateManifest(ctx context.Context, resource Resource, entries ...manifestEntry) (*manifest, error) {
+	location := getManifestLocation(resource)
+	lock, _ := c.manifestLocks.LoadOrStore(location, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	m, err := c.readManifest(ctx, location)
+	if errors.Is(err, ErrResourceNotFound) {
+		m, err = &manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.set(entries...)
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.objects.put(ctx, location, bytes.NewReader(b)); err != nil {
+		return nil, err
+	}
+
+	zip := getZipLocation("", resource)
+	found, err := c.exists(ctx, zip)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		if err = c.objects.remove(ctx, zip, nil); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// readManifest reads the manifest found in location. It returns ErrResourceNotFound if the manifest doesn't exist.
+func (c *contentAddressed) readManifest(ctx context.Context, location string) (*manifest, error) {
+	r, err := c.objects.open(ctx, location, Range{})
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close(r)
+
+	var m manifest
+	if err = json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// exists returns true if there's an object in the given location.
+func (c *contentAddressed) exists(ctx context.Context, location string) (bool, error) {
+	locations, err := c.objects.list(ctx, location)
+	if err != nil {
+		return false, err
+	}
+	for _, l := range locations {
+		if l == location {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getManifestLocation returns the location of the manifest of the given Resource.
+func getManifestLocation(r Resource) string {
+	filename := fmt.Sprintf("%d.json", r.GetVersion())
+	return filepath.Join(r.GetOwner(), r.GetUUID(), ".manifests", filename)
+}
+
+// getBlobLocation returns the location of the content with the given SHA-256 digest for the resource identified by
+// owner and uuid.
+func getBlobLocation(owner string, uuid string, digest string) string {
+	return filepath.Join(owner, uuid, ".blobs", digest[:2], digest)
+}
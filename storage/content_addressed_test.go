@@ -0,0 +1,388 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryObjects is an in-memory bucket used to test the content-addressed layout.
+type memoryObjects struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+	times   map[string]time.Time
+	puts    int
+}
+
+func (m *memoryObjects) funcs() objectFuncs {
+	return objectFuncs{
+		put: func(ctx context.Context, path string, body io.Reader) error {
+			b, err := io.ReadAll(body)
+			if err != nil {
+				return err
+			}
+			m.lock.Lock()
+			defer m.lock.Unlock()
+			m.objects[path] = b
+			m.times[path] = time.Now()
+			m.puts++
+			return nil
+		},
+		open: func(ctx context.Context, location string, rng Range) (io.ReadCloser, error) {
+			m.lock.Lock()
+			defer m.lock.Unlock()
+			b, ok := m.objects[location]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, location)
+			}
+			b = b[rng.Offset:]
+			if rng.Length > 0 {
+				b = b[:rng.Length]
+			}
+			return io.NopCloser(bytes.NewReader(b)), nil
+		},
+		list: func(ctx context.Context, prefix string) ([]string, error) {
+			m.lock.Lock()
+			defer m.lock.Unlock()
+			var out []string
+			for location := range m.objects {
+				if strings.HasPrefix(location, prefix) {
+					out = append(out, location)
+				}
+			}
+			sort.Strings(out)
+			return out, nil
+		},
+		remove: func(ctx context.Context, path string, _ io.Reader) error {
+			m.lock.Lock()
+			defer m.lock.Unlock()
+			delete(m.objects, path)
+			delete(m.times, path)
+			return nil
+		},
+		modTime: func(ctx context.Context, location string) (time.Time, error) {
+			m.lock.Lock()
+			defer m.lock.Unlock()
+			t, ok := m.times[location]
+			if !ok {
+				return time.Time{}, fmt.Errorf("%w: %s", ErrResourceNotFound, location)
+			}
+			return t, nil
+		},
+	}
+}
+
+func (m *memoryObjects) count(prefix string) int {
+	locations, _ := m.funcs().list(context.Background(), prefix)
+	return len(locations)
+}
+
+type ContentAddressedTestSuite struct {
+	suite.Suite
+	objects *memoryObjects
+	storage Storage
+	v1      Resource
+	v2      Resource
+	blobs   string
+}
+
+func TestSuiteContentAddressed(t *testing.T) {
+	suite.Run(t, new(ContentAddressedTestSuite))
+}
+
+func (suite *ContentAddressedTestSuite) SetupTest() {
+	suite.objects = &memoryObjects{objects: make(map[string][]byte), times: make(map[string]time.Time)}
+	suite.storage = newContentAddressed(nil, suite.objects.funcs(), options{})
+	suite.v1 = NewResource(validUUID, owner, 1)
+	suite.v2 = NewResource(validUUID, owner, 2)
+	suite.blobs = getRootLocation("", owner, validUUID) + "/.blobs/"
+
+	suite.Require().NoError(suite.storage.UploadDir(context.Background(), suite.v1, "./testdata/example"))
+}
+
+func (suite *ContentAddressedTestSuite) TestUploadDir_Deduplicates() {
+	suite.Assert().Equal(4, suite.objects.count(suite.blobs))
+
+	// Uploading the same files as a new version only uploads its manifest.
+	puts := suite.objects.puts
+	suite.Require().NoError(suite.storage.UploadDir(context.Background(), suite.v2, "./testdata/example"))
+	suite.Assert().Equal(4, suite.objects.count(suite.blobs))
+	suite.Assert().Equal(puts+1, suite.objects.puts)
+}
+
+func (suite *ContentAddressedTestSuite) TestGetFile() {
+	expected, err := os.ReadFile("./testdata/example/meshes/turtle.dae")
+	suite.Require().NoError(err)
+
+	b, err := suite.storage.GetFile(context.Background(), suite.v1, "/meshes/turtle.dae")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(expected, b)
+
+	_, err = suite.storage.GetFile(context.Background(), suite.v1, "/not_found.sdf")
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+
+	_, err = suite.storage.GetFile(context.Background(), suite.v2, "/model.sdf")
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+}
+
+func (suite *ContentAddressedTestSuite) TestOpenFile() {
+	expected, err := os.ReadFile("./testdata/example/model.sdf")
+	suite.Require().NoError(err)
+
+	r, err := suite.storage.OpenFile(context.Background(), suite.v1, "model.sdf", Range{Offset: 5, Length: 10})
+	suite.Require().NoError(err)
+	b, err := io.ReadAll(r)
+	suite.Require().NoError(err)
+	suite.Require().NoError(r.Close())
+	suite.Assert().Equal(expected[5:15], b)
+
+	_, err = suite.storage.OpenFile(context.Background(), suite.v1, "model.sdf", Range{Offset: int64(len(expected))})
+	suite.Assert().ErrorIs(err, ErrInvalidRange)
+}
+
+func (suite *ContentAddressedTestSuite) TestListFiles() {
+	files, err := suite.storage.ListFiles(context.Background(), suite.v1, "")
+	suite.Require().NoError(err)
+	suite.Require().Len(files, 4)
+	suite.Assert().Equal("meshes/turtle.dae", files[0].Path)
+	suite.Assert().Equal("thumbnails/1.png", files[3].Path)
+
+	files, err = suite.storage.ListFiles(context.Background(), suite.v1, "/thumbnails/")
+	suite.Require().NoError(err)
+	suite.Assert().Len(files, 1)
+
+	files, err = suite.storage.ListFiles(context.Background(), suite.v2, "")
+	suite.Require().NoError(err)
+	suite.Assert().Empty(files)
+}
+
+func (suite *ContentAddressedTestSuite) TestStat() {
+	// The files of the example directory match the files of validResource.
+	expected, err := newFilesystemStorage(basePath).Stat(context.Background(), validResource, "model.sdf")
+	suite.Require().NoError(err)
+
+	info, err := suite.storage.Stat(context.Background(), suite.v1, "model.sdf")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("model.sdf", info.Path)
+	suite.Assert().Equal(expected.Size, info.Size)
+	suite.Assert().Equal(expected.Checksum, info.Checksum)
+}
+
+func (suite *ContentAddressedTestSuite) TestPutFile() {
+	ctx := context.Background()
+
+	// Hide the Seek method of the reader to upload the content from a temporary file.
+	body := struct{ io.Reader }{strings.NewReader("test")}
+	suite.Require().NoError(suite.storage.PutFile(ctx, suite.v1, "model.sdf", body))
+	suite.Assert().Equal(5, suite.objects.count(suite.blobs))
+
+	b, err := suite.storage.GetFile(ctx, suite.v1, "model.sdf")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("test", string(b))
+
+	files, err := suite.storage.ListFiles(ctx, suite.v1, "")
+	suite.Require().NoError(err)
+	suite.Assert().Len(files, 4)
+}
+
+func (suite *ContentAddressedTestSuite) TestPutFile_Concurrent() {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			suite.Assert().NoError(suite.storage.PutFile(ctx, suite.v1, fmt.Sprintf("files/%d.txt", i), strings.NewReader("test")))
+		}(i)
+	}
+	wg.Wait()
+
+	files, err := suite.storage.ListFiles(ctx, suite.v1, "files/")
+	suite.Require().NoError(err)
+	suite.Assert().Len(files, 10)
+}
+
+func (suite *ContentAddressedTestSuite) TestPutFile_RemovesZip() {
+	ctx := context.Background()
+	suite.Require().NoError(suite.storage.RebuildZip(ctx, suite.v1))
+	suite.Require().Equal(1, suite.objects.count(getZipLocation("", suite.v1)))
+
+	suite.Require().NoError(suite.storage.PutFile(ctx, suite.v1, "new.sdf", strings.NewReader("test")))
+	suite.Assert().Zero(suite.objects.count(getZipLocation("", suite.v1)))
+}
+
+func (suite *ContentAddressedTestSuite) TestUploadDir_ZipOnUpload() {
+	ctx := context.Background()
+	suite.storage = newContentAddressed(nil, suite.objects.funcs(), options{zipOnUpload: true})
+	suite.Require().NoError(suite.storage.PutFile(ctx, suite.v2, "new.sdf", strings.NewReader("test")))
+	suite.Require().NoError(suite.storage.UploadDir(ctx, suite.v2, "./testdata/example"))
+
+	// The zip file contains the files that were already stored.
+	r, err := suite.objects.funcs().open(ctx, getZipLocation("", suite.v2), Range{})
+	suite.Require().NoError(err)
+	b, err := io.ReadAll(r)
+	suite.Require().NoError(err)
+	files := readZip(suite.T(), b)
+	suite.Assert().Len(files, 5)
+	suite.Assert().Contains(files, "2/new.sdf")
+}
+
+func (suite *ContentAddressedTestSuite) TestDeleteVersion() {
+	ctx := context.Background()
+	suite.Require().NoError(suite.storage.RebuildZip(ctx, suite.v1))
+	suite.Require().NoError(suite.storage.DeleteVersion(ctx, suite.v1))
+
+	_, err := suite.storage.GetFile(ctx, suite.v1, "model.sdf")
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+	suite.Assert().Zero(suite.objects.count(getZipLocation("", suite.v1)))
+
+	suite.Assert().ErrorIs(suite.storage.DeleteVersion(ctx, suite.v1), ErrResourceNotFound)
+}
+
+func (suite *ContentAddressedTestSuite) TestCollectGarbage() {
+	ctx := context.Background()
+	gc, ok := suite.storage.(GarbageCollector)
+	suite.Require().True(ok)
+
+	suite.Require().NoError(suite.storage.UploadDir(ctx, suite.v2, "./testdata/example"))
+	suite.Require().NoError(suite.storage.PutFile(ctx, suite.v2, "model.sdf", strings.NewReader("test")))
+
+	// All the blobs are referenced by either v1 or v2.
+	removed, err := gc.CollectGarbage(ctx, owner, validUUID)
+	suite.Require().NoError(err)
+	suite.Assert().Zero(removed)
+
+	// The original model.sdf is only referenced by v1.
+	suite.Require().NoError(suite.storage.DeleteVersion(ctx, suite.v1))
+	removed, err = gc.CollectGarbage(ctx, owner, validUUID)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(1, removed)
+	suite.Assert().Equal(4, suite.objects.count(suite.blobs))
+
+	files, err := suite.storage.ListFiles(ctx, suite.v2, "")
+	suite.Require().NoError(err)
+	for _, f := range files {
+		_, err = suite.storage.GetFile(ctx, suite.v2, f.Path)
+		suite.Assert().NoError(err)
+	}
+}
+
+func (suite *ContentAddressedTestSuite) TestCollectGarbage_GracePeriod() {
+	ctx := context.Background()
+	suite.storage = newContentAddressed(nil, suite.objects.funcs(), options{gracePeriod: time.Hour})
+	gc := suite.storage.(GarbageCollector)
+	suite.Require().NoError(suite.storage.DeleteVersion(ctx, suite.v1))
+
+	// Recently uploaded content is kept.
+	removed, err := gc.CollectGarbage(ctx, owner, validUUID)
+	suite.Require().NoError(err)
+	suite.Assert().Zero(removed)
+
+	for location := range suite.objects.times {
+		suite.objects.times[location] = time.Now().Add(-2 * time.Hour)
+	}
+	removed, err = gc.CollectGarbage(ctx, owner, validUUID)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(4, removed)
+}
+
+func (suite *ContentAddressedTestSuite) TestCollectGarbage_ConcurrentUpload() {
+	ctx := context.Background()
+	suite.Require().NoError(suite.storage.DeleteVersion(ctx, suite.v1))
+	for location := range suite.objects.times {
+		suite.objects.times[location] = time.Now().Add(-2 * time.Hour)
+	}
+
+	// Collect garbage after the content of v2 is uploaded, but before its manifest is written.
+	var gc GarbageCollector
+	var removed int
+	var collectErr error
+	funcs := suite.objects.funcs()
+	put := funcs.put
+	funcs.put = func(ctx context.Context, path string, body io.Reader) error {
+		if strings.Contains(path, "/.manifests/") && gc != nil {
+			removed, collectErr = gc.CollectGarbage(ctx, owner, validUUID)
+			gc = nil
+		}
+		return put(ctx, path, body)
+	}
+	suite.storage = newContentAddressed(nil, funcs, options{gracePeriod: time.Hour})
+	gc = suite.storage.(GarbageCollector)
+
+	// Content unreferenced for longer than the grace period is uploaded again instead of being reused.
+	suite.Require().NoError(suite.storage.UploadDir(ctx, suite.v2, "./testdata/example"))
+	suite.Require().NoError(collectErr)
+	suite.Assert().Zero(removed)
+	suite.Assert().Equal(4, suite.objects.count(suite.blobs))
+
+	expected, err := os.ReadFile("./testdata/example/model.sdf")
+	suite.Require().NoError(err)
+	b, err := suite.storage.GetFile(ctx, suite.v2, "model.sdf")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(expected, b)
+
+	// Recently uploaded content is reused.
+	puts := suite.objects.puts
+	suite.Require().NoError(suite.storage.UploadDir(ctx, NewResource(validUUID, owner, 3), "./testdata/example"))
+	suite.Assert().Equal(puts+1, suite.objects.puts)
+}
+
+func (suite *ContentAddressedTestSuite) TestRebuildZip() {
+	ctx := context.Background()
+	suite.Require().NoError(suite.storage.RebuildZip(ctx, suite.v1))
+
+	r, err := suite.objects.funcs().open(ctx, getZipLocation("", suite.v1), Range{})
+	suite.Require().NoError(err)
+	b, err := io.ReadAll(r)
+	suite.Require().NoError(err)
+
+	files := readZip(suite.T(), b)
+	suite.Assert().Len(files, 4)
+	suite.Assert().Contains(files, "1/model.sdf")
+}
+
+func TestNewFS_ContentAddressed(t *testing.T) {
+	ctx := context.Background()
+	s := NewFS(t.TempDir(), ContentAddressed(), GarbageCollectionGracePeriod(0))
+	v1 := NewResource(validUUID, owner, 1)
+	v2 := NewResource(validUUID, owner, 2)
+	require.NoError(t, s.UploadDir(ctx, v1, "./testdata/example"))
+	require.NoError(t, s.UploadDir(ctx, v2, "./testdata/example"))
+
+	expected, err := os.ReadFile("./testdata/example/model.sdf")
+	require.NoError(t, err)
+	b, err := s.GetFile(ctx, v2, "model.sdf")
+	require.NoError(t, err)
+	assert.Equal(t, expected, b)
+
+	files, err := s.ListFiles(ctx, v2, "")
+	require.NoError(t, err)
+	assert.Len(t, files, 4)
+
+	// Zip files are generated from the manifest if they don't exist.
+	zip, err := s.Download(ctx, v2)
+	require.NoError(t, err)
+	b, err = os.ReadFile(zip)
+	require.NoError(t, err)
+	assert.Len(t, readZip(t, b), 4)
+
+	require.NoError(t, s.PutFile(ctx, v2, "model.sdf", strings.NewReader("test")))
+	_, err = os.Stat(zip)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// The original model.sdf is only referenced by v1.
+	require.NoError(t, s.DeleteVersion(ctx, v1))
+	removed, err := s.(GarbageCollector).CollectGarbage(ctx, owner, validUUID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+}
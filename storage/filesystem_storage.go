@@ -2,8 +2,14 @@ package storage
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gazebo-web/gz-go/v10"
 	"github.com/pkg/errors"
@@ -151,6 +157,241 @@ func (s *fileSys) GetFile(ctx context.Context, resource Resource, path string) (
 	return b, nil
 }
 
+// OpenFile returns a reader for the bytes in rng of the file located in path from the given resource.
+func (s *fileSys) OpenFile(ctx context.Context, resource Resource, path string, rng Range) (io.ReadCloser, error) {
+	return OpenFile(ctx, resource, path, rng, s.openFile)
+}
+
+// openFile opens the file located in path from the given resource, positioned at the start of rng.
+func (s *fileSys) openFile(ctx context.Context, resource Resource, path string, rng Range) (io.ReadCloser, error) {
+	return s.openObject(ctx, getLocation("", resource, path), rng)
+}
+
+// openObject opens the file found in location relative to the base path, positioned at the start of rng.
+func (s *fileSys) openObject(ctx context.Context, location string, rng Range) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.basePath, location))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrap(ErrResourceNotFound, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		_ = f.Close()
+		return nil, errors.Wrap(ErrResourceNotFound, "path is a directory")
+	}
+	if rng.Offset > 0 && rng.Offset >= info.Size() {
+		_ = f.Close()
+		return nil, ErrInvalidRange
+	}
+
+	if _, err = f.Seek(rng.Offset, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if rng.Length > 0 {
+		return &limitedReadCloser{Reader: io.LimitReader(f, rng.Length), Closer: f}, nil
+	}
+	return f, nil
+}
+
+// PutFile writes the content read from body to the file located in path in the given resource.
+// The file is written to a temporary file first, and it replaces the existing file once the content is fully written.
+func (s *fileSys) PutFile(ctx context.Context, resource Resource, path string, body io.Reader) error {
+	return PutFile(ctx, resource, path, body, s.writeFile(resource))
+}
+
+// writeFile generates a function that writes a single file in a path relative to the given Resource.
+func (s *fileSys) writeFile(resource Resource) WalkDirFunc {
+	return func(ctx context.Context, path string, body io.Reader) error {
+		return s.writeObject(ctx, getLocation("", resource, path), body)
+	}
+}
+
+// writeObject writes the file found in location relative to the base path.
+func (s *fileSys) writeObject(ctx context.Context, location string, body io.Reader) error {
+	dst := filepath.Join(s.basePath, location)
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// The temporary file no longer exists if it was successfully renamed.
+		_ = os.Remove(tmp.Name())
+	}()
+
+	// Temporary files are only accessible by the current user, use the permissions of regular files instead.
+	if err = tmp.Chmod(0644); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err = io.Copy(tmp, body); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// ListFiles returns the information of the files in the given resource whose path starts with prefix.
+// Checksums are not calculated when listing files, use Stat to get the checksum of a file.
+func (s *fileSys) ListFiles(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error) {
+	return ListFiles(ctx, resource, prefix, s.listFiles)
+}
+
+// listFiles walks the directory of the given resource and returns the files whose path starts with prefix.
+func (s *fileSys) listFiles(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error) {
+	root := getLocation(s.basePath, resource, "")
+	files := []FileInfo{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := getRelativePath(s.basePath, resource, path)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(rel, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, FileInfo{
+			Path:    rel,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return []FileInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Stat returns the information of the file located in path from the given resource.
+// The checksum is calculated by reading the whole file.
+func (s *fileSys) Stat(ctx context.Context, resource Resource, path string) (FileInfo, error) {
+	return Stat(ctx, resource, path, s.stat)
+}
+
+// stat returns the information of the file located in path from the given resource.
+func (s *fileSys) stat(ctx context.Context, resource Resource, path string) (FileInfo, error) {
+	f, err := os.Open(getLocation(s.basePath, resource, path))
+	if errors.Is(err, os.ErrNotExist) {
+		return FileInfo{}, errors.Wrap(ErrResourceNotFound, err.Error())
+	}
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer gz.Close(f)
+
+	info, err := f.Stat()
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if info.IsDir() {
+		return FileInfo{}, errors.Wrap(ErrResourceNotFound, "path is a directory")
+	}
+
+	h := md5.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return FileInfo{}, err
+	}
+
+	return FileInfo{
+		Path:     filepath.ToSlash(path),
+		Size:     info.Size(),
+		Checksum: hex.EncodeToString(h.Sum(nil)),
+		ModTime:  info.ModTime(),
+	}, nil
+}
+
+// DeleteVersion removes the directory and the zip file of the given resource version.
+func (s *fileSys) DeleteVersion(ctx context.Context, resource Resource) error {
+	if err := validateResource(resource); err != nil {
+		return err
+	}
+
+	path := getLocation(s.basePath, resource, "")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(ErrResourceNotFound, err.Error())
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+
+	err := os.Remove(getZipLocation(s.basePath, resource))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// DeleteResource removes the directory that contains all the versions and zip files of the given resource.
+func (s *fileSys) DeleteResource(ctx context.Context, owner string, uuid string) error {
+	if err := validateOwner(owner); err != nil {
+		return err
+	}
+	if err := validateUUID(uuid); err != nil {
+		return err
+	}
+
+	path := getRootLocation(s.basePath, owner, uuid)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(ErrResourceNotFound, err.Error())
+	}
+	return os.RemoveAll(path)
+}
+
+// RebuildZip removes the zip file of the given resource and generates it again from the resource directory.
+func (s *fileSys) RebuildZip(ctx context.Context, resource Resource) error {
+	if err := validateResource(resource); err != nil {
+		return err
+	}
+
+	path := getLocation(s.basePath, resource, "")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(ErrResourceNotFound, err.Error())
+	}
+
+	dst := getZipLocation(s.basePath, resource)
+	if err := gz.RemoveIfFound(dst); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	_, err := s.zip(ctx, resource)
+	return err
+}
+
+// limitedReadCloser is an io.ReadCloser that reads from a limited Reader and closes the underlying Closer.
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
 // zip compresses the given resource to a zip file and returns the path to the zip file.
 // If the file was already created, it returns a cached file.
 func (s *fileSys) zip(ctx context.Context, resource Resource) (string, error) {
@@ -164,10 +405,96 @@ func (s *fileSys) zip(ctx context.Context, resource Resource) (string, error) {
 	return target, nil
 }
 
+// listObjects returns the locations relative to the base path of the files whose location starts with prefix.
+// Temporary files of writes in progress are not listed.
+func (s *fileSys) listObjects(ctx context.Context, prefix string) ([]string, error) {
+	root := filepath.Join(s.basePath, prefix)
+	if !strings.HasSuffix(prefix, "/") {
+		root = filepath.Dir(root)
+	}
+	var locations []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		location, err := filepath.Rel(s.basePath, path)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(location, filepath.FromSlash(prefix)) {
+			locations = append(locations, location)
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// removeObject removes the file found in location relative to the base path. Missing files are ignored.
+func (s *fileSys) removeObject(ctx context.Context, location string, _ io.Reader) error {
+	err := os.Remove(filepath.Join(s.basePath, location))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// modTime returns the last modification time of the file found in location relative to the base path.
+func (s *fileSys) modTime(ctx context.Context, location string) (time.Time, error) {
+	info, err := os.Stat(filepath.Join(s.basePath, location))
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, errors.Wrap(ErrResourceNotFound, err.Error())
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// contentAddressedFileSys provides the operations of a fileSys that don't depend on the layout to the
+// content-addressed layout.
+type contentAddressedFileSys struct {
+	*fileSys
+}
+
+// Download returns the path to the zip file of the given resource. The zip file is generated by the content-addressed
+// layout before calling Download.
+func (s contentAddressedFileSys) Download(ctx context.Context, resource Resource) (string, error) {
+	if err := validateResource(resource); err != nil {
+		return "", err
+	}
+	return getZipLocation(s.basePath, resource), nil
+}
+
+// NewFS initializes a new implementation of Storage using the host filesystem. All resources are stored in the
+// directory found in basePath.
+func NewFS(basePath string, opts ...Option) Storage {
+	s := &fileSys{
+		basePath: basePath,
+	}
+	o := newOptions(opts)
+	if !o.contentAddressed {
+		return s
+	}
+	return newContentAddressed(contentAddressedFileSys{fileSys: s}, objectFuncs{
+		put:     s.writeObject,
+		open:    s.openObject,
+		list:    s.listObjects,
+		remove:  s.removeObject,
+		modTime: s.modTime,
+	}, o)
+}
+
 // newFilesystemStorage initializes a new Storage implementation using the host FileSystem.
 // It receives the base path as an argument, where all resources are stored.
 func newFilesystemStorage(path string) Storage {
-	return &fileSys{
-		basePath: path,
-	}
+	return NewFS(path)
 }
@@ -1,11 +1,16 @@
 package storage
 
 import (
+	"archive/zip"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	uuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/suite"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -328,3 +333,251 @@ func (suite *FilesystemStorageTestSuite) TestUploadZip_SuccessWhenFileAlreadyExi
 	suite.Assert().Equal(before, after)
 
 }
+
+func (suite *FilesystemStorageTestSuite) TestOpenFile_InvalidResource() {
+	_, err := suite.storage.OpenFile(context.Background(), invalidResource, "/model.sdf", Range{})
+	suite.Assert().ErrorIs(err, ErrResourceInvalidFormat)
+}
+
+func (suite *FilesystemStorageTestSuite) TestOpenFile_InvalidRange() {
+	_, err := suite.storage.OpenFile(context.Background(), validResource, "/model.sdf", Range{Offset: -1})
+	suite.Assert().ErrorIs(err, ErrInvalidRange)
+
+	// Offset is past the end of the file
+	_, err = suite.storage.OpenFile(context.Background(), validResource, "/model.sdf", Range{Offset: 1 << 30})
+	suite.Assert().ErrorIs(err, ErrInvalidRange)
+}
+
+func (suite *FilesystemStorageTestSuite) TestOpenFile_InvalidPath() {
+	_, err := suite.storage.OpenFile(context.Background(), validResource, "/", Range{})
+	suite.Assert().ErrorIs(err, ErrInvalidPath)
+}
+
+func (suite *FilesystemStorageTestSuite) TestOpenFile_NotFound() {
+	_, err := suite.storage.OpenFile(context.Background(), validResource, "/not_found.sdf", Range{})
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+
+	_, err = suite.storage.OpenFile(context.Background(), validResource, "/meshes", Range{})
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+}
+
+func (suite *FilesystemStorageTestSuite) TestOpenFile_Success() {
+	expected, err := os.ReadFile("./testdata/OpenRobotics/e6af5323-db4d-4db3-a402-a8992d6c8d99/1/model.sdf")
+	suite.Require().NoError(err)
+
+	r, err := suite.storage.OpenFile(context.Background(), validResource, "/model.sdf", Range{})
+	suite.Require().NoError(err)
+	b, err := io.ReadAll(r)
+	suite.Require().NoError(err)
+	suite.Require().NoError(r.Close())
+	suite.Assert().Equal(expected, b)
+}
+
+func (suite *FilesystemStorageTestSuite) TestOpenFile_Range() {
+	expected, err := os.ReadFile("./testdata/OpenRobotics/e6af5323-db4d-4db3-a402-a8992d6c8d99/1/model.sdf")
+	suite.Require().NoError(err)
+
+	r, err := suite.storage.OpenFile(context.Background(), validResource, "/model.sdf", Range{Offset: 5, Length: 10})
+	suite.Require().NoError(err)
+	b, err := io.ReadAll(r)
+	suite.Require().NoError(err)
+	suite.Require().NoError(r.Close())
+	suite.Assert().Equal(expected[5:15], b)
+
+	r, err = suite.storage.OpenFile(context.Background(), validResource, "/model.sdf", Range{Offset: 5})
+	suite.Require().NoError(err)
+	b, err = io.ReadAll(r)
+	suite.Require().NoError(err)
+	suite.Require().NoError(r.Close())
+	suite.Assert().Equal(expected[5:], b)
+}
+
+func (suite *FilesystemStorageTestSuite) TestPutFile_InvalidResource() {
+	err := suite.storage.PutFile(context.Background(), invalidResource, "/model.sdf", strings.NewReader("test"))
+	suite.Assert().ErrorIs(err, ErrResourceInvalidFormat)
+}
+
+func (suite *FilesystemStorageTestSuite) TestPutFile_BodyNil() {
+	err := suite.storage.PutFile(context.Background(), nonExistentResource, "/model.sdf", nil)
+	suite.Assert().ErrorIs(err, ErrFileNil)
+}
+
+func (suite *FilesystemStorageTestSuite) TestPutFile_Success() {
+	ctx := context.Background()
+	suite.Require().NoError(suite.storage.PutFile(ctx, nonExistentResource, "/meshes/test.dae", strings.NewReader("test")))
+
+	b, err := suite.storage.GetFile(ctx, nonExistentResource, "/meshes/test.dae")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("test", string(b))
+
+	// Uploading the same file again replaces its content.
+	suite.Require().NoError(suite.storage.PutFile(ctx, nonExistentResource, "/meshes/test.dae", strings.NewReader("updated")))
+
+	b, err = suite.storage.GetFile(ctx, nonExistentResource, "/meshes/test.dae")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("updated", string(b))
+}
+
+func (suite *FilesystemStorageTestSuite) TestPutFile_PathOutsideResource() {
+	ctx := context.Background()
+	suite.Require().NoError(suite.storage.PutFile(ctx, nonExistentResource, "../../test.sdf", strings.NewReader("test")))
+
+	b, err := suite.storage.GetFile(ctx, nonExistentResource, "/test.sdf")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("test", string(b))
+}
+
+func (suite *FilesystemStorageTestSuite) TestListFiles_InvalidResource() {
+	_, err := suite.storage.ListFiles(context.Background(), invalidResource, "")
+	suite.Assert().ErrorIs(err, ErrResourceInvalidFormat)
+}
+
+func (suite *FilesystemStorageTestSuite) TestListFiles_NotFound() {
+	files, err := suite.storage.ListFiles(context.Background(), nonExistentResource, "")
+	suite.Require().NoError(err)
+	suite.Assert().Empty(files)
+}
+
+func (suite *FilesystemStorageTestSuite) TestListFiles_Success() {
+	files, err := suite.storage.ListFiles(context.Background(), validResource, "")
+	suite.Require().NoError(err)
+	suite.Require().Len(files, 4)
+	suite.Assert().Equal("meshes/turtle.dae", files[0].Path)
+	suite.Assert().Equal("model.config", files[1].Path)
+	suite.Assert().Equal("model.sdf", files[2].Path)
+	suite.Assert().Equal("thumbnails/1.png", files[3].Path)
+
+	info, err := os.Stat("./testdata/OpenRobotics/e6af5323-db4d-4db3-a402-a8992d6c8d99/1/model.sdf")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(info.Size(), files[2].Size)
+	suite.Assert().Equal(info.ModTime(), files[2].ModTime)
+}
+
+func (suite *FilesystemStorageTestSuite) TestListFiles_Prefix() {
+	files, err := suite.storage.ListFiles(context.Background(), validResource, "/meshes/")
+	suite.Require().NoError(err)
+	suite.Require().Len(files, 1)
+	suite.Assert().Equal("meshes/turtle.dae", files[0].Path)
+
+	files, err = suite.storage.ListFiles(context.Background(), validResource, "model.")
+	suite.Require().NoError(err)
+	suite.Assert().Len(files, 2)
+}
+
+func (suite *FilesystemStorageTestSuite) TestStat_InvalidResource() {
+	_, err := suite.storage.Stat(context.Background(), invalidResource, "/model.sdf")
+	suite.Assert().ErrorIs(err, ErrResourceInvalidFormat)
+}
+
+func (suite *FilesystemStorageTestSuite) TestStat_NotFound() {
+	_, err := suite.storage.Stat(context.Background(), validResource, "/not_found.sdf")
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+
+	_, err = suite.storage.Stat(context.Background(), validResource, "/meshes")
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+}
+
+func (suite *FilesystemStorageTestSuite) TestStat_Success() {
+	b, err := os.ReadFile("./testdata/OpenRobotics/e6af5323-db4d-4db3-a402-a8992d6c8d99/1/meshes/turtle.dae")
+	suite.Require().NoError(err)
+	checksum := md5.Sum(b)
+
+	info, err := suite.storage.Stat(context.Background(), validResource, "/meshes/turtle.dae")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("meshes/turtle.dae", info.Path)
+	suite.Assert().Equal(int64(len(b)), info.Size)
+	suite.Assert().Equal(hex.EncodeToString(checksum[:]), info.Checksum)
+	suite.Assert().False(info.ModTime.IsZero())
+}
+
+func (suite *FilesystemStorageTestSuite) TestDeleteVersion_InvalidResource() {
+	err := suite.storage.DeleteVersion(context.Background(), invalidResource)
+	suite.Assert().ErrorIs(err, ErrResourceInvalidFormat)
+}
+
+func (suite *FilesystemStorageTestSuite) TestDeleteVersion_NotFound() {
+	err := suite.storage.DeleteVersion(context.Background(), nonExistentResource)
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+}
+
+func (suite *FilesystemStorageTestSuite) TestDeleteVersion_Success() {
+	ctx := context.Background()
+	v1 := nonExistentResource
+	v2 := NewResource(v1.GetUUID(), v1.GetOwner(), 2)
+	suite.Require().NoError(suite.storage.UploadDir(ctx, v1, "./testdata/example"))
+	suite.Require().NoError(suite.storage.PutFile(ctx, v2, "model.sdf", strings.NewReader("test")))
+	suite.Require().NoError(os.MkdirAll(filepath.Dir(getZipLocation(basePath, v1)), os.ModePerm))
+	_, err := suite.storage.Download(ctx, v1)
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.storage.DeleteVersion(ctx, v1))
+
+	_, err = os.Stat(getLocation(basePath, v1, ""))
+	suite.Assert().ErrorIs(err, os.ErrNotExist)
+	_, err = os.Stat(getZipLocation(basePath, v1))
+	suite.Assert().ErrorIs(err, os.ErrNotExist)
+
+	// Other versions are kept
+	_, err = suite.storage.GetFile(ctx, v2, "model.sdf")
+	suite.Assert().NoError(err)
+}
+
+func (suite *FilesystemStorageTestSuite) TestDeleteResource_InvalidResource() {
+	err := suite.storage.DeleteResource(context.Background(), "", validUUID)
+	suite.Assert().ErrorIs(err, ErrResourceInvalidFormat)
+
+	err = suite.storage.DeleteResource(context.Background(), owner, "")
+	suite.Assert().ErrorIs(err, ErrResourceInvalidFormat)
+}
+
+func (suite *FilesystemStorageTestSuite) TestDeleteResource_NotFound() {
+	err := suite.storage.DeleteResource(context.Background(), nonExistentResource.GetOwner(), nonExistentResource.GetUUID())
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+}
+
+func (suite *FilesystemStorageTestSuite) TestDeleteResource_Success() {
+	ctx := context.Background()
+	v1 := nonExistentResource
+	v2 := NewResource(v1.GetUUID(), v1.GetOwner(), 2)
+	suite.Require().NoError(suite.storage.UploadDir(ctx, v1, "./testdata/example"))
+	suite.Require().NoError(suite.storage.PutFile(ctx, v2, "model.sdf", strings.NewReader("test")))
+	suite.Require().NoError(os.MkdirAll(filepath.Dir(getZipLocation(basePath, v2)), os.ModePerm))
+	_, err := suite.storage.Download(ctx, v2)
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.storage.DeleteResource(ctx, v1.GetOwner(), v1.GetUUID()))
+
+	_, err = os.Stat(getRootLocation(basePath, v1.GetOwner(), v1.GetUUID()))
+	suite.Assert().ErrorIs(err, os.ErrNotExist)
+}
+
+func (suite *FilesystemStorageTestSuite) TestRebuildZip_InvalidResource() {
+	err := suite.storage.RebuildZip(context.Background(), invalidResource)
+	suite.Assert().ErrorIs(err, ErrResourceInvalidFormat)
+}
+
+func (suite *FilesystemStorageTestSuite) TestRebuildZip_NotFound() {
+	err := suite.storage.RebuildZip(context.Background(), nonExistentResource)
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+}
+
+func (suite *FilesystemStorageTestSuite) TestRebuildZip_Success() {
+	ctx := context.Background()
+	r := nonExistentResource
+	suite.Require().NoError(suite.storage.UploadDir(ctx, r, "./testdata/example"))
+	suite.Require().NoError(suite.storage.RebuildZip(ctx, r))
+
+	// Files added after the zip file was generated are included when rebuilding it.
+	suite.Require().NoError(suite.storage.PutFile(ctx, r, "new.sdf", strings.NewReader("test")))
+	suite.Require().NoError(suite.storage.RebuildZip(ctx, r))
+
+	zr, err := zip.OpenReader(getZipLocation(basePath, r))
+	suite.Require().NoError(err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	suite.Require().NoError(zr.Close())
+	suite.Assert().Contains(names, "1/new.sdf")
+	suite.Assert().Contains(names, "1/model.sdf")
+}
@@ -2,13 +2,17 @@ package storage
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"time"
 
 	"cloud.google.com/go/storage"
-	"github.com/gazebo-web/gz-go/v10"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 )
 
 // gcs implements Storage using the Google Cloud Storage (GCS) service.
@@ -41,6 +45,9 @@ type gcs struct {
 
 	// duration defines the lifespan of a Pre-signed URL.
 	duration time.Duration
+
+	// options contains the optional settings provided when initializing the storage.
+	options options
 }
 
 // getObjectGCS is a helper function that gets the object reference in the given bucket identified by the given path.
@@ -59,8 +66,23 @@ func (g *gcs) UploadZip(ctx context.Context, resource Resource, file *os.File) e
 }
 
 // UploadDir uploads the entire src directory to GCS.
+//
+//	If ZipOnUpload was provided when initializing the storage, the zip file of the resource is also generated and
+//	uploaded.
 func (g *gcs) UploadDir(ctx context.Context, resource Resource, src string) error {
-	return UploadDir(ctx, resource, src, uploadFileGCS(g.client, g.bucket, resource))
+	if err := UploadDir(ctx, resource, src, uploadFileGCS(g.client, g.bucket, resource)); err != nil {
+		return err
+	}
+	if !g.options.zipOnUpload {
+		return nil
+	}
+	return UploadDirZip(ctx, resource, src, uploadFileGCS(g.client, g.bucket, nil))
+}
+
+// RebuildZip generates the zip file of the given resource from the files stored in GCS, and uploads it.
+func (g *gcs) RebuildZip(ctx context.Context, resource Resource) error {
+	return RebuildZip(ctx, resource, listFilesGCS(g.client, g.bucket), readFileGCS(g.client, g.bucket),
+		uploadFileGCS(g.client, g.bucket, nil))
 }
 
 // Download returns the URL to a zip file that contains all the contents of the given Resource.
@@ -98,6 +120,161 @@ func (g *gcs) GetFile(ctx context.Context, resource Resource, path string) ([]by
 	return ReadFile(ctx, resource, path, readFileGCS(g.client, g.bucket))
 }
 
+// OpenFile returns a reader for the bytes in rng of the file located in path from the given resource.
+func (g *gcs) OpenFile(ctx context.Context, resource Resource, path string, rng Range) (io.ReadCloser, error) {
+	return OpenFile(ctx, resource, path, rng, openFileGCS(g.client, g.bucket))
+}
+
+// PutFile streams the content read from body to the file located in path in the given resource.
+func (g *gcs) PutFile(ctx context.Context, resource Resource, path string, body io.Reader) error {
+	return PutFile(ctx, resource, path, body, uploadFileGCS(g.client, g.bucket, resource))
+}
+
+// ListFiles returns the information of the files in the given resource whose path starts with prefix.
+func (g *gcs) ListFiles(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error) {
+	return ListFiles(ctx, resource, prefix, listFilesGCS(g.client, g.bucket))
+}
+
+// Stat returns the information of the file located in path from the given resource.
+func (g *gcs) Stat(ctx context.Context, resource Resource, path string) (FileInfo, error) {
+	return Stat(ctx, resource, path, statGCS(g.client, g.bucket))
+}
+
+// DeleteVersion removes all the files of the given resource version from GCS, including its zip file.
+func (g *gcs) DeleteVersion(ctx context.Context, resource Resource) error {
+	return DeleteVersion(ctx, resource, listGCS(g.client, g.bucket), deleteFileGCS(g.client, g.bucket, nil))
+}
+
+// DeleteResource removes all the versions and zip files of the given resource from GCS.
+func (g *gcs) DeleteResource(ctx context.Context, owner string, uuid string) error {
+	return DeleteResource(ctx, owner, uuid, listGCS(g.client, g.bucket), deleteFileGCS(g.client, g.bucket, nil))
+}
+
+// listGCS generates a function that contains the interaction with GCS to find the name of the objects that start
+// with a prefix.
+func listGCS(client *storage.Client, bucket string) ListFunc {
+	return func(ctx context.Context, prefix string) ([]string, error) {
+		it := client.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+		var names []string
+		for {
+			attrs, err := it.Next()
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, attrs.Name)
+		}
+		return names, nil
+	}
+}
+
+// listFilesGCS generates a function that contains the interaction with GCS to list the files of a resource.
+func listFilesGCS(client *storage.Client, bucket string) ListFilesFunc {
+	return func(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error) {
+		it := client.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: getPrefixLocation(resource, prefix)})
+		files := []FileInfo{}
+		for {
+			attrs, err := it.Next()
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			info, err := newFileInfoGCS(resource, attrs)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, info)
+		}
+		return files, nil
+	}
+}
+
+// statGCS generates a function that contains the interaction with GCS to get the information of a file.
+func statGCS(client *storage.Client, bucket string) StatFunc {
+	return func(ctx context.Context, resource Resource, path string) (FileInfo, error) {
+		attrs, err := getObjectGCS(client, bucket, getLocation("", resource, path)).Attrs(ctx)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return FileInfo{}, fmt.Errorf("%w: %s", ErrResourceNotFound, err)
+		}
+		if err != nil {
+			return FileInfo{}, err
+		}
+		return newFileInfoGCS(resource, attrs)
+	}
+}
+
+// newFileInfoGCS converts the attributes of a GCS object of the given resource to FileInfo.
+func newFileInfoGCS(resource Resource, attrs *storage.ObjectAttrs) (FileInfo, error) {
+	path, err := getRelativePath("", resource, attrs.Name)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return FileInfo{
+		Path:     path,
+		Size:     attrs.Size,
+		Checksum: hex.EncodeToString(attrs.MD5),
+		ModTime:  attrs.Updated,
+	}, nil
+}
+
+// modTimeGCS generates a function that contains the interaction with GCS to get the last modification time of the
+// object found in a location.
+func modTimeGCS(client *storage.Client, bucket string) modTimeFunc {
+	return func(ctx context.Context, location string) (time.Time, error) {
+		attrs, err := getObjectGCS(client, bucket, location).Attrs(ctx)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return time.Time{}, fmt.Errorf("%w: %s", ErrResourceNotFound, err)
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		return attrs.Updated, nil
+	}
+}
+
+// openFileGCS generates a function that contains the interaction with GCS to read a range of bytes of a file.
+func openFileGCS(client *storage.Client, bucket string) OpenFileFunc {
+	open := openObjectGCS(client, bucket)
+	return func(ctx context.Context, resource Resource, path string, rng Range) (io.ReadCloser, error) {
+		return open(ctx, getLocation("", resource, path), rng)
+	}
+}
+
+// openObjectGCS generates a function that contains the interaction with GCS to read a range of bytes of the object
+// found in a location.
+func openObjectGCS(client *storage.Client, bucket string) openObjectFunc {
+	return func(ctx context.Context, location string, rng Range) (io.ReadCloser, error) {
+		length := rng.Length
+		if length == 0 {
+			// A negative length reads the object until its end.
+			length = -1
+		}
+		obj := getObjectGCS(client, bucket, location)
+		r, err := obj.NewRangeReader(ctx, rng.Offset, length)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, err)
+		}
+		if isInvalidRangeGCS(err) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRange, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+}
+
+// isInvalidRangeGCS returns true if err is a GCS error caused by requesting a range that starts after the end of an
+// object.
+func isInvalidRangeGCS(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusRequestedRangeNotSatisfiable
+}
+
 // readFileGCS generates a function that contains the interaction with GCS to read the contents of a file.
 func readFileGCS(client *storage.Client, bucket string) ReadFileFunc {
 	return func(ctx context.Context, resource Resource, path string) (io.ReadCloser, error) {
@@ -119,15 +296,22 @@ func uploadFileGCS(client *storage.Client, bucket string, resource Resource) Wal
 			path = getLocation("", resource, path)
 		}
 
+		// Cancelling the context used to create the writer discards the upload. This prevents partial uploads from
+		// being committed when body fails to be read.
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
 		obj := getObjectGCS(client, bucket, path)
 		w := obj.NewWriter(ctx)
-		defer gz.Close(w)
 
 		if _, err := io.Copy(w, body); err != nil {
+			cancel()
+			_ = w.Close()
 			return err
 		}
 
-		return nil
+		// Uploads are committed when the writer is closed, errors returned by Close indicate that the upload failed.
+		return w.Close()
 	}
 }
 
@@ -150,12 +334,23 @@ func deleteFileGCS(client *storage.Client, bucket string, resource Resource) Wal
 }
 
 // NewGCS initializes a new implementation of Storage using the Google Cloud Storage service.
-func NewGCS(client *storage.Client, bucket string, pk []byte, accessID string) Storage {
-	return &gcs{
+func NewGCS(client *storage.Client, bucket string, pk []byte, accessID string, opts ...Option) Storage {
+	g := &gcs{
 		client:     client,
 		bucket:     bucket,
 		accessID:   accessID,
 		privateKey: pk,
 		duration:   5 * time.Minute,
+		options:    newOptions(opts),
+	}
+	if !g.options.contentAddressed {
+		return g
 	}
+	return newContentAddressed(g, objectFuncs{
+		put:     uploadFileGCS(client, bucket, nil),
+		open:    openObjectGCS(client, bucket),
+		list:    listGCS(client, bucket),
+		remove:  deleteFileGCS(client, bucket, nil),
+		modTime: modTimeGCS(client, bucket),
+	}, g.options)
 }
@@ -14,6 +14,8 @@ var (
 	ErrSourceFolderEmpty     = errors.New("source folder is empty")
 	ErrSourceFile            = errors.New("source is a file, should be a folder")
 	ErrFileNil               = errors.New("no file provided")
+	ErrInvalidRange          = errors.New("invalid byte range")
+	ErrInvalidPath           = errors.New("invalid file path")
 )
 
 // Resource represents the resource that a user wants to download from a cloud storage.
@@ -2,10 +2,14 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	s3api "github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"io"
+	"net/http"
 	"os"
 	"time"
 )
@@ -21,6 +25,7 @@ type s3v1 struct {
 	uploader *s3manager.Uploader
 	bucket   string
 	duration time.Duration
+	options  options
 }
 
 // GetFile reads the content of a file located in path from the given Resource.
@@ -55,8 +60,23 @@ func (s *s3v1) Download(ctx context.Context, resource Resource) (string, error)
 }
 
 // UploadDir uploads the entire src directory to S3.
+//
+//	If ZipOnUpload was provided when initializing the storage, the zip file of the resource is also generated and
+//	uploaded.
 func (s *s3v1) UploadDir(ctx context.Context, resource Resource, src string) error {
-	return UploadDir(ctx, resource, src, uploadFileS3v1(s.uploader, s.bucket, resource))
+	if err := UploadDir(ctx, resource, src, uploadFileS3v1(s.uploader, s.bucket, resource)); err != nil {
+		return err
+	}
+	if !s.options.zipOnUpload {
+		return nil
+	}
+	return UploadDirZip(ctx, resource, src, uploadFileS3v1(s.uploader, s.bucket, nil))
+}
+
+// RebuildZip generates the zip file of the given resource from the files stored in S3, and uploads it.
+func (s *s3v1) RebuildZip(ctx context.Context, resource Resource) error {
+	return RebuildZip(ctx, resource, listFilesS3v1(s.client, s.bucket), readFileS3v1(s.client, s.bucket),
+		uploadFileS3v1(s.uploader, s.bucket, nil))
 }
 
 // UploadZip uploads a zip file of the given resource to S3. It should be called before any attempts to Download
@@ -68,14 +88,35 @@ func (s *s3v1) UploadZip(ctx context.Context, resource Resource, file *os.File)
 	return UploadZip(ctx, resource, file, uploadFileS3v1(s.uploader, s.bucket, nil))
 }
 
+// OpenFile returns a reader for the bytes in rng of the file located in path from the given resource.
+func (s *s3v1) OpenFile(ctx context.Context, resource Resource, path string, rng Range) (io.ReadCloser, error) {
+	return OpenFile(ctx, resource, path, rng, openFileS3v1(s.client, s.bucket))
+}
+
+// PutFile streams the content read from body to the file located in path in the given resource.
+func (s *s3v1) PutFile(ctx context.Context, resource Resource, path string, body io.Reader) error {
+	return PutFile(ctx, resource, path, body, uploadFileS3v1(s.uploader, s.bucket, resource))
+}
+
 // NewS3v1 initializes a new implementation of Storage using the AWS S3 v1 service.
-func NewS3v1(client *s3api.S3, uploader *s3manager.Uploader, bucket string) Storage {
-	return &s3v1{
+func NewS3v1(client *s3api.S3, uploader *s3manager.Uploader, bucket string, opts ...Option) Storage {
+	s := &s3v1{
 		client:   client,
 		uploader: uploader,
 		bucket:   bucket,
 		duration: 5 * time.Minute,
+		options:  newOptions(opts),
+	}
+	if !s.options.contentAddressed {
+		return s
 	}
+	return newContentAddressed(s, objectFuncs{
+		put:     uploadFileS3v1(uploader, bucket, nil),
+		open:    openObjectS3v1(client, bucket),
+		list:    listS3v1(client, bucket),
+		remove:  deleteFileS3v1(client, bucket, nil),
+		modTime: modTimeS3v1(client, bucket),
+	}, s.options)
 }
 
 // readFileS3v1 generates a function that contains the interaction with S3 to read the contents of a file.
@@ -92,6 +133,168 @@ func readFileS3v1(client *s3api.S3, bucket string) ReadFileFunc {
 	}
 }
 
+// ListFiles returns the information of the files in the given resource whose path starts with prefix.
+func (s *s3v1) ListFiles(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error) {
+	return ListFiles(ctx, resource, prefix, listFilesS3v1(s.client, s.bucket))
+}
+
+// Stat returns the information of the file located in path from the given resource.
+func (s *s3v1) Stat(ctx context.Context, resource Resource, path string) (FileInfo, error) {
+	return Stat(ctx, resource, path, statS3v1(s.client, s.bucket))
+}
+
+// DeleteVersion removes all the files of the given resource version from S3, including its zip file.
+func (s *s3v1) DeleteVersion(ctx context.Context, resource Resource) error {
+	return DeleteVersion(ctx, resource, listS3v1(s.client, s.bucket), deleteFileS3v1(s.client, s.bucket, nil))
+}
+
+// DeleteResource removes all the versions and zip files of the given resource from S3.
+func (s *s3v1) DeleteResource(ctx context.Context, owner string, uuid string) error {
+	return DeleteResource(ctx, owner, uuid, listS3v1(s.client, s.bucket), deleteFileS3v1(s.client, s.bucket, nil))
+}
+
+// listS3v1 generates a function that contains the interaction with S3 to find the keys of the objects that start
+// with a prefix.
+func listS3v1(client *s3api.S3, bucket string) ListFunc {
+	return func(ctx context.Context, prefix string) ([]string, error) {
+		var keys []string
+		err := client.ListObjectsV2PagesWithContext(ctx, &s3api.ListObjectsV2Input{
+			Bucket: aws.String(bucket),
+			Prefix: aws.String(prefix),
+		}, func(out *s3api.ListObjectsV2Output, _ bool) bool {
+			for _, obj := range out.Contents {
+				keys = append(keys, aws.StringValue(obj.Key))
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		return keys, nil
+	}
+}
+
+// listFilesS3v1 generates a function that contains the interaction with S3 to list the files of a resource.
+func listFilesS3v1(client *s3api.S3, bucket string) ListFilesFunc {
+	return func(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error) {
+		files := []FileInfo{}
+		var walkErr error
+		err := client.ListObjectsV2PagesWithContext(ctx, &s3api.ListObjectsV2Input{
+			Bucket: aws.String(bucket),
+			Prefix: aws.String(getPrefixLocation(resource, prefix)),
+		}, func(out *s3api.ListObjectsV2Output, _ bool) bool {
+			for _, obj := range out.Contents {
+				path, err := getRelativePath("", resource, aws.StringValue(obj.Key))
+				if err != nil {
+					walkErr = err
+					return false
+				}
+				files = append(files, FileInfo{
+					Path:     path,
+					Size:     aws.Int64Value(obj.Size),
+					Checksum: checksumFromETag(aws.StringValue(obj.ETag)),
+					ModTime:  aws.TimeValue(obj.LastModified),
+				})
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		if walkErr != nil {
+			return nil, walkErr
+		}
+		return files, nil
+	}
+}
+
+// statS3v1 generates a function that contains the interaction with S3 to get the information of a file.
+func statS3v1(client *s3api.S3, bucket string) StatFunc {
+	return func(ctx context.Context, resource Resource, path string) (FileInfo, error) {
+		out, err := client.HeadObjectWithContext(ctx, &s3api.HeadObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(getLocation("", resource, path)),
+		})
+		// HEAD responses don't have a body, so missing objects are reported with a generic not found code.
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && (aerr.Code() == s3api.ErrCodeNoSuchKey || aerr.Code() == "NotFound") {
+			return FileInfo{}, fmt.Errorf("%w: %s", ErrResourceNotFound, err)
+		}
+		if err != nil {
+			return FileInfo{}, err
+		}
+		return FileInfo{
+			Path:     path,
+			Size:     aws.Int64Value(out.ContentLength),
+			Checksum: checksumFromETag(aws.StringValue(out.ETag)),
+			ModTime:  aws.TimeValue(out.LastModified),
+		}, nil
+	}
+}
+
+// modTimeS3v1 generates a function that contains the interaction with S3 to get the last modification time of the
+// object found in a location.
+func modTimeS3v1(client *s3api.S3, bucket string) modTimeFunc {
+	return func(ctx context.Context, location string) (time.Time, error) {
+		out, err := client.HeadObjectWithContext(ctx, &s3api.HeadObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(location),
+		})
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && (aerr.Code() == s3api.ErrCodeNoSuchKey || aerr.Code() == "NotFound") {
+			return time.Time{}, fmt.Errorf("%w: %s", ErrResourceNotFound, err)
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		return aws.TimeValue(out.LastModified), nil
+	}
+}
+
+// openFileS3v1 generates a function that contains the interaction with S3 to read a range of bytes of a file.
+func openFileS3v1(client *s3api.S3, bucket string) OpenFileFunc {
+	open := openObjectS3v1(client, bucket)
+	return func(ctx context.Context, resource Resource, path string, rng Range) (io.ReadCloser, error) {
+		return open(ctx, getLocation("", resource, path), rng)
+	}
+}
+
+// openObjectS3v1 generates a function that contains the interaction with S3 to read a range of bytes of the object
+// found in a location.
+func openObjectS3v1(client *s3api.S3, bucket string) openObjectFunc {
+	return func(ctx context.Context, location string, rng Range) (io.ReadCloser, error) {
+		input := &s3api.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(location),
+		}
+		if !rng.IsFull() {
+			input.Range = aws.String(rng.header())
+		}
+		out, err := client.GetObjectWithContext(ctx, input)
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && aerr.Code() == s3api.ErrCodeNoSuchKey {
+			return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, err)
+		}
+		if isInvalidRangeS3v1(err) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRange, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return out.Body, nil
+	}
+}
+
+// isInvalidRangeS3v1 returns true if err is returned by S3 when the requested range cannot be satisfied.
+func isInvalidRangeS3v1(err error) bool {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == "InvalidRange" {
+		return true
+	}
+	var reqErr awserr.RequestFailure
+	return errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusRequestedRangeNotSatisfiable
+}
+
 // uploadFileS3v1 generates a function that uploads a single file in a path.
 func uploadFileS3v1(uploader *s3manager.Uploader, bucket string, resource Resource) WalkDirFunc {
 	return func(ctx context.Context, path string, body io.Reader) error {
@@ -100,7 +303,8 @@ func uploadFileS3v1(uploader *s3manager.Uploader, bucket string, resource Resour
 		if resource != nil {
 			path = getLocation("", resource, path)
 		}
-		_, err := uploader.Upload(&s3manager.UploadInput{
+		// The uploader splits body in multiple parts if needed, so files of any size can be streamed.
+		_, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
 			Bucket: aws.String(bucket),
 			Key:    aws.String(path),
 			Body:   body,
@@ -117,7 +321,7 @@ func deleteFileS3v1(client *s3api.S3, bucket string, resource Resource) WalkDirF
 		if resource != nil {
 			path = getLocation("", resource, path)
 		}
-		_, err := client.DeleteObject(&s3api.DeleteObjectInput{
+		_, err := client.DeleteObjectWithContext(ctx, &s3api.DeleteObjectInput{
 			Bucket: aws.String(bucket),
 			Key:    aws.String(path),
 		})
@@ -10,8 +10,10 @@ import (
 	"github.com/johannesboyne/gofakes3"
 	"github.com/johannesboyne/gofakes3/backend/s3mem"
 	"github.com/stretchr/testify/suite"
+	"io"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -229,3 +231,231 @@ func (suite *s3v1StorageTestSuite) TestUploadZip_Success() {
 	suite.Require().NoError(err)
 	suite.Require().Contains(link, "2.zip")
 }
+
+func (suite *s3v1StorageTestSuite) TestOpenFile_InvalidResource() {
+	_, err := suite.storage.OpenFile(context.Background(), invalidResource, "model.sdf", Range{})
+	suite.Assert().ErrorIs(err, ErrResourceInvalidFormat)
+}
+
+func (suite *s3v1StorageTestSuite) TestOpenFile_NotFound() {
+	_, err := suite.storage.OpenFile(context.Background(), validResource, "model123.sdf", Range{})
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+}
+
+func (suite *s3v1StorageTestSuite) TestOpenFile_InvalidRange() {
+	_, err := suite.storage.OpenFile(context.Background(), validResource, "model.sdf", Range{Offset: 1 << 30, Length: 10})
+	suite.Assert().ErrorIs(err, ErrInvalidRange)
+}
+
+func (suite *s3v1StorageTestSuite) TestOpenFile_Success() {
+	ctx := context.Background()
+	expected, err := suite.fsStorage.GetFile(ctx, validResource, "model.sdf")
+	suite.Require().NoError(err)
+
+	r, err := suite.storage.OpenFile(ctx, validResource, "model.sdf", Range{})
+	suite.Require().NoError(err)
+	content, err := io.ReadAll(r)
+	suite.Require().NoError(err)
+	suite.Require().NoError(r.Close())
+	suite.Assert().Equal(expected, content)
+
+	r, err = suite.storage.OpenFile(ctx, validResource, "model.sdf", Range{Offset: 5, Length: 10})
+	suite.Require().NoError(err)
+	content, err = io.ReadAll(r)
+	suite.Require().NoError(err)
+	suite.Require().NoError(r.Close())
+	suite.Assert().Equal(expected[5:15], content)
+}
+
+func (suite *s3v1StorageTestSuite) TestPutFile_InvalidResource() {
+	err := suite.storage.PutFile(context.Background(), invalidResource, "model.sdf", strings.NewReader("test"))
+	suite.Assert().ErrorIs(err, ErrResourceInvalidFormat)
+}
+
+func (suite *s3v1StorageTestSuite) TestPutFile_Success() {
+	ctx := context.Background()
+	suite.Require().NoError(suite.storage.PutFile(ctx, nonExistentResource, "meshes/test.dae", strings.NewReader("test")))
+	defer func() {
+		suite.Require().NoError(deleteFileS3v1(suite.client, suite.bucketName, nonExistentResource)(ctx, "meshes/test.dae", nil))
+	}()
+
+	content, err := suite.storage.GetFile(ctx, nonExistentResource, "meshes/test.dae")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("test", string(content))
+}
+
+func (suite *s3v1StorageTestSuite) TestListFiles_InvalidResource() {
+	_, err := suite.storage.ListFiles(context.Background(), invalidResource, "")
+	suite.Assert().ErrorIs(err, ErrResourceInvalidFormat)
+}
+
+func (suite *s3v1StorageTestSuite) TestListFiles_Success() {
+	ctx := context.Background()
+	expected, err := suite.fsStorage.ListFiles(ctx, validResource, "")
+	suite.Require().NoError(err)
+
+	files, err := suite.storage.ListFiles(ctx, validResource, "")
+	suite.Require().NoError(err)
+	suite.Require().Len(files, len(expected))
+	for i := range expected {
+		suite.Assert().Equal(expected[i].Path, files[i].Path)
+		suite.Assert().Equal(expected[i].Size, files[i].Size)
+	}
+
+	files, err = suite.storage.ListFiles(ctx, validResource, "meshes/")
+	suite.Require().NoError(err)
+	suite.Require().Len(files, 1)
+	suite.Assert().Equal("meshes/turtle.dae", files[0].Path)
+}
+
+func (suite *s3v1StorageTestSuite) TestStat_NotFound() {
+	_, err := suite.storage.Stat(context.Background(), validResource, "model123.sdf")
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+}
+
+func (suite *s3v1StorageTestSuite) TestStat_Success() {
+	ctx := context.Background()
+	expected, err := suite.fsStorage.Stat(ctx, validResource, "model.sdf")
+	suite.Require().NoError(err)
+
+	info, err := suite.storage.Stat(ctx, validResource, "model.sdf")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(expected.Path, info.Path)
+	suite.Assert().Equal(expected.Size, info.Size)
+	suite.Assert().Equal(expected.Checksum, info.Checksum)
+}
+
+func (suite *s3v1StorageTestSuite) TestDeleteVersion_InvalidResource() {
+	err := suite.storage.DeleteVersion(context.Background(), invalidResource)
+	suite.Assert().ErrorIs(err, ErrResourceInvalidFormat)
+}
+
+func (suite *s3v1StorageTestSuite) TestDeleteVersion_NotFound() {
+	err := suite.storage.DeleteVersion(context.Background(), nonExistentResource)
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+}
+
+func (suite *s3v1StorageTestSuite) TestDeleteVersion_Success() {
+	ctx := context.Background()
+	v1 := nonExistentResource
+	v2 := NewResource(v1.GetUUID(), v1.GetOwner(), 2)
+	suite.Require().NoError(suite.storage.UploadDir(ctx, v1, "./testdata/example"))
+	suite.Require().NoError(suite.storage.UploadDir(ctx, v2, "./testdata/example"))
+	f, err := os.Open(getZipLocation(basePath, validResource))
+	suite.Require().NoError(err)
+	defer f.Close()
+	suite.Require().NoError(suite.storage.UploadZip(ctx, v1, f))
+	defer func() {
+		suite.Require().NoError(suite.storage.DeleteVersion(ctx, v2))
+	}()
+
+	suite.Require().NoError(suite.storage.DeleteVersion(ctx, v1))
+
+	files, err := suite.storage.ListFiles(ctx, v1, "")
+	suite.Require().NoError(err)
+	suite.Assert().Empty(files)
+	_, err = suite.storage.Download(ctx, v1)
+	suite.Assert().Error(err)
+
+	// Other versions are kept
+	files, err = suite.storage.ListFiles(ctx, v2, "")
+	suite.Require().NoError(err)
+	suite.Assert().Len(files, 4)
+}
+
+func (suite *s3v1StorageTestSuite) TestDeleteResource_NotFound() {
+	err := suite.storage.DeleteResource(context.Background(), nonExistentResource.GetOwner(), nonExistentResource.GetUUID())
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+}
+
+func (suite *s3v1StorageTestSuite) TestDeleteResource_Success() {
+	ctx := context.Background()
+	v1 := nonExistentResource
+	v2 := NewResource(v1.GetUUID(), v1.GetOwner(), 2)
+	suite.Require().NoError(suite.storage.UploadDir(ctx, v1, "./testdata/example"))
+	suite.Require().NoError(suite.storage.UploadDir(ctx, v2, "./testdata/example"))
+	f, err := os.Open(getZipLocation(basePath, validResource))
+	suite.Require().NoError(err)
+	defer f.Close()
+	suite.Require().NoError(suite.storage.UploadZip(ctx, v2, f))
+
+	suite.Require().NoError(suite.storage.DeleteResource(ctx, v1.GetOwner(), v1.GetUUID()))
+
+	for _, r := range []Resource{v1, v2} {
+		files, err := suite.storage.ListFiles(ctx, r, "")
+		suite.Require().NoError(err)
+		suite.Assert().Empty(files)
+		_, err = suite.storage.Download(ctx, r)
+		suite.Assert().Error(err)
+	}
+}
+
+func (suite *s3v1StorageTestSuite) TestUploadDir_ZipOnUpload() {
+	ctx := context.Background()
+	r := nonExistentResource
+	s := NewS3v1(suite.client, suite.uploader, suite.bucketName, ZipOnUpload())
+
+	suite.Require().NoError(s.UploadDir(ctx, r, "./testdata/example"))
+	defer func() {
+		suite.Require().NoError(s.DeleteVersion(ctx, r))
+	}()
+
+	url, err := s.Download(ctx, r)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(url, ".zip")
+}
+
+func (suite *s3v1StorageTestSuite) TestRebuildZip_NotFound() {
+	err := suite.storage.RebuildZip(context.Background(), nonExistentResource)
+	suite.Assert().ErrorIs(err, ErrResourceNotFound)
+}
+
+func (suite *s3v1StorageTestSuite) TestRebuildZip_Success() {
+	ctx := context.Background()
+	r := nonExistentResource
+	suite.Require().NoError(suite.storage.UploadDir(ctx, r, "./testdata/example"))
+	defer func() {
+		suite.Require().NoError(suite.storage.DeleteVersion(ctx, r))
+	}()
+
+	_, err := suite.storage.Download(ctx, r)
+	suite.Require().Error(err)
+
+	suite.Require().NoError(suite.storage.RebuildZip(ctx, r))
+
+	url, err := suite.storage.Download(ctx, r)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(url, ".zip")
+}
+
+func (suite *s3v1StorageTestSuite) TestContentAddressed() {
+	ctx := context.Background()
+	s := NewS3v1(suite.client, suite.uploader, suite.bucketName, ContentAddressed())
+	v1 := nonExistentResource
+	v2 := NewResource(v1.GetUUID(), v1.GetOwner(), 2)
+	suite.Require().NoError(s.UploadDir(ctx, v1, "./testdata/example"))
+	suite.Require().NoError(s.UploadDir(ctx, v2, "./testdata/example"))
+	defer func() {
+		suite.Require().NoError(s.DeleteResource(ctx, v1.GetOwner(), v1.GetUUID()))
+	}()
+
+	expected, err := suite.fsStorage.GetFile(ctx, validResource, "model.sdf")
+	suite.Require().NoError(err)
+	content, err := s.GetFile(ctx, v2, "model.sdf")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(expected, content)
+
+	files, err := s.ListFiles(ctx, v2, "")
+	suite.Require().NoError(err)
+	suite.Assert().Len(files, 4)
+
+	// Zip files are generated from the manifest if they don't exist.
+	url, err := s.Download(ctx, v2)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(url, ".zip")
+
+	suite.Require().NoError(s.DeleteVersion(ctx, v1))
+	removed, err := s.(GarbageCollector).CollectGarbage(ctx, v1.GetOwner(), v1.GetUUID())
+	suite.Require().NoError(err)
+	suite.Assert().Zero(removed)
+}
@@ -1,14 +1,23 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	s3api "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"io"
+	"net/http"
 	"os"
 	"time"
 )
 
+// s3v2PartSize is the size of the parts used to upload files that cannot be seeked. S3 requires all parts but the
+// last one to be at least 5 MiB.
+const s3v2PartSize = 8 << 20
+
 // s3v2 implements Storage using the Amazon Web Services - Simple Storage Service (S3).
 // It uses the second version of the SDK.
 //
@@ -20,6 +29,7 @@ type s3v2 struct {
 	presign  *s3api.PresignClient
 	bucket   string
 	duration time.Duration
+	options  options
 }
 
 // UploadZip uploads a zip file of the given resource to S3.
@@ -31,8 +41,23 @@ func (s *s3v2) UploadZip(ctx context.Context, resource Resource, file *os.File)
 }
 
 // UploadDir uploads the entire src directory to S3.
+//
+//	If ZipOnUpload was provided when initializing the storage, the zip file of the resource is also generated and
+//	uploaded.
 func (s *s3v2) UploadDir(ctx context.Context, resource Resource, src string) error {
-	return UploadDir(ctx, resource, src, uploadFileS3v2(s.client, s.bucket, resource))
+	if err := UploadDir(ctx, resource, src, uploadFileS3v2(s.client, s.bucket, resource)); err != nil {
+		return err
+	}
+	if !s.options.zipOnUpload {
+		return nil
+	}
+	return UploadDirZip(ctx, resource, src, uploadFileS3v2(s.client, s.bucket, nil))
+}
+
+// RebuildZip generates the zip file of the given resource from the files stored in S3, and uploads it.
+func (s *s3v2) RebuildZip(ctx context.Context, resource Resource) error {
+	return RebuildZip(ctx, resource, listFilesS3v2(s.client, s.bucket), readFileS3v2(s.client, s.bucket),
+		uploadFileS3v2(s.client, s.bucket, nil))
 }
 
 // Download downloads a zip file of the given resource from S3.
@@ -66,14 +91,35 @@ func (s *s3v2) GetFile(ctx context.Context, resource Resource, path string) ([]b
 	return ReadFile(ctx, resource, path, readFileS3v2(s.client, s.bucket))
 }
 
+// OpenFile returns a reader for the bytes in rng of the file located in path from the given resource.
+func (s *s3v2) OpenFile(ctx context.Context, resource Resource, path string, rng Range) (io.ReadCloser, error) {
+	return OpenFile(ctx, resource, path, rng, openFileS3v2(s.client, s.bucket))
+}
+
+// PutFile streams the content read from body to the file located in path in the given resource.
+func (s *s3v2) PutFile(ctx context.Context, resource Resource, path string, body io.Reader) error {
+	return PutFile(ctx, resource, path, body, uploadFileS3v2(s.client, s.bucket, resource))
+}
+
 // NewS3v2 initializes a new implementation of Storage using the AWS S3 service.
-func NewS3v2(client *s3api.Client, bucket string) Storage {
-	return &s3v2{
+func NewS3v2(client *s3api.Client, bucket string, opts ...Option) Storage {
+	s := &s3v2{
 		client:   client,
 		presign:  s3api.NewPresignClient(client),
 		bucket:   bucket,
 		duration: 5 * time.Minute,
+		options:  newOptions(opts),
+	}
+	if !s.options.contentAddressed {
+		return s
 	}
+	return newContentAddressed(s, objectFuncs{
+		put:     uploadFileS3v2(client, bucket, nil),
+		open:    openObjectS3v2(client, bucket),
+		list:    listS3v2(client, bucket),
+		remove:  deleteFileS3v2(client, bucket, nil),
+		modTime: modTimeS3v2(client, bucket),
+	}, s.options)
 }
 
 // readFileS3v2 generates a function that contains the interaction with S3 to read the content of a file.
@@ -90,6 +136,167 @@ func readFileS3v2(client *s3api.Client, bucket string) ReadFileFunc {
 	}
 }
 
+// ListFiles returns the information of the files in the given resource whose path starts with prefix.
+func (s *s3v2) ListFiles(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error) {
+	return ListFiles(ctx, resource, prefix, listFilesS3v2(s.client, s.bucket))
+}
+
+// Stat returns the information of the file located in path from the given resource.
+func (s *s3v2) Stat(ctx context.Context, resource Resource, path string) (FileInfo, error) {
+	return Stat(ctx, resource, path, statS3v2(s.client, s.bucket))
+}
+
+// DeleteVersion removes all the files of the given resource version from S3, including its zip file.
+func (s *s3v2) DeleteVersion(ctx context.Context, resource Resource) error {
+	return DeleteVersion(ctx, resource, listS3v2(s.client, s.bucket), deleteFileS3v2(s.client, s.bucket, nil))
+}
+
+// DeleteResource removes all the versions and zip files of the given resource from S3.
+func (s *s3v2) DeleteResource(ctx context.Context, owner string, uuid string) error {
+	return DeleteResource(ctx, owner, uuid, listS3v2(s.client, s.bucket), deleteFileS3v2(s.client, s.bucket, nil))
+}
+
+// listS3v2 generates a function that contains the interaction with S3 to find the keys of the objects that start
+// with a prefix.
+func listS3v2(client *s3api.Client, bucket string) ListFunc {
+	return func(ctx context.Context, prefix string) ([]string, error) {
+		paginator := s3api.NewListObjectsV2Paginator(client, &s3api.ListObjectsV2Input{
+			Bucket: aws.String(bucket),
+			Prefix: aws.String(prefix),
+		})
+		var keys []string
+		for paginator.HasMorePages() {
+			out, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, obj := range out.Contents {
+				keys = append(keys, aws.ToString(obj.Key))
+			}
+		}
+		return keys, nil
+	}
+}
+
+// listFilesS3v2 generates a function that contains the interaction with S3 to list the files of a resource.
+func listFilesS3v2(client *s3api.Client, bucket string) ListFilesFunc {
+	return func(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error) {
+		paginator := s3api.NewListObjectsV2Paginator(client, &s3api.ListObjectsV2Input{
+			Bucket: aws.String(bucket),
+			Prefix: aws.String(getPrefixLocation(resource, prefix)),
+		})
+		files := []FileInfo{}
+		for paginator.HasMorePages() {
+			out, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, obj := range out.Contents {
+				path, err := getRelativePath("", resource, aws.ToString(obj.Key))
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, FileInfo{
+					Path:     path,
+					Size:     aws.ToInt64(obj.Size),
+					Checksum: checksumFromETag(aws.ToString(obj.ETag)),
+					ModTime:  aws.ToTime(obj.LastModified),
+				})
+			}
+		}
+		return files, nil
+	}
+}
+
+// statS3v2 generates a function that contains the interaction with S3 to get the information of a file.
+func statS3v2(client *s3api.Client, bucket string) StatFunc {
+	return func(ctx context.Context, resource Resource, path string) (FileInfo, error) {
+		out, err := client.HeadObject(ctx, &s3api.HeadObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(getLocation("", resource, path)),
+		})
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return FileInfo{}, fmt.Errorf("%w: %s", ErrResourceNotFound, err)
+		}
+		if err != nil {
+			return FileInfo{}, err
+		}
+		return FileInfo{
+			Path:     path,
+			Size:     aws.ToInt64(out.ContentLength),
+			Checksum: checksumFromETag(aws.ToString(out.ETag)),
+			ModTime:  aws.ToTime(out.LastModified),
+		}, nil
+	}
+}
+
+// modTimeS3v2 generates a function that contains the interaction with S3 to get the last modification time of the
+// object found in a location.
+func modTimeS3v2(client *s3api.Client, bucket string) modTimeFunc {
+	return func(ctx context.Context, location string) (time.Time, error) {
+		out, err := client.HeadObject(ctx, &s3api.HeadObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(location),
+		})
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return time.Time{}, fmt.Errorf("%w: %s", ErrResourceNotFound, err)
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		return aws.ToTime(out.LastModified), nil
+	}
+}
+
+// openFileS3v2 generates a function that contains the interaction with S3 to read a range of bytes of a file.
+func openFileS3v2(client *s3api.Client, bucket string) OpenFileFunc {
+	open := openObjectS3v2(client, bucket)
+	return func(ctx context.Context, resource Resource, path string, rng Range) (io.ReadCloser, error) {
+		return open(ctx, getLocation("", resource, path), rng)
+	}
+}
+
+// openObjectS3v2 generates a function that contains the interaction with S3 to read a range of bytes of the object
+// found in a location.
+func openObjectS3v2(client *s3api.Client, bucket string) openObjectFunc {
+	return func(ctx context.Context, location string, rng Range) (io.ReadCloser, error) {
+		input := &s3api.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(location),
+		}
+		if !rng.IsFull() {
+			input.Range = aws.String(rng.header())
+		}
+		out, err := client.GetObject(ctx, input)
+		var notFound *types.NoSuchKey
+		if errors.As(err, &notFound) {
+			return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, err)
+		}
+		if isInvalidRangeS3v2(err) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRange, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return out.Body, nil
+	}
+}
+
+// isInvalidRangeS3v2 returns true if err is returned by S3 when the requested range cannot be satisfied.
+// The SDK doesn't model this error, so it's identified by its error code or HTTP status code.
+func isInvalidRangeS3v2(err error) bool {
+	// Implemented by smithy.APIError.
+	var apiErr interface{ ErrorCode() string }
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidRange" {
+		return true
+	}
+	// Implemented by the HTTP response errors returned by the SDK.
+	var respErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusRequestedRangeNotSatisfiable
+}
+
 // uploadFileS3v2 generates a function that allows to upload a single file in a path.
 func uploadFileS3v2(client *s3api.Client, bucket string, resource Resource) WalkDirFunc {
 	return func(ctx context.Context, path string, body io.Reader) error {
@@ -98,13 +305,91 @@ func uploadFileS3v2(client *s3api.Client, bucket string, resource Resource) Walk
 		if resource != nil {
 			path = getLocation("", resource, path)
 		}
-		_, err := client.PutObject(ctx, &s3api.PutObjectInput{
+		// Bodies that can be seeked are sent in a single request, the rest are streamed using a multipart upload.
+		if _, ok := body.(io.ReadSeeker); ok {
+			_, err := client.PutObject(ctx, &s3api.PutObjectInput{
+				Bucket: aws.String(bucket),
+				Key:    aws.String(path),
+				Body:   body,
+			})
+			return err
+		}
+		return uploadMultipartS3v2(ctx, client, bucket, path, body)
+	}
+}
+
+// uploadMultipartS3v2 uploads the content read from body to the given key using a multipart upload. Only one part is
+// kept in memory at a time. Content smaller than a single part is uploaded in a single request.
+func uploadMultipartS3v2(ctx context.Context, client *s3api.Client, bucket string, key string, body io.Reader) error {
+	buf := make([]byte, s3v2PartSize)
+	n, err := io.ReadFull(body, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		_, err = client.PutObject(ctx, &s3api.PutObjectInput{
 			Bucket: aws.String(bucket),
-			Key:    aws.String(path),
-			Body:   body,
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(buf[:n]),
 		})
 		return err
 	}
+	if err != nil {
+		return err
+	}
+
+	upload, err := client.CreateMultipartUpload(ctx, &s3api.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+
+	parts, err := uploadPartsS3v2(ctx, client, upload, buf, n, body)
+	if err != nil {
+		// Abort the upload to free the storage used by the parts that were already uploaded.
+		_, _ = client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3api.AbortMultipartUploadInput{
+			Bucket:   upload.Bucket,
+			Key:      upload.Key,
+			UploadId: upload.UploadId,
+		})
+		return err
+	}
+
+	_, err = client.CompleteMultipartUpload(ctx, &s3api.CompleteMultipartUploadInput{
+		Bucket:          upload.Bucket,
+		Key:             upload.Key,
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	return err
+}
+
+// uploadPartsS3v2 uploads the parts of the given multipart upload. The first n bytes of buf contain the first part, the
+// rest of the parts are read from body using buf.
+func uploadPartsS3v2(ctx context.Context, client *s3api.Client, upload *s3api.CreateMultipartUploadOutput, buf []byte,
+	n int, body io.Reader) ([]types.CompletedPart, error) {
+	var parts []types.CompletedPart
+	for number := int32(1); n > 0; number++ {
+		out, err := client.UploadPart(ctx, &s3api.UploadPartInput{
+			Bucket:     upload.Bucket,
+			Key:        upload.Key,
+			UploadId:   upload.UploadId,
+			PartNumber: aws.Int32(number),
+			Body:       bytes.NewReader(buf[:n]),
+		})
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, types.CompletedPart{
+			ETag:       out.ETag,
+			PartNumber: aws.Int32(number),
+		})
+
+		n, err = io.ReadFull(body, buf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, err
+		}
+	}
+	return parts, nil
 }
 
 // deleteFileS3v2 generates a function that allows to delete a single file in a path.
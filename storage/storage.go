@@ -7,7 +7,10 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gazebo-web/gz-go/v10"
 	"github.com/pkg/errors"
@@ -26,6 +29,131 @@ type Storage interface {
 	//	Resources can have a compressed representation of the resource itself that acts like a cache, it contains all the
 	//	files from the said resource. This function uploads that zip file.
 	UploadZip(ctx context.Context, resource Resource, file *os.File) error
+	// OpenFile returns a reader to stream the content of the file located in path from the given resource. Only the
+	// bytes within rng are read, pass an empty Range to read the whole file. The returned reader must be closed.
+	OpenFile(ctx context.Context, resource Resource, path string, rng Range) (io.ReadCloser, error)
+	// PutFile uploads the content read from body to the file located in path in the given resource. The content is
+	// streamed to the storage without loading the entire file in memory. If the file already exists, it is replaced.
+	PutFile(ctx context.Context, resource Resource, path string, body io.Reader) error
+	// ListFiles returns the information of the files in the given resource whose path starts with prefix, sorted by
+	// path. Pass an empty prefix to list all the files of the resource.
+	ListFiles(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error)
+	// Stat returns the information of the file located in path from the given resource.
+	Stat(ctx context.Context, resource Resource, path string) (FileInfo, error)
+	// DeleteVersion removes all the files of the given resource version, including its zip file. Other versions of
+	// the resource are not modified.
+	DeleteVersion(ctx context.Context, resource Resource) error
+	// DeleteResource removes all the versions of the resource identified by the given owner and uuid, including
+	// their zip files.
+	DeleteResource(ctx context.Context, owner string, uuid string) error
+	// RebuildZip generates the zip file of the given resource from the files that are currently stored, replacing
+	// the existing zip file. It can be used to refresh zip files that no longer match the files of the resource.
+	RebuildZip(ctx context.Context, resource Resource) error
+}
+
+// Option contains logic that can be passed to a cloud Storage initializer to configure it.
+type Option func(o *options)
+
+// options contains the optional settings of cloud Storage implementations.
+type options struct {
+	// zipOnUpload is true if the zip file of a resource is generated when calling UploadDir.
+	zipOnUpload bool
+	// contentAddressed is true if files are stored using the content-addressed layout.
+	contentAddressed bool
+	// gracePeriod is the time during which content that is not referenced by any version is kept by the
+	// GarbageCollector of the content-addressed layout.
+	gracePeriod time.Duration
+}
+
+// ZipOnUpload makes UploadDir generate the zip file of the uploaded resource and upload it together with the rest of
+// the files. The zip file is streamed to the cloud storage while it's generated, so it's never written to disk.
+// By default, zip files must be uploaded with UploadZip.
+func ZipOnUpload() Option {
+	return func(o *options) {
+		o.zipOnUpload = true
+	}
+}
+
+// ContentAddressed stores files by the SHA-256 digest of their content instead of storing a full copy of every
+// version. Files with the same content are stored once per resource, and each version keeps a manifest that maps the
+// paths of its files to their content. Unreferenced content is removed by the GarbageCollector implemented by the
+// returned Storage.
+//
+// Resources stored with the default layout cannot be read using the content-addressed layout, and vice versa.
+func ContentAddressed() Option {
+	return func(o *options) {
+		o.contentAddressed = true
+	}
+}
+
+// defaultGracePeriod is the default value of GarbageCollectionGracePeriod.
+const defaultGracePeriod = 24 * time.Hour
+
+// GarbageCollectionGracePeriod sets the minimum time that content stored using the content-addressed layout is kept
+// after it was last modified when it's not referenced by any version. It prevents the GarbageCollector from removing
+// the content of uploads in progress, whose manifest is written once all of their content has been uploaded.
+// By default, the grace period is 24 hours.
+func GarbageCollectionGracePeriod(d time.Duration) Option {
+	return func(o *options) {
+		o.gracePeriod = d
+	}
+}
+
+// newOptions returns the options resulting from applying opts to the default options.
+func newOptions(opts []Option) options {
+	o := options{
+		gracePeriod: defaultGracePeriod,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// FileInfo describes a file of a Resource.
+type FileInfo struct {
+	// Path is the location of the file relative to the resource, using forward slashes as separators.
+	Path string
+	// Size is the length of the file in bytes.
+	Size int64
+	// Checksum is the hex-encoded MD5 digest of the file content. It's empty when the storage doesn't provide it, such
+	// as when listing files in the filesystem, or for files uploaded to S3 in multiple parts.
+	Checksum string
+	// ModTime is the time the file was last modified.
+	ModTime time.Time
+}
+
+// Range defines a range of bytes in a file.
+type Range struct {
+	// Offset is the position of the first byte to read.
+	Offset int64
+	// Length is the number of bytes to read. If Length is 0, the file is read from Offset until its end.
+	Length int64
+}
+
+// IsFull returns true if the range contains the whole file.
+func (r Range) IsFull() bool {
+	return r.Offset == 0 && r.Length == 0
+}
+
+// header returns the value of the HTTP Range header that requests the bytes in the range.
+// It returns an empty string if the range contains the whole file.
+func (r Range) header() string {
+	if r.IsFull() {
+		return ""
+	}
+	if r.Length == 0 {
+		return fmt.Sprintf("bytes=%d-", r.Offset)
+	}
+	return fmt.Sprintf("bytes=%d-%d", r.Offset, r.Offset+r.Length-1)
+}
+
+// validateRange validates the given range, it returns an error if the range is invalid.
+func validateRange(r Range) error {
+	if r.Offset < 0 || r.Length < 0 {
+		return ErrInvalidRange
+	}
+	return nil
 }
 
 // ReadFileFunc is used to provide integration with cloud providers while using the same business logic
@@ -50,6 +178,80 @@ func ReadFile(ctx context.Context, resource Resource, path string, fn ReadFileFu
 	return b, nil
 }
 
+// OpenFileFunc is used to provide integration with cloud providers while using the same business logic
+// when streaming the content of a file.
+type OpenFileFunc func(ctx context.Context, resource Resource, path string, rng Range) (io.ReadCloser, error)
+
+// OpenFile returns a reader for the bytes in rng of the file located in path from the given resource.
+// The integration the specific storage providers is provided by the OpenFileFunc.
+func OpenFile(ctx context.Context, resource Resource, path string, rng Range, fn OpenFileFunc) (io.ReadCloser, error) {
+	if err := validateResource(resource); err != nil {
+		return nil, err
+	}
+	if err := validateRange(rng); err != nil {
+		return nil, err
+	}
+	path, err := cleanPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return fn(ctx, resource, path, rng)
+}
+
+// PutFile uploads the content read from body to the file located in path of the given resource.
+// The WalkDirFunc receives the path relative to the resource.
+func PutFile(ctx context.Context, resource Resource, path string, body io.Reader, fn WalkDirFunc) error {
+	if err := validateResource(resource); err != nil {
+		return err
+	}
+	if body == nil {
+		return ErrFileNil
+	}
+	path, err := cleanPath(path)
+	if err != nil {
+		return err
+	}
+	return fn(ctx, path, body)
+}
+
+// ListFilesFunc is used to provide integration with cloud providers while using the same business logic
+// when listing the files of a resource.
+type ListFilesFunc func(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error)
+
+// ListFiles returns the information of the files in the given resource whose path starts with prefix, sorted by path.
+// The integration the specific storage providers is provided by the ListFilesFunc, which receives the prefix without
+// leading slashes.
+func ListFiles(ctx context.Context, resource Resource, prefix string, fn ListFilesFunc) ([]FileInfo, error) {
+	if err := validateResource(resource); err != nil {
+		return nil, err
+	}
+	files, err := fn(ctx, resource, strings.TrimLeft(prefix, "/"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// StatFunc is used to provide integration with cloud providers while using the same business logic
+// when getting the information of a file.
+type StatFunc func(ctx context.Context, resource Resource, path string) (FileInfo, error)
+
+// Stat returns the information of the file located in path from the given resource.
+// The integration the specific storage providers is provided by the StatFunc.
+func Stat(ctx context.Context, resource Resource, path string, fn StatFunc) (FileInfo, error) {
+	if err := validateResource(resource); err != nil {
+		return FileInfo{}, err
+	}
+	path, err := cleanPath(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return fn(ctx, resource, path)
+}
+
 // UploadDir uploads the directory and all the sub elements found in src using the provided WalkDirFunc
 // for each file found inside src. They will be uploaded as the assets for the given Resource.
 func UploadDir(ctx context.Context, resource Resource, src string, fn WalkDirFunc) error {
@@ -102,6 +304,107 @@ func UploadZip(ctx context.Context, resource Resource, file *os.File, fn WalkDir
 	return nil
 }
 
+// ListFunc is used to provide integration with cloud providers while using the same business logic
+// when finding the locations of all the files that start with prefix.
+type ListFunc func(ctx context.Context, prefix string) ([]string, error)
+
+// DeleteVersion removes the files and the zip file of the given resource version. The locations of the files are
+// provided by the ListFunc, and the WalkDirFunc deletes each of them. It returns ErrResourceNotFound if the version
+// doesn't have any files.
+func DeleteVersion(ctx context.Context, resource Resource, list ListFunc, fn WalkDirFunc) error {
+	if err := validateResource(resource); err != nil {
+		return err
+	}
+	locations, err := list(ctx, getLocation("", resource, "")+"/")
+	if err != nil {
+		return err
+	}
+	if len(locations) == 0 {
+		return ErrResourceNotFound
+	}
+
+	// Only the zip file of the current version is removed, other zip files share the same prefix (e.g. 1.zip and 10.zip).
+	zip := getZipLocation("", resource)
+	zips, err := list(ctx, zip)
+	if err != nil {
+		return err
+	}
+	for _, location := range zips {
+		if location == zip {
+			locations = append(locations, location)
+		}
+	}
+
+	return deleteLocations(ctx, locations, fn)
+}
+
+// DeleteResource removes all the versions and zip files of the resource identified by owner and uuid. The locations
+// of the files are provided by the ListFunc, and the WalkDirFunc deletes each of them. It returns ErrResourceNotFound
+// if the resource doesn't have any files.
+func DeleteResource(ctx context.Context, owner string, uuid string, list ListFunc, fn WalkDirFunc) error {
+	if err := validateOwner(owner); err != nil {
+		return err
+	}
+	if err := validateUUID(uuid); err != nil {
+		return err
+	}
+	locations, err := list(ctx, getRootLocation("", owner, uuid)+"/")
+	if err != nil {
+		return err
+	}
+	if len(locations) == 0 {
+		return ErrResourceNotFound
+	}
+	return deleteLocations(ctx, locations, fn)
+}
+
+// deleteLocations calls fn with each of the given locations. It stops at the first error.
+func deleteLocations(ctx context.Context, locations []string, fn WalkDirFunc) error {
+	for _, location := range locations {
+		if err := fn(ctx, location, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanPath returns the shortest path relative to a resource equivalent to path. Paths cannot point outside the
+// resource, parent directory elements at the start of path are removed. It returns ErrInvalidPath if path doesn't
+// point to a file.
+func cleanPath(path string) (string, error) {
+	clean := strings.TrimPrefix(filepath.Clean("/"+path), "/")
+	if len(clean) == 0 {
+		return "", ErrInvalidPath
+	}
+	return clean, nil
+}
+
+// getRelativePath returns the path of the file found in location relative to the location of the given Resource,
+// using forward slashes as separators.
+func getRelativePath(base string, r Resource, location string) (string, error) {
+	rel, err := filepath.Rel(getLocation(base, r, ""), location)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// getPrefixLocation returns the location used to find the files of a Resource whose path starts with prefix. Unlike
+// getLocation, trailing slashes in prefix are kept.
+func getPrefixLocation(r Resource, prefix string) string {
+	return getLocation("", r, "") + "/" + prefix
+}
+
+// checksumFromETag returns the checksum of a file from its ETag. ETags only match the MD5 digest of the content for
+// files uploaded in a single part, it returns an empty string for files uploaded in multiple parts.
+func checksumFromETag(etag string) string {
+	etag = strings.Trim(etag, "\"")
+	if strings.Contains(etag, "-") {
+		return ""
+	}
+	return etag
+}
+
 // getLocation returns the location of a Resource relative to the base location.
 //
 //	If path is not empty, it will append the given path to the resulting location of the resource.
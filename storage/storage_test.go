@@ -10,6 +10,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -96,3 +97,69 @@ func TestUploadZip(t *testing.T) {
 	assert.Equal(t, expected, path)
 	require.NoError(t, f.Close())
 }
+
+func TestRange_Header(t *testing.T) {
+	assert.True(t, Range{}.IsFull())
+	assert.Empty(t, Range{}.header())
+	assert.Equal(t, "bytes=10-", Range{Offset: 10}.header())
+	assert.Equal(t, "bytes=0-9", Range{Length: 10}.header())
+	assert.Equal(t, "bytes=10-14", Range{Offset: 10, Length: 5}.header())
+}
+
+func TestCleanPath(t *testing.T) {
+	path, err := cleanPath("/meshes/../model.sdf")
+	assert.NoError(t, err)
+	assert.Equal(t, "model.sdf", path)
+
+	path, err = cleanPath("../../meshes/turtle.dae")
+	assert.NoError(t, err)
+	assert.Equal(t, "meshes/turtle.dae", path)
+
+	_, err = cleanPath("/")
+	assert.ErrorIs(t, err, ErrInvalidPath)
+
+	_, err = cleanPath("")
+	assert.ErrorIs(t, err, ErrInvalidPath)
+}
+
+func TestChecksumFromETag(t *testing.T) {
+	assert.Equal(t, "9b2cf535f27731c974343645a3985328", checksumFromETag(`"9b2cf535f27731c974343645a3985328"`))
+	assert.Equal(t, "9b2cf535f27731c974343645a3985328", checksumFromETag("9b2cf535f27731c974343645a3985328"))
+	assert.Empty(t, checksumFromETag(`"d41d8cd98f00b204e9800998ecf8427e-2"`))
+}
+
+func TestDeleteVersion(t *testing.T) {
+	ctx := context.Background()
+	files := map[string]bool{
+		"OpenRobotics/e6af5323-db4d-4db3-a402-a8992d6c8d99/1/model.sdf":         true,
+		"OpenRobotics/e6af5323-db4d-4db3-a402-a8992d6c8d99/1/meshes/turtle.dae": true,
+		"OpenRobotics/e6af5323-db4d-4db3-a402-a8992d6c8d99/10/model.sdf":        true,
+		"OpenRobotics/e6af5323-db4d-4db3-a402-a8992d6c8d99/.zips/1.zip":         true,
+		"OpenRobotics/e6af5323-db4d-4db3-a402-a8992d6c8d99/.zips/10.zip":        true,
+	}
+	list := func(ctx context.Context, prefix string) ([]string, error) {
+		var out []string
+		for f := range files {
+			if strings.HasPrefix(f, prefix) {
+				out = append(out, f)
+			}
+		}
+		return out, nil
+	}
+	remove := func(ctx context.Context, path string, body io.Reader) error {
+		delete(files, path)
+		return nil
+	}
+
+	require.NoError(t, DeleteVersion(ctx, validResource, list, remove))
+	assert.Len(t, files, 2)
+	assert.Contains(t, files, "OpenRobotics/e6af5323-db4d-4db3-a402-a8992d6c8d99/10/model.sdf")
+	assert.Contains(t, files, "OpenRobotics/e6af5323-db4d-4db3-a402-a8992d6c8d99/.zips/10.zip")
+
+	assert.ErrorIs(t, DeleteVersion(ctx, validResource, list, remove), ErrResourceNotFound)
+
+	require.NoError(t, DeleteResource(ctx, owner, validUUID, list, remove))
+	assert.Empty(t, files)
+
+	assert.ErrorIs(t, DeleteResource(ctx, owner, validUUID, list, remove), ErrResourceNotFound)
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+)
+
+// openFunc opens the file located in path relative to the resource that is being compressed.
+type openFunc func(ctx context.Context, path string) (io.ReadCloser, error)
+
+// UploadDirZip generates the zip file of the given resource using the files found in src, and uploads it using the
+// provided WalkDirFunc. The zip file is streamed to the WalkDirFunc while it's generated, it's never written to disk.
+func UploadDirZip(ctx context.Context, resource Resource, src string, fn WalkDirFunc) error {
+	if err := validateResource(resource); err != nil {
+		return err
+	}
+
+	var files []FileInfo
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, FileInfo{
+			Path:    filepath.ToSlash(rel),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return ErrSourceFolderEmpty
+	}
+
+	return uploadZip(ctx, resource, files, func(ctx context.Context, p string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(src, filepath.FromSlash(p)))
+	}, fn)
+}
+
+// RebuildZip generates the zip file of the given resource using the files that are already stored, and uploads it
+// using the provided WalkDirFunc, replacing the existing zip file. The files of the resource are listed using the
+// ListFilesFunc and read using the ReadFileFunc. The zip file is streamed to the WalkDirFunc while it's generated.
+// It returns ErrResourceNotFound if the resource doesn't have any files.
+func RebuildZip(ctx context.Context, resource Resource, list ListFilesFunc, read ReadFileFunc, fn WalkDirFunc) error {
+	files, err := ListFiles(ctx, resource, "", list)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return ErrResourceNotFound
+	}
+
+	return uploadZip(ctx, resource, files, func(ctx context.Context, p string) (io.ReadCloser, error) {
+		return read(ctx, resource, p)
+	}, fn)
+}
+
+// uploadZip writes the zip file of the given resource containing the given files to a pipe, and uploads the content
+// read from the pipe to the zip location of the resource using fn.
+func uploadZip(ctx context.Context, resource Resource, files []FileInfo, open openFunc, fn WalkDirFunc) error {
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := writeZip(ctx, pw, resource, files, open)
+		// Closing the pipe with a nil error signals the end of the zip file to the reader.
+		_ = pw.CloseWithError(err)
+		done <- err
+	}()
+
+	err := fn(ctx, getZipLocation("", resource), pr)
+	// Stop the writer if fn returned before reading the whole zip file.
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+
+	if writeErr := <-done; writeErr != nil && err == nil {
+		return writeErr
+	}
+	return err
+}
+
+// writeZip writes a zip file with the given files of resource to w. Files are placed in a folder named after the
+// resource version, matching the layout of the zip files generated by the filesystem storage.
+func writeZip(ctx context.Context, w io.Writer, resource Resource, files []FileInfo, open openFunc) error {
+	zw := zip.NewWriter(w)
+	root := strconv.FormatUint(resource.GetVersion(), 10)
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := writeZipFile(ctx, zw, path.Join(root, file.Path), file, open); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeZipFile adds the given file to zw using name as the file name.
+func writeZipFile(ctx context.Context, zw *zip.Writer, name string, file FileInfo, open openFunc) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: file.ModTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	r, err := open(ctx, file.Path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	if closeErr := r.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"testing"
+)
+
+// readZip returns the content of the files of the given zip file indexed by name.
+func readZip(t *testing.T, b []byte) map[string][]byte {
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+	files := make(map[string][]byte)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], err = io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+	}
+	return files
+}
+
+func TestUploadDirZip(t *testing.T) {
+	ctx := context.Background()
+	var location string
+	var content []byte
+	require.NoError(t, UploadDirZip(ctx, validResource, "./testdata/example", func(ctx context.Context, path string, body io.Reader) error {
+		location = path
+		var err error
+		content, err = io.ReadAll(body)
+		return err
+	}))
+	assert.Equal(t, getZipLocation("", validResource), location)
+
+	files := readZip(t, content)
+	assert.Len(t, files, 4)
+	expected, err := os.ReadFile("./testdata/example/meshes/turtle.dae")
+	require.NoError(t, err)
+	assert.Equal(t, expected, files["1/meshes/turtle.dae"])
+	assert.Contains(t, files, "1/model.sdf")
+	assert.Contains(t, files, "1/model.config")
+	assert.Contains(t, files, "1/thumbnails/1.png")
+}
+
+func TestUploadDirZip_SourceIsEmpty(t *testing.T) {
+	err := UploadDirZip(context.Background(), validResource, t.TempDir(), func(ctx context.Context, path string, body io.Reader) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrSourceFolderEmpty)
+}
+
+func TestUploadDirZip_UploadError(t *testing.T) {
+	errUpload := errors.New("upload failed")
+	err := UploadDirZip(context.Background(), validResource, "./testdata/example", func(ctx context.Context, path string, body io.Reader) error {
+		// Stop reading before the zip file is complete.
+		_, _ = body.Read(make([]byte, 10))
+		return errUpload
+	})
+	assert.ErrorIs(t, err, errUpload)
+}
+
+func TestRebuildZip(t *testing.T) {
+	ctx := context.Background()
+	list := func(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error) {
+		return []FileInfo{{Path: "model.sdf"}, {Path: "meshes/turtle.dae"}}, nil
+	}
+	read := func(ctx context.Context, resource Resource, path string) (io.ReadCloser, error) {
+		return os.Open(getLocation(basePath, resource, path))
+	}
+
+	var content []byte
+	require.NoError(t, RebuildZip(ctx, validResource, list, read, func(ctx context.Context, path string, body io.Reader) error {
+		var err error
+		content, err = io.ReadAll(body)
+		return err
+	}))
+
+	files := readZip(t, content)
+	assert.Len(t, files, 2)
+	expected, err := os.ReadFile(getLocation(basePath, validResource, "model.sdf"))
+	require.NoError(t, err)
+	assert.Equal(t, expected, files["1/model.sdf"])
+}
+
+func TestRebuildZip_ReadError(t *testing.T) {
+	ctx := context.Background()
+	list := func(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error) {
+		return []FileInfo{{Path: "model.sdf"}}, nil
+	}
+	errRead := errors.New("read failed")
+	read := func(ctx context.Context, resource Resource, path string) (io.ReadCloser, error) {
+		return nil, errRead
+	}
+
+	err := RebuildZip(ctx, validResource, list, read, func(ctx context.Context, path string, body io.Reader) error {
+		_, err := io.ReadAll(body)
+		return err
+	})
+	assert.ErrorIs(t, err, errRead)
+}
+
+func TestRebuildZip_NotFound(t *testing.T) {
+	list := func(ctx context.Context, resource Resource, prefix string) ([]FileInfo, error) {
+		return []FileInfo{}, nil
+	}
+	err := RebuildZip(context.Background(), validResource, list, nil, nil)
+	assert.ErrorIs(t, err, ErrResourceNotFound)
+}